@@ -2,11 +2,16 @@ package main
 
 import (
 	"account_storage/internal/app/apiserver"
+	"account_storage/pkg/logadapter"
 	"context"
 	"flag"
+	"fmt"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
+	"text/tabwriter"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	"github.com/sirupsen/logrus"
@@ -24,6 +29,7 @@ func main() {
 
 	logger = logrus.New()
 	logger.SetLevel(logrus.DebugLevel)
+	logger.AddHook(logadapter.NewRedactHook())
 
 	ctx, cancel := context.WithCancel(context.Background())
 	go handleSignals(cancel)
@@ -35,6 +41,14 @@ func main() {
 		logger.Fatal(err)
 	}
 
+	if flag.Arg(0) == "migrate" {
+		err = runMigrate(ctx, logger, config, flag.Args()[1:])
+		if err != nil {
+			logger.Fatal(err)
+		}
+		return
+	}
+
 	server, err := apiserver.NewServer(logger, ctx, config)
 	if err != nil {
 		logger.Fatal(err)
@@ -53,3 +67,58 @@ func handleSignals(cancel context.CancelFunc) {
 	<-signalChannel
 	cancel()
 }
+
+// runMigrate runs "migrate up", "migrate down [steps]" or "migrate status"
+// against the sql or sqlite database of config.
+func runMigrate(ctx context.Context, logger *logrus.Logger, config *apiserver.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: accounts_storage migrate up|down [steps]|status")
+	}
+
+	migrator, err := apiserver.NewMigrator(ctx, logger, config)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("applied %d migrations\n", applied)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("reverted %d migrations\n", reverted)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "VERSION\tNAME\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(writer, "%s\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+		return writer.Flush()
+	default:
+		return fmt.Errorf("unknown migrate command %s, expected up, down or status", args[0])
+	}
+
+	return nil
+}
@@ -15,9 +15,9 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/accounts": {
+        "/account-types": {
             "get": {
-                "description": "Retrieve a list of all accounts",
+                "description": "Retrieve the registered account types",
                 "consumes": [
                     "application/json"
                 ],
@@ -25,26 +25,26 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "accounts"
+                    "account-types"
                 ],
-                "summary": "Get all accounts",
+                "summary": "Get all account types",
                 "responses": {
                     "200": {
-                        "description": "List of accounts",
+                        "description": "List of account types",
                         "schema": {
-                            "$ref": "#/definitions/account.GetAllResponse"
+                            "$ref": "#/definitions/accounttype.GetAllResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/httperror.Response"
                         }
                     }
                 }
             },
             "post": {
-                "description": "Create a new account with the specified details",
+                "description": "Register the definition accounts of a type are validated against",
                 "consumes": [
                     "application/json"
                 ],
@@ -52,17 +52,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "accounts"
+                    "account-types"
                 ],
-                "summary": "Create a new account",
+                "summary": "Register an account type",
                 "parameters": [
                     {
-                        "description": "Account to create",
-                        "name": "account",
+                        "description": "Account type to register",
+                        "name": "accountType",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/account.CreateRequest"
+                            "$ref": "#/definitions/accounttype.CreateRequest"
                         }
                     }
                 ],
@@ -70,27 +70,33 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/account.CreateResponse"
+                            "$ref": "#/definitions/accounttype.CreateResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Account type already exists",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/httperror.Response"
                         }
                     }
                 }
             }
         },
-        "/accounts/{id}": {
+        "/account-types/{name}": {
             "get": {
-                "description": "Retrieve an account by its unique identifier",
+                "description": "Retrieve the definition of an account type",
                 "consumes": [
                     "application/json"
                 ],
@@ -98,47 +104,41 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "accounts"
+                    "account-types"
                 ],
-                "summary": "Get account by ID",
+                "summary": "Get an account type",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Account ID",
-                        "name": "id",
+                        "description": "Account type name",
+                        "name": "name",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Account data",
-                        "schema": {
-                            "$ref": "#/definitions/account.GetByIDResponse"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/accounttype.GetByNameResponse"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/httperror.Response"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/httperror.Response"
                         }
                     }
                 }
             },
             "put": {
-                "description": "Update an account with specified details",
+                "description": "Replace the definition of an account type. Stored accounts are validated against it when they are next changed",
                 "consumes": [
                     "application/json"
                 ],
@@ -146,56 +146,56 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "accounts"
+                    "account-types"
                 ],
-                "summary": "Update an account",
+                "summary": "Replace an account type",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Account ID",
-                        "name": "id",
+                        "description": "Account type name",
+                        "name": "name",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Account to update",
-                        "name": "account",
+                        "description": "Account type definition",
+                        "name": "accountType",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/account.UpdateRequest"
+                            "$ref": "#/definitions/accounttype.UpdateRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Updated account data",
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/account.UpdateResponse"
+                            "$ref": "#/definitions/accounttype.UpdateResponse"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/httperror.Response"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/httperror.Response"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/httperror.Response"
                         }
                     }
                 }
             },
             "delete": {
-                "description": "Delete an account",
+                "description": "Delete an account type that no account has",
                 "consumes": [
                     "application/json"
                 ],
@@ -203,14 +203,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "accounts"
+                    "account-types"
                 ],
-                "summary": "Delete an account",
+                "summary": "Delete an account type",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Account ID to delete",
-                        "name": "id",
+                        "description": "Account type name",
+                        "name": "name",
                         "in": "path",
                         "required": true
                     }
@@ -219,27 +219,33 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/account.DeleteResponse"
+                            "$ref": "#/definitions/accounttype.DeleteResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Account type is in use",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/httperror.Response"
                         }
                     }
                 }
             }
         },
-        "/nginx": {
+        "/accounts": {
             "get": {
-                "description": "Makes an HTTP GET request to Nginx and returns the response body as a string.",
+                "description": "Retrieve a page of accounts with secret fields masked",
                 "consumes": [
                     "application/json"
                 ],
@@ -247,103 +253,2573 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "nginx"
+                    "accounts"
+                ],
+                "summary": "Get all accounts",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Account type",
+                        "name": "account_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Email, case insensitive",
+                        "name": "email",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created at or after, RFC 3339",
+                        "name": "created_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created before, RFC 3339",
+                        "name": "created_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Earliest cookie expiry before, RFC 3339",
+                        "name": "cookies_expiring_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Non-secret attribute value as name:value, all must match",
+                        "name": "attribute",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Tag",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Whether accounts must have all tags or any of them, all by default",
+                        "name": "tag_match",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort field, created_at or name, prefixed with - for descending order",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor from the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Get data from Nginx",
                 "responses": {
                     "200": {
-                        "description": "Response body from Nginx",
+                        "description": "Page of accounts",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/account.GetAllResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad request",
+                        "description": "Bad Request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/httperror.Response"
                         }
                     },
                     "500": {
-                        "description": "Internal server error",
+                        "description": "Internal Server Error",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/httperror.Response"
                         }
                     }
                 }
-            }
-        }
-    },
-    "definitions": {
-        "account.CreateRequest": {
-            "type": "object",
-            "properties": {
-                "account": {
-                    "$ref": "#/definitions/model.AccountCreate"
-                }
-            }
-        },
-        "account.CreateResponse": {
-            "type": "object",
-            "properties": {
-                "error": {},
-                "id": {
-                    "type": "string"
+            },
+            "post": {
+                "description": "Create a new account with the specified details",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "accounts"
+                ],
+                "summary": "Create a new account",
+                "parameters": [
+                    {
+                        "description": "Account to create",
+                        "name": "account",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/account.CreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/account.CreateResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    }
                 }
             }
         },
-        "account.DeleteResponse": {
-            "type": "object",
-            "properties": {
-                "error": {}
-            }
-        },
-        "account.GetAllResponse": {
-            "type": "object",
-            "properties": {
-                "accounts": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/model.Account"
+        "/accounts/export": {
+            "get": {
+                "description": "Stream the accounts matching the filter as CSV, with their secrets in plaintext. Every exported account with secrets is audited before it is written, the export stops when the audit fails",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "accounts"
+                ],
+                "summary": "Export accounts as CSV",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Export format, only csv",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated columns, all by default",
+                        "name": "columns",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Field delimiter, a comma by default",
+                        "name": "delimiter",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Write a header line, true by default",
+                        "name": "header",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Account type",
+                        "name": "account_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Email, case insensitive",
+                        "name": "email",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created at or after, RFC 3339",
+                        "name": "created_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created before, RFC 3339",
+                        "name": "created_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Earliest cookie expiry before, RFC 3339",
+                        "name": "cookies_expiring_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Non-secret attribute value as name:value, all must match",
+                        "name": "attribute",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Tag",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Whether accounts must have all tags or any of them, all by default",
+                        "name": "tag_match",
+                        "in": "query"
                     }
-                },
-                "error": {}
+                ],
+                "responses": {
+                    "200": {
+                        "description": "CSV of the accounts",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    }
+                }
             }
         },
-        "account.GetByIDResponse": {
-            "type": "object",
-            "properties": {
-                "account": {
-                    "$ref": "#/definitions/model.Account"
+        "/accounts/import": {
+            "post": {
+                "description": "Create accounts from CSV lines. Columns come from the header line or the columns parameter, e.g. login,password,email,email_password with delimiter \":\". Lines failing validation are reported and skipped",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "accounts"
+                ],
+                "summary": "Import accounts from CSV",
+                "parameters": [
+                    {
+                        "description": "CSV lines",
+                        "name": "csv",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Field delimiter, a comma by default, \\t for tab",
+                        "name": "delimiter",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated columns of the lines, - skips a column",
+                        "name": "columns",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Skip the first line when columns are given",
+                        "name": "header",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only validate the lines",
+                        "name": "dry_run",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Result of every line",
+                        "schema": {
+                            "$ref": "#/definitions/account.ImportResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/accounts/lease": {
+            "post": {
+                "description": "Atomically reserve a free account, optionally of a given type and status, for the caller until the lease expires",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "leases"
+                ],
+                "summary": "Lease an account",
+                "parameters": [
+                    {
+                        "description": "Lease filter and ttl in seconds",
+                        "name": "lease",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/account.LeaseRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Lease and leased account",
+                        "schema": {
+                            "$ref": "#/definitions/account.LeaseResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "No account available",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/accounts/trash": {
+            "get": {
+                "description": "Retrieve a page of the accounts in the trash with secret fields masked",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "accounts"
+                ],
+                "summary": "List deleted accounts",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Account type",
+                        "name": "account_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Email, case insensitive",
+                        "name": "email",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created at or after, RFC 3339",
+                        "name": "created_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created before, RFC 3339",
+                        "name": "created_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Non-secret attribute value as name:value, all must match",
+                        "name": "attribute",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Tag",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Whether accounts must have all tags or any of them, all by default",
+                        "name": "tag_match",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort field, created_at or name, prefixed with - for descending order",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor from the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Page of deleted accounts",
+                        "schema": {
+                            "$ref": "#/definitions/account.TrashResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/accounts/{id}": {
+            "get": {
+                "description": "Retrieve an account by its unique identifier with secret fields masked",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "accounts"
+                ],
+                "summary": "Get account by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Account ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Account data",
+                        "schema": {
+                            "$ref": "#/definitions/account.GetByIDResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Account version"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Replace every field of an account, omitted fields are cleared",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "accounts"
+                ],
+                "summary": "Replace an account",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Account ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the account",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Reason of a status change",
+                        "name": "X-Status-Reason",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Account to update",
+                        "name": "account",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/account.UpdateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated account data",
+                        "schema": {
+                            "$ref": "#/definitions/account.UpdateResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Account version"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Status change not allowed",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "412": {
+                        "description": "Account has changed",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "428": {
+                        "description": "If-Match is missing",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Move an account to the trash, it can be restored until it is purged",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "accounts"
+                ],
+                "summary": "Delete an account",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Account ID to delete",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the account",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/account.DeleteResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "412": {
+                        "description": "Account has changed",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "428": {
+                        "description": "If-Match is missing",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Apply a JSON merge patch (RFC 7396) to an account, null clears a field",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "accounts"
+                ],
+                "summary": "Patch an account",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Account ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the account",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Reason of a status change",
+                        "name": "X-Status-Reason",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Merge patch of the account",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/account.UpdateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/account.PatchResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Account version"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Status change not allowed",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "412": {
+                        "description": "Account has changed",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "428": {
+                        "description": "If-Match is missing",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/accounts/{id}/cookies": {
+            "get": {
+                "description": "Retrieve the cookies of an account as browser extension JSON or Netscape cookies.txt. Every read is audited as a reveal",
+                "produces": [
+                    "application/json",
+                    "text/plain"
+                ],
+                "tags": [
+                    "cookies"
+                ],
+                "summary": "Get the cookie jar of an account",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Account ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cookie format, json or netscape",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Cookie jar",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "object"
+                            }
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Account version"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Stored cookie is not a cookie jar",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Replace the cookies of an account with a jar in browser extension JSON or Netscape cookies.txt",
+                "consumes": [
+                    "application/json",
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cookies"
+                ],
+                "summary": "Replace the cookie jar of an account",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Account ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the account",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cookie format, json or netscape",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Cookie jar",
+                        "name": "cookies",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/account.CookiesResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Account version"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "412": {
+                        "description": "Account has changed",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "428": {
+                        "description": "If-Match is missing",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Add cookies to an account, replacing those with the same domain, path and name. Expired cookies remove their match",
+                "consumes": [
+                    "application/json",
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cookies"
+                ],
+                "summary": "Merge cookies into the cookie jar of an account",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Account ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the account",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cookie format, json or netscape",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Cookies to merge",
+                        "name": "cookies",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/account.CookiesResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Account version"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Stored cookie is not a cookie jar",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "412": {
+                        "description": "Account has changed",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "428": {
+                        "description": "If-Match is missing",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/accounts/{id}/history": {
+            "get": {
+                "description": "Retrieve the replaced versions of the secret fields of an account with secrets masked, oldest first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "accounts"
+                ],
+                "summary": "Get the credential history of an account",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Account ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/account.CredentialHistoryResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/accounts/{id}/lease/release": {
+            "post": {
+                "description": "Release a lease so the account can be leased again",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "leases"
+                ],
+                "summary": "Release a lease",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Account ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Lease ID",
+                        "name": "lease",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/account.ReleaseLeaseRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/account.ReleaseLeaseResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Lease is not held",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/accounts/{id}/lease/renew": {
+            "post": {
+                "description": "Extend a lease that has not expired yet",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "leases"
+                ],
+                "summary": "Renew a lease",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Account ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Lease ID and new ttl in seconds",
+                        "name": "lease",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/account.RenewLeaseRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Renewed lease",
+                        "schema": {
+                            "$ref": "#/definitions/account.RenewLeaseResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Lease is not held",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/accounts/{id}/restore": {
+            "post": {
+                "description": "Take an account out of the trash",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "accounts"
+                ],
+                "summary": "Restore a deleted account",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Account ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/account.RestoreResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New account version"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/accounts/{id}/reveal": {
+            "post": {
+                "description": "Retrieve an account with its secret fields in plaintext. Every reveal is audited",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "accounts"
+                ],
+                "summary": "Reveal account secrets",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Account ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Account data with secrets",
+                        "schema": {
+                            "$ref": "#/definitions/account.RevealResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/accounts/{id}/rollback/{version}": {
+            "post": {
+                "description": "Restore the secret fields of an account as they were at a version from its credential history. The credentials that are replaced are kept in the history too",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "accounts"
+                ],
+                "summary": "Roll back the credentials of an account",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Account ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Version to roll back to",
+                        "name": "version",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the account",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/account.RollbackResponse"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New account version"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "412": {
+                        "description": "Account has changed",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "428": {
+                        "description": "If-Match is missing",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/accounts/{id}/status-history": {
+            "get": {
+                "description": "Retrieve every status change of an account with its time, reason and actor, oldest first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "accounts"
+                ],
+                "summary": "Get the status history of an account",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Account ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/account.StatusHistoryResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/accounts:batchCreate": {
+            "post": {
+                "description": "Create up to 5000 accounts in one transaction. In atomic mode a failing item fails the whole batch, in best_effort mode failures are reported per item",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "accounts"
+                ],
+                "summary": "Create accounts in a batch",
+                "parameters": [
+                    {
+                        "description": "Accounts to create",
+                        "name": "accounts",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/account.BatchCreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Result of every item",
+                        "schema": {
+                            "$ref": "#/definitions/account.BatchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/accounts:batchDelete": {
+            "post": {
+                "description": "Delete up to 5000 accounts, each at its expected version, in one transaction. In atomic mode a failing item fails the whole batch, in best_effort mode failures are reported per item",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "accounts"
+                ],
+                "summary": "Delete accounts in a batch",
+                "parameters": [
+                    {
+                        "description": "Accounts to delete",
+                        "name": "accounts",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/account.BatchDeleteRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Result of every item",
+                        "schema": {
+                            "$ref": "#/definitions/account.BatchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "412": {
+                        "description": "Account has changed",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/accounts:batchUpdate": {
+            "post": {
+                "description": "Replace up to 5000 accounts, each at its expected version, in one transaction. In atomic mode a failing item fails the whole batch, in best_effort mode failures are reported per item",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "accounts"
+                ],
+                "summary": "Replace accounts in a batch",
+                "parameters": [
+                    {
+                        "description": "Accounts to replace",
+                        "name": "accounts",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/account.BatchUpdateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Result of every item",
+                        "schema": {
+                            "$ref": "#/definitions/account.BatchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "412": {
+                        "description": "Account has changed",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/accounts:tag": {
+            "post": {
+                "description": "Add tags to up to 5000 accounts. Nothing changes when one of the accounts does not exist",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "accounts"
+                ],
+                "summary": "Tag accounts",
+                "parameters": [
+                    {
+                        "description": "Accounts and tags",
+                        "name": "tags",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/account.TagRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/account.TagResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/accounts:untag": {
+            "post": {
+                "description": "Remove tags from up to 5000 accounts. Nothing changes when one of the accounts does not exist",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "accounts"
+                ],
+                "summary": "Untag accounts",
+                "parameters": [
+                    {
+                        "description": "Accounts and tags",
+                        "name": "tags",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/account.TagRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/account.TagResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/api-keys": {
+            "get": {
+                "description": "Retrieve a list of all api keys without their secrets",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get all api keys",
+                "responses": {
+                    "200": {
+                        "description": "List of api keys",
+                        "schema": {
+                            "$ref": "#/definitions/apikey.GetAllResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a new api key. The key is returned only once, only its hash is stored",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Create an api key",
+                "parameters": [
+                    {
+                        "description": "API key to create",
+                        "name": "apiKey",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/apikey.CreateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apikey.CreateResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/api-keys/{id}": {
+            "delete": {
+                "description": "Revoke an api key",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Delete an api key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key ID to delete",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apikey.DeleteResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/keys/rewrap": {
+            "post": {
+                "description": "Re-wrap the data keys of all accounts with the current master key",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Re-wrap data keys",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/account.RewrapKeysResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/audit": {
+            "get": {
+                "description": "Retrieve audit records of account operations, oldest first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "audit"
+                ],
+                "summary": "Get audit records",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Account ID",
+                        "name": "account_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Actor",
+                        "name": "actor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start of the time range, RFC 3339",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End of the time range (exclusive), RFC 3339",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "List of audit records",
+                        "schema": {
+                            "$ref": "#/definitions/audit.GetAllResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/nginx": {
+            "get": {
+                "description": "Makes an HTTP GET request to Nginx and returns the response body as a string.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "nginx"
+                ],
+                "summary": "Get data from Nginx",
+                "responses": {
+                    "200": {
+                        "description": "Response body from Nginx",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/tags": {
+            "get": {
+                "description": "Retrieve every tag with the number of accounts that have it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "accounts"
+                ],
+                "summary": "Get all tags",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/account.GetTagsResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httperror.Response"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "account.BatchCreateRequest": {
+            "type": "object",
+            "properties": {
+                "accounts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.AccountCreate"
+                    }
+                },
+                "mode": {
+                    "enum": [
+                        "atomic",
+                        "best_effort"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.BatchMode"
+                        }
+                    ]
+                }
+            }
+        },
+        "account.BatchDeleteRequest": {
+            "type": "object",
+            "properties": {
+                "accounts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.AccountVersion"
+                    }
+                },
+                "mode": {
+                    "enum": [
+                        "atomic",
+                        "best_effort"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.BatchMode"
+                        }
+                    ]
+                }
+            }
+        },
+        "account.BatchItemResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "$ref": "#/definitions/httperror.Detail"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "index": {
+                    "type": "integer"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "account.BatchResponse": {
+            "type": "object",
+            "properties": {
+                "error": {},
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/account.BatchItemResult"
+                    }
+                }
+            }
+        },
+        "account.BatchUpdateRequest": {
+            "type": "object",
+            "properties": {
+                "accounts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.AccountBatchUpdate"
+                    }
+                },
+                "mode": {
+                    "enum": [
+                        "atomic",
+                        "best_effort"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.BatchMode"
+                        }
+                    ]
+                }
+            }
+        },
+        "account.CookiesResponse": {
+            "type": "object",
+            "properties": {
+                "error": {},
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "account.CreateRequest": {
+            "type": "object",
+            "properties": {
+                "account": {
+                    "$ref": "#/definitions/model.AccountCreate"
+                }
+            }
+        },
+        "account.CreateResponse": {
+            "type": "object",
+            "properties": {
+                "error": {},
+                "id": {
+                    "type": "string"
+                }
+            }
+        },
+        "account.CredentialHistoryResponse": {
+            "type": "object",
+            "properties": {
+                "credential_versions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.CredentialVersion"
+                    }
+                },
+                "error": {}
+            }
+        },
+        "account.DeleteResponse": {
+            "type": "object",
+            "properties": {
+                "error": {}
+            }
+        },
+        "account.GetAllResponse": {
+            "type": "object",
+            "properties": {
+                "accounts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Account"
+                    }
+                },
+                "error": {},
+                "next_cursor": {
+                    "type": "string"
+                }
+            }
+        },
+        "account.GetByIDResponse": {
+            "type": "object",
+            "properties": {
+                "account": {
+                    "$ref": "#/definitions/model.Account"
+                },
+                "error": {}
+            }
+        },
+        "account.GetTagsResponse": {
+            "type": "object",
+            "properties": {
+                "error": {},
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.TagCount"
+                    }
+                }
+            }
+        },
+        "account.ImportLine": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "$ref": "#/definitions/httperror.Detail"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "line": {
+                    "type": "integer"
+                }
+            }
+        },
+        "account.ImportResponse": {
+            "type": "object",
+            "properties": {
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "error": {},
+                "failed": {
+                    "type": "integer"
+                },
+                "imported": {
+                    "type": "integer"
+                },
+                "lines": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/account.ImportLine"
+                    }
+                }
+            }
+        },
+        "account.LeaseRequest": {
+            "type": "object",
+            "properties": {
+                "lease": {
+                    "$ref": "#/definitions/model.LeaseCreate"
+                }
+            }
+        },
+        "account.LeaseResponse": {
+            "type": "object",
+            "properties": {
+                "account": {
+                    "$ref": "#/definitions/model.Account"
+                },
+                "error": {},
+                "lease": {
+                    "$ref": "#/definitions/model.Lease"
+                }
+            }
+        },
+        "account.PatchResponse": {
+            "type": "object",
+            "properties": {
+                "error": {},
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "account.ReleaseLeaseRequest": {
+            "type": "object",
+            "properties": {
+                "lease_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "account.ReleaseLeaseResponse": {
+            "type": "object",
+            "properties": {
+                "error": {}
+            }
+        },
+        "account.RenewLeaseRequest": {
+            "type": "object",
+            "properties": {
+                "lease": {
+                    "$ref": "#/definitions/model.LeaseRenew"
+                }
+            }
+        },
+        "account.RenewLeaseResponse": {
+            "type": "object",
+            "properties": {
+                "error": {},
+                "lease": {
+                    "$ref": "#/definitions/model.Lease"
+                }
+            }
+        },
+        "account.RestoreResponse": {
+            "type": "object",
+            "properties": {
+                "error": {},
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "account.RevealResponse": {
+            "type": "object",
+            "properties": {
+                "account": {
+                    "$ref": "#/definitions/model.Account"
+                },
+                "error": {}
+            }
+        },
+        "account.RewrapKeysResponse": {
+            "type": "object",
+            "properties": {
+                "error": {},
+                "rewrapped": {
+                    "type": "integer"
+                }
+            }
+        },
+        "account.RollbackResponse": {
+            "type": "object",
+            "properties": {
+                "error": {},
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "account.StatusHistoryResponse": {
+            "type": "object",
+            "properties": {
+                "error": {},
+                "status_transitions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.StatusTransition"
+                    }
+                }
+            }
+        },
+        "account.TagRequest": {
+            "type": "object",
+            "properties": {
+                "account_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "account.TagResponse": {
+            "type": "object",
+            "properties": {
+                "error": {}
+            }
+        },
+        "account.TrashResponse": {
+            "type": "object",
+            "properties": {
+                "accounts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Account"
+                    }
+                },
+                "error": {},
+                "next_cursor": {
+                    "type": "string"
+                }
+            }
+        },
+        "account.UpdateRequest": {
+            "type": "object",
+            "properties": {
+                "account": {
+                    "$ref": "#/definitions/model.AccountUpdate"
+                }
+            }
+        },
+        "account.UpdateResponse": {
+            "type": "object",
+            "properties": {
+                "error": {},
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "accounttype.CreateRequest": {
+            "type": "object",
+            "properties": {
+                "account_type": {
+                    "$ref": "#/definitions/model.AccountType"
+                }
+            }
+        },
+        "accounttype.CreateResponse": {
+            "type": "object",
+            "properties": {
+                "error": {},
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "accounttype.DeleteResponse": {
+            "type": "object",
+            "properties": {
+                "error": {}
+            }
+        },
+        "accounttype.GetAllResponse": {
+            "type": "object",
+            "properties": {
+                "account_types": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.AccountType"
+                    }
                 },
                 "error": {}
             }
         },
-        "account.UpdateRequest": {
+        "accounttype.GetByNameResponse": {
+            "type": "object",
+            "properties": {
+                "account_type": {
+                    "$ref": "#/definitions/model.AccountType"
+                },
+                "error": {}
+            }
+        },
+        "accounttype.UpdateRequest": {
+            "type": "object",
+            "properties": {
+                "account_type": {
+                    "$ref": "#/definitions/model.AccountType"
+                }
+            }
+        },
+        "accounttype.UpdateResponse": {
+            "type": "object",
+            "properties": {
+                "error": {}
+            }
+        },
+        "apikey.CreateRequest": {
             "type": "object",
             "properties": {
-                "account": {
-                    "$ref": "#/definitions/model.AccountUpdate"
+                "api_key": {
+                    "$ref": "#/definitions/model.APIKeyCreate"
                 }
             }
         },
-        "account.UpdateResponse": {
+        "apikey.CreateResponse": {
+            "type": "object",
+            "properties": {
+                "error": {},
+                "id": {
+                    "type": "string"
+                },
+                "key": {
+                    "type": "string"
+                }
+            }
+        },
+        "apikey.DeleteResponse": {
+            "type": "object",
+            "properties": {
+                "error": {}
+            }
+        },
+        "apikey.GetAllResponse": {
+            "type": "object",
+            "properties": {
+                "api_keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.APIKey"
+                    }
+                },
+                "error": {}
+            }
+        },
+        "audit.GetAllResponse": {
             "type": "object",
             "properties": {
+                "audit_records": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.AuditRecord"
+                    }
+                },
                 "error": {}
             }
         },
+        "httperror.Detail": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "httperror.Response": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "$ref": "#/definitions/httperror.Detail"
+                }
+            }
+        },
+        "model.APIKey": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.APIKeyCreate": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
         "model.Account": {
             "type": "object",
             "properties": {
                 "account_type": {
                     "type": "string"
                 },
+                "attributes": {
+                    "$ref": "#/definitions/model.Attributes"
+                },
                 "cookie": {
                     "type": "string"
                 },
+                "cookies_expire_at": {
+                    "description": "CookiesExpireAt is the earliest expiry of the cookies, derived from\nCookie when the account is stored.",
+                    "type": "string"
+                },
                 "created_at": {
                     "type": "string"
                 },
+                "deleted_at": {
+                    "description": "DeletedAt is set while the account is in the trash.",
+                    "type": "string"
+                },
                 "email": {
                     "type": "string"
                 },
@@ -370,6 +2846,30 @@ const docTemplate = `{
                 },
                 "status": {
                     "type": "string"
+                },
+                "tags": {
+                    "description": "Tags are changed with the tag endpoints only, sorted, and do not\nchange the version.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.AccountBatchUpdate": {
+            "type": "object",
+            "properties": {
+                "account": {
+                    "$ref": "#/definitions/model.AccountUpdate"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
                 }
             }
         },
@@ -379,6 +2879,9 @@ const docTemplate = `{
                 "account_type": {
                     "type": "string"
                 },
+                "attributes": {
+                    "$ref": "#/definitions/model.Attributes"
+                },
                 "cookie": {
                     "type": "string"
                 },
@@ -408,12 +2911,50 @@ const docTemplate = `{
                 }
             }
         },
+        "model.AccountType": {
+            "type": "object",
+            "properties": {
+                "allowed_statuses": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "attributes": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/model.AttributeDefinition"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "required_fields": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
         "model.AccountUpdate": {
             "type": "object",
             "properties": {
                 "account_type": {
                     "type": "string"
                 },
+                "attributes": {
+                    "$ref": "#/definitions/model.Attributes"
+                },
                 "cookie": {
                     "type": "string"
                 },
@@ -442,6 +2983,203 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "model.AccountVersion": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.Attribute": {
+            "type": "object",
+            "properties": {
+                "secret": {
+                    "type": "boolean"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "value": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.AttributeDefinition": {
+            "type": "object",
+            "properties": {
+                "required": {
+                    "type": "boolean"
+                },
+                "secret": {
+                    "type": "boolean"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.Attributes": {
+            "type": "object",
+            "additionalProperties": {
+                "$ref": "#/definitions/model.Attribute"
+            }
+        },
+        "model.AuditRecord": {
+            "type": "object",
+            "properties": {
+                "account_id": {
+                    "type": "string"
+                },
+                "action": {
+                    "type": "string"
+                },
+                "actor": {
+                    "type": "string"
+                },
+                "caller": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "fields": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.BatchMode": {
+            "type": "string",
+            "enum": [
+                "atomic",
+                "best_effort"
+            ],
+            "x-enum-varnames": [
+                "BatchModeAtomic",
+                "BatchModeBestEffort"
+            ]
+        },
+        "model.CredentialVersion": {
+            "type": "object",
+            "properties": {
+                "account_id": {
+                    "type": "string"
+                },
+                "actor": {
+                    "type": "string"
+                },
+                "attributes": {
+                    "$ref": "#/definitions/model.Attributes"
+                },
+                "cookie": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "description": "CreatedAt is when the version was replaced.",
+                    "type": "string"
+                },
+                "emailPassword": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                },
+                "recovery_email_password": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.Lease": {
+            "type": "object",
+            "properties": {
+                "account_id": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "holder": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.LeaseCreate": {
+            "type": "object",
+            "properties": {
+                "account_type": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "ttl": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.LeaseRenew": {
+            "type": "object",
+            "properties": {
+                "lease_id": {
+                    "type": "string"
+                },
+                "ttl": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.StatusTransition": {
+            "type": "object",
+            "properties": {
+                "account_id": {
+                    "type": "string"
+                },
+                "actor": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.TagCount": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "tag": {
+                    "type": "string"
+                }
+            }
         }
     }
 }`
@@ -5,9 +5,22 @@ go 1.22.0
 require (
 	github.com/BurntSushi/toml v1.3.2
 	github.com/google/uuid v1.6.0
+	modernc.org/sqlite v1.29.5
 )
 
-require github.com/google/go-cmp v0.5.9 // indirect
+require (
+	github.com/dustin/go-humanize v1.0.1 // indirect
+	github.com/google/go-cmp v0.5.9 // indirect
+	github.com/hashicorp/golang-lru/v2 v2.0.7 // indirect
+	github.com/ncruces/go-strftime v0.1.9 // indirect
+	github.com/remyoudompheng/bigfft v0.0.0-20230129092748-24d4a6f8daec // indirect
+	modernc.org/gc/v3 v3.0.0-20240107210532-573471604cb6 // indirect
+	modernc.org/libc v1.41.0 // indirect
+	modernc.org/mathutil v1.6.0 // indirect
+	modernc.org/memory v1.7.2 // indirect
+	modernc.org/strutil v1.2.0 // indirect
+	modernc.org/token v1.1.0 // indirect
+)
 
 require (
 	github.com/KyleBanks/depth v1.2.1 // indirect
@@ -1,15 +1,27 @@
 package apiserver
 
 import (
+	"account_storage/internal/app/encryption"
 	"account_storage/internal/app/store"
 	"account_storage/internal/app/store/localstore"
+	"account_storage/internal/app/store/migrate"
+	"account_storage/internal/app/store/sqlitestore"
 	"account_storage/internal/app/store/sqlstore"
+	"account_storage/migrations"
+	"account_storage/pkg/auth"
 	"account_storage/pkg/model/account"
+	"account_storage/pkg/model/accounttype"
+	"account_storage/pkg/model/apikey"
+	"account_storage/pkg/model/audit"
 	"account_storage/pkg/oc"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"database/sql"
 	"fmt"
+	"io"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -26,21 +38,72 @@ type server struct {
 	store  store.Store
 	config *Config
 	ctx    context.Context
+
+	adminAPIKey string
 }
 
 func NewServer(logger *logrus.Logger, ctx context.Context, config *Config) (*server, error) {
 	var store store.Store
 
+	keyProvider, err := newKeyProvider(config)
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"package":     "apiserver",
+			"function":    "NewServer",
+			"error":       err,
+			"keyProvider": config.KeyProvider,
+		}).Error("creating key provider failed")
+
+		return nil, err
+	}
+	envelope := encryption.NewEnvelope(keyProvider)
+
+	adminKey, err := adminAPIKey(config)
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"package":  "apiserver",
+			"function": "NewServer",
+			"error":    err,
+		}).Error("reading admin api key failed")
+
+		return nil, err
+	}
+
 	switch config.DatabaseType {
-	case "sql":
-		db, err := newDB(config.DatabaseURL, logger)
+	case "sql", "sqlite":
+		db, dialect, err := openDB(ctx, config, logger)
+		if err != nil {
+			return nil, err
+		}
+
+		err = migrateDB(ctx, db, dialect, envelope, logger)
 		if err != nil {
+			db.Close()
 			return nil, err
 		}
 
-		store = sqlstore.New(db, logger)
+		if dialect == migrations.DialectSQLite {
+			store = sqlitestore.New(db, logger, envelope)
+		} else {
+			store = sqlstore.New(db, logger, envelope)
+		}
 	case "local":
-		store = localstore.New(logger)
+		options, err := newLocalOptions(config)
+		if err != nil {
+			return nil, err
+		}
+
+		store, err = localstore.New(logger, envelope, options)
+		if err != nil {
+			logger.WithFields(logrus.Fields{
+				"package":  "apiserver",
+				"function": "NewServer",
+				"error":    err,
+				"path":     config.LocalPath,
+			}).Error("opening local store failed")
+
+			return nil, err
+		}
 	default:
 		return nil, fmt.Errorf("unknown database_type %s", config.DatabaseType)
 	}
@@ -51,9 +114,11 @@ func NewServer(logger *logrus.Logger, ctx context.Context, config *Config) (*ser
 		store:  store,
 		config: config,
 		ctx:    ctx,
+
+		adminAPIKey: adminKey,
 	}
 
-	err := server.configureLogger(config.LogLevel)
+	err = server.configureLogger(config.LogLevel)
 	if err != nil {
 		return nil, err
 	}
@@ -85,35 +150,150 @@ func (server *server) Start() error {
 	var accountService account.Service
 	{
 		accountRepository := server.store.Account()
-		accountService = account.NewService(accountRepository, server.logger)
+		auditRepository := server.store.Audit()
+		statusHistoryRepository := server.store.StatusHistory()
+		accountTypeRepository := server.store.AccountType()
+		credentialHistoryRepository := server.store.CredentialHistory()
+		accountService = account.NewService(accountRepository, auditRepository, statusHistoryRepository, accountTypeRepository, credentialHistoryRepository, server.config.StatusMachine, server.logger)
+	}
+
+	var apiKeyService apikey.Service
+	{
+		apiKeyRepository := server.store.APIKey()
+		apiKeyService = apikey.NewService(apiKeyRepository, server.adminAPIKey, server.logger)
+	}
+
+	var accountTypeService accounttype.Service
+	{
+		accountTypeRepository := server.store.AccountType()
+		accountRepository := server.store.Account()
+		accountTypeService = accounttype.NewService(accountTypeRepository, accountRepository, server.logger)
+	}
+
+	var auditService audit.Service
+	{
+		auditRepository := server.store.Audit()
+		auditService = audit.NewService(auditRepository, server.logger)
 	}
 
 	var accountEndpoints account.Endpoints
 	{
 		accountEndpoints = account.MakeEndpoints(accountService)
 
+		authorize := server.config.RBAC.Authorize
+
 		accountEndpoints = account.Endpoints{
-			Create:  oc.ServerEndpoint("Create")(accountEndpoints.Create),
-			GetByID: oc.ServerEndpoint("GetByID")(accountEndpoints.GetByID),
-			Update:  oc.ServerEndpoint("Update")(accountEndpoints.Update),
-			Delete:  oc.ServerEndpoint("Delete")(accountEndpoints.Delete),
-			GetAll:  oc.ServerEndpoint("GetAll")(accountEndpoints.GetAll),
+			Create:         oc.ServerEndpoint("Create")(authorize("Create")(accountEndpoints.Create)),
+			GetByID:        oc.ServerEndpoint("GetByID")(authorize("GetByID")(accountEndpoints.GetByID)),
+			Update:         oc.ServerEndpoint("Update")(authorize("Update")(accountEndpoints.Update)),
+			Patch:          oc.ServerEndpoint("Patch")(authorize("Patch")(accountEndpoints.Patch)),
+			Delete:         oc.ServerEndpoint("Delete")(authorize("Delete")(accountEndpoints.Delete)),
+			Trash:          oc.ServerEndpoint("Trash")(authorize("Trash")(accountEndpoints.Trash)),
+			Restore:        oc.ServerEndpoint("Restore")(authorize("Restore")(accountEndpoints.Restore)),
+			BatchCreate:    oc.ServerEndpoint("BatchCreate")(authorize("BatchCreate")(accountEndpoints.BatchCreate)),
+			BatchUpdate:    oc.ServerEndpoint("BatchUpdate")(authorize("BatchUpdate")(accountEndpoints.BatchUpdate)),
+			BatchDelete:    oc.ServerEndpoint("BatchDelete")(authorize("BatchDelete")(accountEndpoints.BatchDelete)),
+			GetAll:         oc.ServerEndpoint("GetAll")(authorize("GetAll")(accountEndpoints.GetAll)),
+			Import:         oc.ServerEndpoint("Import")(authorize("Import")(accountEndpoints.Import)),
+			Export:         oc.ServerEndpoint("Export")(authorize("Export")(accountEndpoints.Export)),
+			Reveal:         oc.ServerEndpoint("Reveal")(authorize("Reveal")(accountEndpoints.Reveal)),
+			GetCookies:     oc.ServerEndpoint("GetCookies")(authorize("GetCookies")(accountEndpoints.GetCookies)),
+			ReplaceCookies: oc.ServerEndpoint("ReplaceCookies")(authorize("ReplaceCookies")(accountEndpoints.ReplaceCookies)),
+			MergeCookies:   oc.ServerEndpoint("MergeCookies")(authorize("MergeCookies")(accountEndpoints.MergeCookies)),
+			StatusHistory:  oc.ServerEndpoint("StatusHistory")(authorize("StatusHistory")(accountEndpoints.StatusHistory)),
+			History:        oc.ServerEndpoint("History")(authorize("History")(accountEndpoints.History)),
+			Rollback:       oc.ServerEndpoint("Rollback")(authorize("Rollback")(accountEndpoints.Rollback)),
+			Tag:            oc.ServerEndpoint("Tag")(authorize("Tag")(accountEndpoints.Tag)),
+			Untag:          oc.ServerEndpoint("Untag")(authorize("Untag")(accountEndpoints.Untag)),
+			GetTags:        oc.ServerEndpoint("GetTags")(authorize("GetTags")(accountEndpoints.GetTags)),
+			Lease:          oc.ServerEndpoint("Lease")(authorize("Lease")(accountEndpoints.Lease)),
+			RenewLease:     oc.ServerEndpoint("RenewLease")(authorize("RenewLease")(accountEndpoints.RenewLease)),
+			ReleaseLease:   oc.ServerEndpoint("ReleaseLease")(authorize("ReleaseLease")(accountEndpoints.ReleaseLease)),
+			RewrapKeys:     oc.ServerEndpoint("RewrapKeys")(authorize("RewrapKeys")(accountEndpoints.RewrapKeys)),
 		}
 	}
+
+	var apiKeyEndpoints apikey.Endpoints
+	{
+		apiKeyEndpoints = apikey.MakeEndpoints(apiKeyService)
+
+		authorize := server.config.RBAC.Authorize
+
+		apiKeyEndpoints = apikey.Endpoints{
+			Create: oc.ServerEndpoint("CreateAPIKey")(authorize("CreateAPIKey")(apiKeyEndpoints.Create)),
+			GetAll: oc.ServerEndpoint("GetAllAPIKeys")(authorize("GetAllAPIKeys")(apiKeyEndpoints.GetAll)),
+			Delete: oc.ServerEndpoint("DeleteAPIKey")(authorize("DeleteAPIKey")(apiKeyEndpoints.Delete)),
+		}
+	}
+
+	var accountTypeEndpoints accounttype.Endpoints
+	{
+		accountTypeEndpoints = accounttype.MakeEndpoints(accountTypeService)
+
+		authorize := server.config.RBAC.Authorize
+
+		accountTypeEndpoints = accounttype.Endpoints{
+			Create:    oc.ServerEndpoint("CreateAccountType")(authorize("CreateAccountType")(accountTypeEndpoints.Create)),
+			GetAll:    oc.ServerEndpoint("GetAllAccountTypes")(authorize("GetAllAccountTypes")(accountTypeEndpoints.GetAll)),
+			GetByName: oc.ServerEndpoint("GetAccountType")(authorize("GetAccountType")(accountTypeEndpoints.GetByName)),
+			Update:    oc.ServerEndpoint("UpdateAccountType")(authorize("UpdateAccountType")(accountTypeEndpoints.Update)),
+			Delete:    oc.ServerEndpoint("DeleteAccountType")(authorize("DeleteAccountType")(accountTypeEndpoints.Delete)),
+		}
+	}
+
+	var auditEndpoints audit.Endpoints
+	{
+		auditEndpoints = audit.MakeEndpoints(auditService)
+
+		authorize := server.config.RBAC.Authorize
+
+		auditEndpoints = audit.Endpoints{
+			GetAll: oc.ServerEndpoint("GetAuditRecords")(authorize("GetAuditRecords")(auditEndpoints.GetAll)),
+		}
+	}
+
+	server.startRewrapJob(accountService)
+	server.startSessionExpiryJob(accountService)
+	server.startPurgeJob(accountService)
+	server.startSnapshotJob()
+
 	var httpHandler http.Handler
 	{
 		ocTracing := opencensus.HTTPServerTrace()
 		serverOptions := []kithttp.ServerOption{ocTracing}
-		httpHandler = account.NewGinService(accountEndpoints, serverOptions, server.logger)
+		authMiddleware := auth.Middleware(apiKeyService, server.logger)
+
+		router := account.NewGinService(accountEndpoints, serverOptions, server.logger, authMiddleware)
+		apikey.RegisterGinRoutes(router.Group("/admin", authMiddleware), apiKeyEndpoints, serverOptions, server.logger)
+		audit.RegisterGinRoutes(router.Group("", authMiddleware), auditEndpoints, serverOptions, server.logger)
+		accounttype.RegisterGinRoutes(router.Group("", authMiddleware), accountTypeEndpoints, serverOptions, server.logger)
+		httpHandler = router
+	}
+
+	tlsConfig, err := newTLSConfig(server.config)
+	if err != nil {
+		server.logger.WithFields(logrus.Fields{
+			"package":  "apiserver",
+			"function": "Start",
+			"error":    err,
+		}).Error("loading tls config failed")
+
+		return err
 	}
 
 	httpServer := &http.Server{
-		Addr:    server.config.BindAddres,
-		Handler: httpHandler,
+		Addr:      server.config.BindAddres,
+		Handler:   httpHandler,
+		TLSConfig: tlsConfig,
 	}
 
 	go func() {
-		err := httpServer.ListenAndServe()
+		var err error
+		if httpServer.TLSConfig != nil {
+			err = httpServer.ListenAndServeTLS(server.config.TLSCertFile, server.config.TLSKeyFile)
+		} else {
+			err = httpServer.ListenAndServe()
+		}
 		if err != nil && err != http.ErrServerClosed {
 			server.logger.WithFields(logrus.Fields{
 				"package":    "apiserver",
@@ -135,7 +315,7 @@ func (server *server) Start() error {
 		time.Second*time.Duration(server.config.ShutdownTimeout))
 	defer cancel()
 
-	err := httpServer.Shutdown(shutdownContext)
+	err = httpServer.Shutdown(shutdownContext)
 	if err != nil {
 		server.logger.WithFields(logrus.Fields{
 			"package":    "apiserver",
@@ -147,11 +327,349 @@ func (server *server) Start() error {
 		return err
 	}
 
+	if closer, ok := server.store.(io.Closer); ok {
+		err = closer.Close()
+		if err != nil {
+			server.logger.WithFields(logrus.Fields{
+				"package":  "apiserver",
+				"function": "Start",
+				"error":    err,
+			}).Error("closing store failed")
+
+			return err
+		}
+	}
+
 	server.logger.Debug("Server stopped gracefully")
 
 	return nil
 }
 
+// startRewrapJob periodically moves data keys wrapped by retired master keys
+// to the current one, so a rotation completes without an explicit admin call.
+func (server *server) startRewrapJob(accountService account.Service) {
+	if server.config.RewrapInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Second * time.Duration(server.config.RewrapInterval))
+
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-server.ctx.Done():
+				return
+			case <-ticker.C:
+				rewrapped, err := accountService.RewrapKeys(server.ctx)
+				if err != nil {
+					continue
+				}
+
+				if rewrapped > 0 {
+					server.logger.WithFields(logrus.Fields{
+						"package":   "apiserver",
+						"function":  "startRewrapJob",
+						"rewrapped": rewrapped,
+					}).Info("data keys rewrapped")
+				}
+			}
+		}
+	}()
+}
+
+// startSessionExpiryJob periodically marks accounts whose cookies have
+// expired, so their sessions are refreshed before they are leased again.
+func (server *server) startSessionExpiryJob(accountService account.Service) {
+	if server.config.SessionExpiryInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Second * time.Duration(server.config.SessionExpiryInterval))
+
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-server.ctx.Done():
+				return
+			case <-ticker.C:
+				expired, err := accountService.ExpireSessions(server.ctx)
+				if err != nil {
+					continue
+				}
+
+				if expired > 0 {
+					server.logger.WithFields(logrus.Fields{
+						"package":  "apiserver",
+						"function": "startSessionExpiryJob",
+						"expired":  expired,
+					}).Info("sessions expired")
+				}
+			}
+		}
+	}()
+}
+
+// startPurgeJob periodically removes for good the accounts that have been
+// in the trash for longer than the retention period.
+func (server *server) startPurgeJob(accountService account.Service) {
+	if server.config.PurgeInterval <= 0 || server.config.TrashRetention <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Second * time.Duration(server.config.PurgeInterval))
+	retention := time.Second * time.Duration(server.config.TrashRetention)
+
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-server.ctx.Done():
+				return
+			case <-ticker.C:
+				purged, err := accountService.PurgeDeleted(server.ctx, time.Now().Add(-retention))
+				if err != nil {
+					continue
+				}
+
+				if purged > 0 {
+					server.logger.WithFields(logrus.Fields{
+						"package":  "apiserver",
+						"function": "startPurgeJob",
+						"purged":   purged,
+					}).Info("deleted accounts purged")
+				}
+			}
+		}
+	}()
+}
+
+// snapshotter is a store that compacts its own write-ahead log.
+type snapshotter interface {
+	Snapshot() error
+}
+
+// startSnapshotJob periodically compacts the write-ahead log of the local
+// store into a snapshot, so it is replayed quickly on startup.
+func (server *server) startSnapshotJob() {
+	store, ok := server.store.(snapshotter)
+	if !ok || server.config.SnapshotInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Second * time.Duration(server.config.SnapshotInterval))
+
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-server.ctx.Done():
+				return
+			case <-ticker.C:
+				err := store.Snapshot()
+				if err != nil {
+					server.logger.WithFields(logrus.Fields{
+						"package":  "apiserver",
+						"function": "startSnapshotJob",
+						"error":    err,
+					}).Error("taking snapshot failed")
+				}
+			}
+		}
+	}()
+}
+
+// newLocalOptions returns where the local store keeps its data, nowhere
+// without a local_path.
+func newLocalOptions(config *Config) (localstore.Options, error) {
+	fsync, err := localstore.ParseFsyncPolicy(config.LocalFsync)
+	if err != nil {
+		return localstore.Options{}, err
+	}
+
+	fsyncInterval := config.LocalFsyncInterval
+	if fsyncInterval <= 0 {
+		fsyncInterval = 1
+	}
+
+	return localstore.Options{
+		Path:          config.LocalPath,
+		Fsync:         fsync,
+		FsyncInterval: time.Second * time.Duration(fsyncInterval),
+	}, nil
+}
+
+// newTLSConfig returns nil when TLS is not configured. With a client CA,
+// client certificates are verified when presented and identify the caller.
+func newTLSConfig(config *Config) (*tls.Config, error) {
+	if config.TLSCertFile == "" {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if config.TLSClientCAFile != "" {
+		clientCA, err := os.ReadFile(config.TLSClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading client ca file: %w", err)
+		}
+
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(clientCA) {
+			return nil, fmt.Errorf("no certificates found in client ca file %s", config.TLSClientCAFile)
+		}
+
+		tlsConfig.ClientCAs = clientCAs
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return tlsConfig, nil
+}
+
+// KeyFileEnv names the environment variable that overrides key_file, so the
+// keyring can be mounted as a secret instead of shipping with the config.
+const KeyFileEnv = "ACCOUNTS_STORAGE_KEY_FILE"
+
+func newKeyProvider(config *Config) (encryption.KeyProvider, error) {
+	switch config.KeyProvider {
+	case "", "file":
+		path := config.KeyFile
+		if envPath := os.Getenv(KeyFileEnv); envPath != "" {
+			path = envPath
+		}
+		if path == "" {
+			return nil, fmt.Errorf("key_file or %s must name the keyring", KeyFileEnv)
+		}
+		return encryption.NewFileKeyProvider(path)
+	default:
+		return nil, fmt.Errorf("unknown key_provider %s", config.KeyProvider)
+	}
+}
+
+// AdminAPIKeyEnv names the environment variable that overrides
+// admin_api_key, so the bootstrap key can come from a secret instead of the
+// config.
+const AdminAPIKeyEnv = "ACCOUNTS_STORAGE_ADMIN_API_KEY"
+
+// minAdminAPIKeyLength rejects placeholders like "change-me", the bootstrap
+// key grants full admin access.
+const minAdminAPIKeyLength = 32
+
+func adminAPIKey(config *Config) (string, error) {
+	key := config.AdminAPIKey
+	if envKey := os.Getenv(AdminAPIKeyEnv); envKey != "" {
+		key = envKey
+	}
+	if len(key) < minAdminAPIKeyLength {
+		return "", fmt.Errorf("admin_api_key or %s must hold a bootstrap key of at least %d characters", AdminAPIKeyEnv, minAdminAPIKeyLength)
+	}
+
+	return key, nil
+}
+
+// NewMigrator returns a migrator for the sql or sqlite database of config.
+// Closing it closes the database.
+func NewMigrator(ctx context.Context, logger *logrus.Logger, config *Config) (*migrate.Migrator, error) {
+	keyProvider, err := newKeyProvider(config)
+	if err != nil {
+		return nil, err
+	}
+
+	db, dialect, err := openDB(ctx, config, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	migrator, err := newMigrator(db, dialect, encryption.NewEnvelope(keyProvider), logger)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return migrator, nil
+}
+
+// requireDataKeyVersion is the migration that makes the data key of
+// accounts mandatory, the accounts without one are encrypted right before.
+const requireDataKeyVersion = "20240415103100"
+
+func newMigrator(db *sql.DB, dialect string, envelope *encryption.Envelope, logger *logrus.Logger) (*migrate.Migrator, error) {
+	migrator, err := migrate.New(db, dialect, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	migrator.BeforeUp(requireDataKeyVersion, sqlstore.BackfillDataKeys(envelope))
+
+	return migrator, nil
+}
+
+// openDB opens the database of a sql or sqlite database_type and returns
+// the migration dialect it speaks.
+func openDB(ctx context.Context, config *Config, logger *logrus.Logger) (*sql.DB, string, error) {
+	switch config.DatabaseType {
+	case "sql":
+		db, err := newDB(config.DatabaseURL, logger)
+		return db, migrations.DialectPostgres, err
+	case "sqlite":
+		path := config.SQLitePath
+		if path == "" {
+			path = "accounts_storage.db"
+		}
+
+		db, err := sqlitestore.Open(ctx, path)
+		if err != nil {
+			logger.WithFields(logrus.Fields{
+				"package":  "apiserver",
+				"function": "openDB",
+				"error":    err,
+				"path":     path,
+			}).Error("opening sqlite store failed")
+
+			return nil, "", err
+		}
+		return db, migrations.DialectSQLite, nil
+	default:
+		return nil, "", fmt.Errorf("database_type %s has no sql database", config.DatabaseType)
+	}
+}
+
+// migrateDB applies the pending migrations before the store is used.
+func migrateDB(ctx context.Context, db *sql.DB, dialect string, envelope *encryption.Envelope, logger *logrus.Logger) error {
+	migrator, err := newMigrator(db, dialect, envelope, logger)
+	if err != nil {
+		return err
+	}
+
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		logger.WithFields(logrus.Fields{
+			"package":  "apiserver",
+			"function": "migrateDB",
+			"error":    err,
+		}).Error("migrating database failed")
+
+		return err
+	}
+
+	if applied > 0 {
+		logger.WithFields(logrus.Fields{
+			"package":  "apiserver",
+			"function": "migrateDB",
+			"applied":  applied,
+		}).Info("database migrated")
+	}
+
+	return nil
+}
+
 func newDB(databaseURL string, logger *logrus.Logger) (*sql.DB, error) {
 
 	db, err := sql.Open("postgres", databaseURL)
@@ -164,16 +682,6 @@ func newDB(databaseURL string, logger *logrus.Logger) (*sql.DB, error) {
 
 		return nil, err
 	}
-	defer func() {
-		err := db.Close()
-		if err != nil {
-			logger.WithFields(logrus.Fields{
-				"package":  "apiserver",
-				"function": "Start",
-				"error":    err,
-			}).Error("closing database failed")
-		}
-	}()
 
 	err = db.Ping()
 	if err != nil {
@@ -184,6 +692,7 @@ func newDB(databaseURL string, logger *logrus.Logger) (*sql.DB, error) {
 			"error":       err,
 		}).Error("ping database failed")
 
+		db.Close()
 		return nil, err
 	}
 	return db, nil
@@ -1,11 +1,33 @@
 package apiserver
 
+import (
+	"account_storage/pkg/auth"
+	"account_storage/pkg/model"
+)
+
 type Config struct {
-	ShutdownTimeout int    `toml:"shutdown_timeout"`
-	BindAddres      string `toml:"bind_addres"`
-	LogLevel        string `toml:"log_level"`
-	DatabaseType    string `toml:"database_type"`
-	DatabaseURL     string `toml:"database_url"`
+	ShutdownTimeout       int                 `toml:"shutdown_timeout"`
+	BindAddres            string              `toml:"bind_addres"`
+	LogLevel              string              `toml:"log_level"`
+	DatabaseType          string              `toml:"database_type"`
+	DatabaseURL           string              `toml:"database_url"`
+	SQLitePath            string              `toml:"sqlite_path"`
+	LocalPath             string              `toml:"local_path"`
+	LocalFsync            string              `toml:"local_fsync"`
+	LocalFsyncInterval    int                 `toml:"local_fsync_interval"`
+	SnapshotInterval      int                 `toml:"snapshot_interval"`
+	KeyProvider           string              `toml:"key_provider"`
+	KeyFile               string              `toml:"key_file"`
+	RewrapInterval        int                 `toml:"rewrap_interval"`
+	SessionExpiryInterval int                 `toml:"session_expiry_interval"`
+	TrashRetention        int                 `toml:"trash_retention"`
+	PurgeInterval         int                 `toml:"purge_interval"`
+	AdminAPIKey           string              `toml:"admin_api_key"`
+	TLSCertFile           string              `toml:"tls_cert_file"`
+	TLSKeyFile            string              `toml:"tls_key_file"`
+	TLSClientCAFile       string              `toml:"tls_client_ca_file"`
+	RBAC                  auth.Policy         `toml:"rbac"`
+	StatusMachine         model.StatusMachine `toml:"status_machine"`
 }
 
 func NewConfig() *Config {
@@ -0,0 +1,97 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrMalformedCiphertext = errors.New("malformed ciphertext")
+)
+
+// Encryptor seals string fields with AES-GCM. Ciphertexts are stored as
+// base64(nonce || sealed), empty fields are left untouched.
+type Encryptor struct {
+	aead cipher.AEAD
+}
+
+func NewEncryptor(key []byte) (*Encryptor, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("error creating aes cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("error creating gcm: %w", err)
+	}
+
+	return &Encryptor{aead: aead}, nil
+}
+
+func (encryptor *Encryptor) Encrypt(fields ...*string) error {
+	for _, field := range fields {
+		if *field == "" {
+			continue
+		}
+
+		sealed, err := encryptor.seal([]byte(*field))
+		if err != nil {
+			return err
+		}
+
+		*field = base64.StdEncoding.EncodeToString(sealed)
+	}
+
+	return nil
+}
+
+func (encryptor *Encryptor) Decrypt(fields ...*string) error {
+	for _, field := range fields {
+		if *field == "" {
+			continue
+		}
+
+		sealed, err := base64.StdEncoding.DecodeString(*field)
+		if err != nil {
+			return fmt.Errorf("error decoding ciphertext: %w", err)
+		}
+
+		plaintext, err := encryptor.open(sealed)
+		if err != nil {
+			return err
+		}
+
+		*field = string(plaintext)
+	}
+
+	return nil
+}
+
+func (encryptor *Encryptor) seal(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, encryptor.aead.NonceSize())
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return nil, fmt.Errorf("error generating nonce: %w", err)
+	}
+
+	return encryptor.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func (encryptor *Encryptor) open(sealed []byte) ([]byte, error) {
+	nonceSize := encryptor.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return nil, ErrMalformedCiphertext
+	}
+
+	plaintext, err := encryptor.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting: %w", err)
+	}
+
+	return plaintext, nil
+}
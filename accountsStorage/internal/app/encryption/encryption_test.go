@@ -0,0 +1,98 @@
+package encryption_test
+
+import (
+	"account_storage/internal/app/encryption"
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+)
+
+func newTestEncryptor(t *testing.T) *encryption.Encryptor {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	encryptor, err := encryption.NewEncryptor(key)
+	if err != nil {
+		t.Fatalf("creating encryptor: %v", err)
+	}
+	return encryptor
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	encryptor := newTestEncryptor(t)
+
+	password, cookie, empty := "password", "session=1", ""
+	if err := encryptor.Encrypt(&password, &cookie, &empty); err != nil {
+		t.Fatalf("encrypting: %v", err)
+	}
+	if password == "password" || cookie == "session=1" {
+		t.Fatalf("fields are not encrypted: %q, %q", password, cookie)
+	}
+	if empty != "" {
+		t.Fatalf("empty field is encrypted to %q", empty)
+	}
+
+	if err := encryptor.Decrypt(&password, &cookie, &empty); err != nil {
+		t.Fatalf("decrypting: %v", err)
+	}
+	if password != "password" || cookie != "session=1" || empty != "" {
+		t.Fatalf("decrypted %q, %q, %q", password, cookie, empty)
+	}
+}
+
+func TestEncryptUsesFreshNonces(t *testing.T) {
+	encryptor := newTestEncryptor(t)
+
+	first, second := "password", "password"
+	if err := encryptor.Encrypt(&first, &second); err != nil {
+		t.Fatalf("encrypting: %v", err)
+	}
+	if first == second {
+		t.Fatal("the same plaintext encrypts to the same ciphertext")
+	}
+}
+
+func TestDecryptRejects(t *testing.T) {
+	encryptor := newTestEncryptor(t)
+
+	sealed := "password"
+	if err := encryptor.Encrypt(&sealed); err != nil {
+		t.Fatalf("encrypting: %v", err)
+	}
+	raw, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		t.Fatalf("decoding ciphertext: %v", err)
+	}
+	raw[len(raw)-1] ^= 1
+	tampered := base64.StdEncoding.EncodeToString(raw)
+
+	tests := []struct {
+		name       string
+		encryptor  *encryption.Encryptor
+		ciphertext string
+	}{
+		{name: "tampered ciphertext", encryptor: encryptor, ciphertext: tampered},
+		{name: "other key", encryptor: newTestEncryptor(t), ciphertext: sealed},
+		{name: "not base64", encryptor: encryptor, ciphertext: "not base64!"},
+		{name: "shorter than a nonce", encryptor: encryptor, ciphertext: base64.StdEncoding.EncodeToString([]byte("short"))},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			field := test.ciphertext
+			if err := test.encryptor.Decrypt(&field); err == nil {
+				t.Fatalf("decrypted to %q, want an error", field)
+			}
+			if field != test.ciphertext {
+				t.Fatalf("field changed to %q on a failed decryption", field)
+			}
+		})
+	}
+}
+
+func TestNewEncryptorRejectsWrongKeyLength(t *testing.T) {
+	if _, err := encryption.NewEncryptor([]byte("too short")); err == nil {
+		t.Fatal("got no error, want one")
+	}
+}
@@ -0,0 +1,51 @@
+// Package encryptiontest provides keyrings and envelopes for tests. Its
+// keys are fixtures, never use them outside of tests.
+package encryptiontest
+
+import (
+	"account_storage/internal/app/encryption"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// Key is a base64 encoded 32 byte master key.
+const Key = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+
+// WriteKeyring writes a keyring with keys by version and current as the
+// current version to a temporary file and returns its path.
+func WriteKeyring(t testing.TB, current int, keys map[int]string) string {
+	t.Helper()
+
+	versions := make([]int, 0, len(keys))
+	for version := range keys {
+		versions = append(versions, version)
+	}
+	sort.Ints(versions)
+
+	var keyring strings.Builder
+	fmt.Fprintf(&keyring, "current = %d\n[keys]\n", current)
+	for _, version := range versions {
+		fmt.Fprintf(&keyring, "%d = %q\n", version, keys[version])
+	}
+
+	path := filepath.Join(t.TempDir(), "keyring.toml")
+	if err := os.WriteFile(path, []byte(keyring.String()), 0o600); err != nil {
+		t.Fatalf("writing keyring: %v", err)
+	}
+	return path
+}
+
+// NewEnvelope returns an envelope over a keyring with Key as version 1.
+func NewEnvelope(t testing.TB) *encryption.Envelope {
+	t.Helper()
+
+	keyProvider, err := encryption.NewFileKeyProvider(WriteKeyring(t, 1, map[int]string{1: Key}))
+	if err != nil {
+		t.Fatalf("loading keyring: %v", err)
+	}
+	return encryption.NewEnvelope(keyProvider)
+}
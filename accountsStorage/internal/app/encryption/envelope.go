@@ -0,0 +1,102 @@
+package encryption
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+)
+
+const dataKeySize = 32
+
+// WrappedKey is a per-record data key encrypted by the master key of Version.
+type WrappedKey struct {
+	Ciphertext []byte
+	Version    int
+}
+
+// KeyProvider wraps and unwraps data keys with master keys it never hands
+// out, so a real KMS can be plugged in instead of the local key file.
+type KeyProvider interface {
+	CurrentVersion(ctx context.Context) (int, error)
+	WrapKey(ctx context.Context, dataKey []byte) (WrappedKey, error)
+	UnwrapKey(ctx context.Context, wrappedKey WrappedKey) ([]byte, error)
+	Refresh(ctx context.Context) error
+}
+
+// Envelope encrypts every record with its own data key and keeps only the
+// wrapped form of that key next to the record.
+type Envelope struct {
+	keyProvider KeyProvider
+}
+
+func NewEnvelope(keyProvider KeyProvider) *Envelope {
+	return &Envelope{
+		keyProvider: keyProvider,
+	}
+}
+
+func (envelope *Envelope) Seal(ctx context.Context, fields ...*string) (WrappedKey, error) {
+	dataKey := make([]byte, dataKeySize)
+	_, err := rand.Read(dataKey)
+	if err != nil {
+		return WrappedKey{}, fmt.Errorf("error generating data key: %w", err)
+	}
+
+	wrappedKey, err := envelope.keyProvider.WrapKey(ctx, dataKey)
+	if err != nil {
+		return WrappedKey{}, fmt.Errorf("error wrapping data key: %w", err)
+	}
+
+	encryptor, err := NewEncryptor(dataKey)
+	if err != nil {
+		return WrappedKey{}, err
+	}
+
+	err = encryptor.Encrypt(fields...)
+	if err != nil {
+		return WrappedKey{}, err
+	}
+
+	return wrappedKey, nil
+}
+
+func (envelope *Envelope) Open(ctx context.Context, wrappedKey WrappedKey, fields ...*string) error {
+	dataKey, err := envelope.keyProvider.UnwrapKey(ctx, wrappedKey)
+	if err != nil {
+		return fmt.Errorf("error unwrapping data key: %w", err)
+	}
+
+	encryptor, err := NewEncryptor(dataKey)
+	if err != nil {
+		return err
+	}
+
+	return encryptor.Decrypt(fields...)
+}
+
+// Rewrap re-encrypts the data key with the current master key. The record
+// ciphertexts stay valid because the data key itself does not change.
+func (envelope *Envelope) Rewrap(ctx context.Context, wrappedKey WrappedKey) (WrappedKey, error) {
+	dataKey, err := envelope.keyProvider.UnwrapKey(ctx, wrappedKey)
+	if err != nil {
+		return WrappedKey{}, fmt.Errorf("error unwrapping data key: %w", err)
+	}
+
+	rewrappedKey, err := envelope.keyProvider.WrapKey(ctx, dataKey)
+	if err != nil {
+		return WrappedKey{}, fmt.Errorf("error wrapping data key: %w", err)
+	}
+
+	return rewrappedKey, nil
+}
+
+// Refresh reloads the master keys and returns the version new data keys are
+// wrapped with.
+func (envelope *Envelope) Refresh(ctx context.Context) (int, error) {
+	err := envelope.keyProvider.Refresh(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("error refreshing master keys: %w", err)
+	}
+
+	return envelope.keyProvider.CurrentVersion(ctx)
+}
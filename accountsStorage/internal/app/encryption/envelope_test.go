@@ -0,0 +1,151 @@
+package encryption_test
+
+import (
+	"account_storage/internal/app/encryption"
+	"account_storage/internal/app/encryption/encryptiontest"
+	"context"
+	"encoding/base64"
+	"errors"
+	"os"
+	"testing"
+)
+
+// otherKey is a second base64 encoded 32 byte master key.
+const otherKey = "ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA="
+
+func newTestKeyProvider(t *testing.T, path string) *encryption.FileKeyProvider {
+	keyProvider, err := encryption.NewFileKeyProvider(path)
+	if err != nil {
+		t.Fatalf("loading keyring: %v", err)
+	}
+	return keyProvider
+}
+
+func TestEnvelopeSealOpen(t *testing.T) {
+	ctx := context.Background()
+	envelope := encryptiontest.NewEnvelope(t)
+
+	password, cookie := "password", "session=1"
+	dataKey, err := envelope.Seal(ctx, &password, &cookie)
+	if err != nil {
+		t.Fatalf("sealing: %v", err)
+	}
+	if password == "password" || cookie == "session=1" {
+		t.Fatalf("fields are not encrypted: %q, %q", password, cookie)
+	}
+	if dataKey.Version != 1 {
+		t.Fatalf("data key is wrapped by version %d, want 1", dataKey.Version)
+	}
+
+	if err := envelope.Open(ctx, dataKey, &password, &cookie); err != nil {
+		t.Fatalf("opening: %v", err)
+	}
+	if password != "password" || cookie != "session=1" {
+		t.Fatalf("opened %q, %q", password, cookie)
+	}
+}
+
+func TestEnvelopeOpenRejects(t *testing.T) {
+	ctx := context.Background()
+	envelope := encryptiontest.NewEnvelope(t)
+
+	password := "password"
+	dataKey, err := envelope.Seal(ctx, &password)
+	if err != nil {
+		t.Fatalf("sealing: %v", err)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(password)
+	if err != nil {
+		t.Fatalf("decoding ciphertext: %v", err)
+	}
+	sealed[len(sealed)-1] ^= 1
+	tamperedPassword := base64.StdEncoding.EncodeToString(sealed)
+
+	tamperedKey := dataKey
+	tamperedKey.Ciphertext = append([]byte{}, dataKey.Ciphertext...)
+	tamperedKey.Ciphertext[len(tamperedKey.Ciphertext)-1] ^= 1
+
+	unknownVersion := dataKey
+	unknownVersion.Version = 2
+
+	tests := []struct {
+		name     string
+		dataKey  encryption.WrappedKey
+		field    string
+		expected error
+	}{
+		{name: "tampered field", dataKey: dataKey, field: tamperedPassword},
+		{name: "tampered data key", dataKey: tamperedKey, field: password},
+		{name: "unknown key version", dataKey: unknownVersion, field: password, expected: encryption.ErrUnknownKeyVersion},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			field := test.field
+			err := envelope.Open(ctx, test.dataKey, &field)
+			if err == nil {
+				t.Fatalf("opened to %q, want an error", field)
+			}
+			if test.expected != nil && !errors.Is(err, test.expected) {
+				t.Fatalf("got %v, want %v", err, test.expected)
+			}
+		})
+	}
+}
+
+func TestEnvelopeRewrap(t *testing.T) {
+	ctx := context.Background()
+	path := encryptiontest.WriteKeyring(t, 1, map[int]string{1: encryptiontest.Key})
+	envelope := encryption.NewEnvelope(newTestKeyProvider(t, path))
+
+	password := "password"
+	dataKey, err := envelope.Seal(ctx, &password)
+	if err != nil {
+		t.Fatalf("sealing: %v", err)
+	}
+
+	rotate := func(current int, keys map[int]string) {
+		t.Helper()
+		rotated := encryptiontest.WriteKeyring(t, current, keys)
+		if err := os.Rename(rotated, path); err != nil {
+			t.Fatalf("replacing keyring: %v", err)
+		}
+	}
+
+	rotate(2, map[int]string{1: encryptiontest.Key, 2: otherKey})
+	currentVersion, err := envelope.Refresh(ctx)
+	if err != nil {
+		t.Fatalf("refreshing: %v", err)
+	}
+	if currentVersion != 2 {
+		t.Fatalf("current version is %d after the rotation, want 2", currentVersion)
+	}
+
+	rewrapped, err := envelope.Rewrap(ctx, dataKey)
+	if err != nil {
+		t.Fatalf("rewrapping: %v", err)
+	}
+	if rewrapped.Version != 2 {
+		t.Fatalf("rewrapped to version %d, want 2", rewrapped.Version)
+	}
+
+	// Once every data key is rewrapped, the retired key can go.
+	rotate(2, map[int]string{2: otherKey})
+	if _, err := envelope.Refresh(ctx); err != nil {
+		t.Fatalf("refreshing: %v", err)
+	}
+
+	opened := password
+	if err := envelope.Open(ctx, rewrapped, &opened); err != nil {
+		t.Fatalf("opening with the rewrapped data key: %v", err)
+	}
+	if opened != "password" {
+		t.Fatalf("opened %q", opened)
+	}
+
+	stale := password
+	if err := envelope.Open(ctx, dataKey, &stale); !errors.Is(err, encryption.ErrUnknownKeyVersion) {
+		t.Fatalf("opening with the retired key: got %v, want %v", err, encryption.ErrUnknownKeyVersion)
+	}
+}
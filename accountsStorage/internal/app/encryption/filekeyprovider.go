@@ -0,0 +1,126 @@
+package encryption
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+)
+
+var (
+	ErrUnknownKeyVersion = errors.New("unknown master key version")
+)
+
+// masterKeySize is the length of every master key, AES-256.
+const masterKeySize = 32
+
+type keyring struct {
+	Current int               `toml:"current"`
+	Keys    map[string]string `toml:"keys"`
+}
+
+// FileKeyProvider keeps versioned master keys in a local TOML keyring:
+//
+//	current = 2
+//	[keys]
+//	1 = "base64 encoded 32 byte key"
+//	2 = "base64 encoded 32 byte key"
+//
+// Rotating means adding a key, bumping current and calling Refresh.
+type FileKeyProvider struct {
+	sync.RWMutex
+	path       string
+	current    int
+	masterKeys map[int]*Encryptor
+}
+
+func NewFileKeyProvider(path string) (*FileKeyProvider, error) {
+	fileKeyProvider := &FileKeyProvider{
+		path: path,
+	}
+
+	err := fileKeyProvider.Refresh(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	return fileKeyProvider, nil
+}
+
+func (fileKeyProvider *FileKeyProvider) CurrentVersion(_ context.Context) (int, error) {
+	fileKeyProvider.RLock()
+	defer fileKeyProvider.RUnlock()
+
+	return fileKeyProvider.current, nil
+}
+
+func (fileKeyProvider *FileKeyProvider) WrapKey(_ context.Context, dataKey []byte) (WrappedKey, error) {
+	fileKeyProvider.RLock()
+	defer fileKeyProvider.RUnlock()
+
+	masterKey := fileKeyProvider.masterKeys[fileKeyProvider.current]
+
+	ciphertext, err := masterKey.seal(dataKey)
+	if err != nil {
+		return WrappedKey{}, err
+	}
+
+	return WrappedKey{Ciphertext: ciphertext, Version: fileKeyProvider.current}, nil
+}
+
+func (fileKeyProvider *FileKeyProvider) UnwrapKey(_ context.Context, wrappedKey WrappedKey) ([]byte, error) {
+	fileKeyProvider.RLock()
+	defer fileKeyProvider.RUnlock()
+
+	masterKey, ok := fileKeyProvider.masterKeys[wrappedKey.Version]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownKeyVersion, wrappedKey.Version)
+	}
+
+	return masterKey.open(wrappedKey.Ciphertext)
+}
+
+func (fileKeyProvider *FileKeyProvider) Refresh(_ context.Context) error {
+	var keyring keyring
+	_, err := toml.DecodeFile(fileKeyProvider.path, &keyring)
+	if err != nil {
+		return fmt.Errorf("error reading keyring %s: %w", fileKeyProvider.path, err)
+	}
+
+	masterKeys := make(map[int]*Encryptor, len(keyring.Keys))
+	for versionStr, encodedKey := range keyring.Keys {
+		version, err := strconv.Atoi(versionStr)
+		if err != nil {
+			return fmt.Errorf("error parsing key version %q: %w", versionStr, err)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(encodedKey)
+		if err != nil {
+			return fmt.Errorf("error decoding master key %d: %w", version, err)
+		}
+		if len(key) != masterKeySize {
+			return fmt.Errorf("master key %d is %d bytes, expected %d", version, len(key), masterKeySize)
+		}
+
+		masterKeys[version], err = NewEncryptor(key)
+		if err != nil {
+			return fmt.Errorf("error loading master key %d: %w", version, err)
+		}
+	}
+
+	if _, ok := masterKeys[keyring.Current]; !ok {
+		return fmt.Errorf("%w: %d", ErrUnknownKeyVersion, keyring.Current)
+	}
+
+	fileKeyProvider.Lock()
+	defer fileKeyProvider.Unlock()
+
+	fileKeyProvider.current = keyring.Current
+	fileKeyProvider.masterKeys = masterKeys
+
+	return nil
+}
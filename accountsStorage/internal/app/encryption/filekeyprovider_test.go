@@ -0,0 +1,90 @@
+package encryption_test
+
+import (
+	"account_storage/internal/app/encryption"
+	"account_storage/internal/app/encryption/encryptiontest"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileKeyProviderParsesKeyring(t *testing.T) {
+	tests := []struct {
+		name     string
+		keyring  string
+		current  int
+		wantErr  bool
+		expected error
+	}{
+		{
+			name:    "valid",
+			keyring: "current = 2\n[keys]\n1 = \"" + encryptiontest.Key + "\"\n2 = \"" + otherKey + "\"\n",
+			current: 2,
+		},
+		{
+			name:    "bad base64",
+			keyring: "current = 1\n[keys]\n1 = \"not base64!\"\n",
+			wantErr: true,
+		},
+		{
+			name:    "wrong key length",
+			keyring: "current = 1\n[keys]\n1 = \"MDEyMzQ1Njc4OWFiY2RlZg==\"\n",
+			wantErr: true,
+		},
+		{
+			name:    "version not a number",
+			keyring: "current = 1\n[keys]\none = \"" + encryptiontest.Key + "\"\n",
+			wantErr: true,
+		},
+		{
+			name:     "current version missing",
+			keyring:  "current = 2\n[keys]\n1 = \"" + encryptiontest.Key + "\"\n",
+			wantErr:  true,
+			expected: encryption.ErrUnknownKeyVersion,
+		},
+		{
+			name:    "not toml",
+			keyring: "current = \n",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "keyring.toml")
+			if err := os.WriteFile(path, []byte(test.keyring), 0o600); err != nil {
+				t.Fatalf("writing keyring: %v", err)
+			}
+
+			keyProvider, err := encryption.NewFileKeyProvider(path)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("got no error, want one")
+				}
+				if test.expected != nil && !errors.Is(err, test.expected) {
+					t.Fatalf("got %v, want %v", err, test.expected)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loading keyring: %v", err)
+			}
+
+			current, err := keyProvider.CurrentVersion(context.Background())
+			if err != nil {
+				t.Fatalf("getting current version: %v", err)
+			}
+			if current != test.current {
+				t.Fatalf("current version is %d, want %d", current, test.current)
+			}
+		})
+	}
+}
+
+func TestFileKeyProviderRequiresKeyring(t *testing.T) {
+	if _, err := encryption.NewFileKeyProvider(filepath.Join(t.TempDir(), "missing.toml")); err == nil {
+		t.Fatal("got no error, want one")
+	}
+}
@@ -0,0 +1,159 @@
+package localstore
+
+import (
+	"account_storage/pkg/model"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type AccountTypeRepository struct {
+	sync.Mutex
+	accountTypes map[string]model.AccountType
+	journal      *journal
+	logger       *logrus.Logger
+}
+
+func (accountTypeRepository *AccountTypeRepository) replay(record journalRecord) error {
+	if record.Value == nil {
+		delete(accountTypeRepository.accountTypes, record.Key)
+		return nil
+	}
+
+	var accountType model.AccountType
+	if err := json.Unmarshal(record.Value, &accountType); err != nil {
+		return fmt.Errorf("error decoding account type %s: %w", record.Key, err)
+	}
+	accountTypeRepository.accountTypes[record.Key] = accountType
+	return nil
+}
+
+// records returns the records of every account type, the caller must hold
+// the lock.
+func (accountTypeRepository *AccountTypeRepository) records() ([]journalRecord, error) {
+	var records []journalRecord
+	for name, accountType := range accountTypeRepository.accountTypes {
+		record, err := newRecord(recordKindAccountType, name, accountType)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func (accountTypeRepository *AccountTypeRepository) Create(ctx context.Context, accountType model.AccountType) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	accountTypeRepository.Lock()
+	defer accountTypeRepository.Unlock()
+
+	if _, ok := accountTypeRepository.accountTypes[accountType.Name]; ok {
+		return model.Errorf(model.ErrConflict, "account type %s already exists", accountType.Name)
+	}
+
+	now := time.Now()
+	accountType.CreatedAt = now
+	accountType.UpdatedAt = now
+	if err := accountTypeRepository.journal.put(recordKindAccountType, accountType.Name, accountType); err != nil {
+		return err
+	}
+	accountTypeRepository.accountTypes[accountType.Name] = accountType
+
+	return nil
+}
+
+func (accountTypeRepository *AccountTypeRepository) GetByName(ctx context.Context, name string) (model.AccountType, error) {
+	select {
+	case <-ctx.Done():
+		return model.AccountType{}, ctx.Err()
+	default:
+	}
+
+	accountTypeRepository.Lock()
+	defer accountTypeRepository.Unlock()
+
+	accountType, ok := accountTypeRepository.accountTypes[name]
+	if !ok {
+		return model.AccountType{}, model.Errorf(model.ErrNotFound, "no account type %s", name)
+	}
+
+	return accountType, nil
+}
+
+func (accountTypeRepository *AccountTypeRepository) GetAll(ctx context.Context) ([]model.AccountType, error) {
+	select {
+	case <-ctx.Done():
+		return []model.AccountType{}, ctx.Err()
+	default:
+	}
+
+	accountTypeRepository.Lock()
+	defer accountTypeRepository.Unlock()
+
+	accountTypes := make([]model.AccountType, 0, len(accountTypeRepository.accountTypes))
+	for _, accountType := range accountTypeRepository.accountTypes {
+		accountTypes = append(accountTypes, accountType)
+	}
+	sort.Slice(accountTypes, func(i, j int) bool {
+		return accountTypes[i].Name < accountTypes[j].Name
+	})
+
+	return accountTypes, nil
+}
+
+func (accountTypeRepository *AccountTypeRepository) Update(ctx context.Context, accountType model.AccountType) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	accountTypeRepository.Lock()
+	defer accountTypeRepository.Unlock()
+
+	existing, ok := accountTypeRepository.accountTypes[accountType.Name]
+	if !ok {
+		return model.Errorf(model.ErrNotFound, "no account type %s", accountType.Name)
+	}
+
+	accountType.CreatedAt = existing.CreatedAt
+	accountType.UpdatedAt = time.Now()
+	if err := accountTypeRepository.journal.put(recordKindAccountType, accountType.Name, accountType); err != nil {
+		return err
+	}
+	accountTypeRepository.accountTypes[accountType.Name] = accountType
+
+	return nil
+}
+
+func (accountTypeRepository *AccountTypeRepository) Delete(ctx context.Context, name string) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	accountTypeRepository.Lock()
+	defer accountTypeRepository.Unlock()
+
+	if _, ok := accountTypeRepository.accountTypes[name]; !ok {
+		return model.Errorf(model.ErrNotFound, "no account type %s", name)
+	}
+
+	if err := accountTypeRepository.journal.remove(recordKindAccountType, name); err != nil {
+		return err
+	}
+	delete(accountTypeRepository.accountTypes, name)
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package localstore
+
+import (
+	"account_storage/pkg/model"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type APIKeyRepository struct {
+	sync.Mutex
+	apiKeys map[string]model.APIKey
+	journal *journal
+	logger  *logrus.Logger
+}
+
+// journaledAPIKey is an api key as it is journaled, with its hash.
+type journaledAPIKey struct {
+	model.APIKey
+	KeyHash string `json:"key_hash"`
+}
+
+func (apiKeyRepository *APIKeyRepository) replay(record journalRecord) error {
+	if record.Value == nil {
+		delete(apiKeyRepository.apiKeys, record.Key)
+		return nil
+	}
+
+	var apiKey journaledAPIKey
+	if err := json.Unmarshal(record.Value, &apiKey); err != nil {
+		return fmt.Errorf("error decoding api key %s: %w", record.Key, err)
+	}
+	apiKey.APIKey.KeyHash = apiKey.KeyHash
+	apiKeyRepository.apiKeys[record.Key] = apiKey.APIKey
+	return nil
+}
+
+// records returns the records of every api key, the caller must hold the
+// lock.
+func (apiKeyRepository *APIKeyRepository) records() ([]journalRecord, error) {
+	var records []journalRecord
+	for id, apiKey := range apiKeyRepository.apiKeys {
+		record, err := newRecord(recordKindAPIKey, id, journaledAPIKey{APIKey: apiKey, KeyHash: apiKey.KeyHash})
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func (apiKeyRepository *APIKeyRepository) Create(ctx context.Context, apiKeyCreate model.APIKeyCreate) (string, error) {
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	default:
+	}
+
+	apiKeyRepository.Lock()
+	defer apiKeyRepository.Unlock()
+
+	for _, apiKey := range apiKeyRepository.apiKeys {
+		if apiKey.KeyHash == apiKeyCreate.KeyHash {
+			return "", model.NewError(model.ErrConflict, "api key already exists")
+		}
+	}
+
+	apiKeyID := uuid.New()
+
+	apiKey := model.APIKey{
+		ID:        apiKeyID,
+		Name:      apiKeyCreate.Name,
+		KeyHash:   apiKeyCreate.KeyHash,
+		CreatedAt: time.Now(),
+	}
+
+	stringAPIKeyID := apiKeyID.String()
+	if err := apiKeyRepository.journal.put(recordKindAPIKey, stringAPIKeyID, journaledAPIKey{APIKey: apiKey, KeyHash: apiKey.KeyHash}); err != nil {
+		return "", err
+	}
+	apiKeyRepository.apiKeys[stringAPIKeyID] = apiKey
+
+	return stringAPIKeyID, nil
+}
+
+func (apiKeyRepository *APIKeyRepository) GetByHash(ctx context.Context, keyHash string) (model.APIKey, error) {
+	select {
+	case <-ctx.Done():
+		return model.APIKey{}, ctx.Err()
+	default:
+	}
+
+	apiKeyRepository.Lock()
+	defer apiKeyRepository.Unlock()
+
+	for _, apiKey := range apiKeyRepository.apiKeys {
+		if apiKey.KeyHash == keyHash {
+			return apiKey, nil
+		}
+	}
+
+	return model.APIKey{}, model.NewError(model.ErrNotFound, "no api key with given hash")
+}
+
+func (apiKeyRepository *APIKeyRepository) GetAll(ctx context.Context) ([]model.APIKey, error) {
+	select {
+	case <-ctx.Done():
+		return []model.APIKey{}, ctx.Err()
+	default:
+	}
+
+	apiKeyRepository.Lock()
+	defer apiKeyRepository.Unlock()
+
+	apiKeys := make([]model.APIKey, 0, len(apiKeyRepository.apiKeys))
+	for _, apiKey := range apiKeyRepository.apiKeys {
+		apiKeys = append(apiKeys, apiKey)
+	}
+
+	return apiKeys, nil
+}
+
+func (apiKeyRepository *APIKeyRepository) Delete(ctx context.Context, id string) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	apiKeyRepository.Lock()
+	defer apiKeyRepository.Unlock()
+
+	_, ok := apiKeyRepository.apiKeys[id]
+	if !ok {
+		return model.Errorf(model.ErrNotFound, "no api key with id %s", id)
+	}
+
+	if err := apiKeyRepository.journal.remove(recordKindAPIKey, id); err != nil {
+		return err
+	}
+	delete(apiKeyRepository.apiKeys, id)
+
+	return nil
+}
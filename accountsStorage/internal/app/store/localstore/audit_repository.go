@@ -0,0 +1,103 @@
+package localstore
+
+import (
+	"account_storage/pkg/model"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type AuditRepository struct {
+	sync.Mutex
+	auditRecords []model.AuditRecord
+	journal      *journal
+	logger       *logrus.Logger
+}
+
+func (auditRepository *AuditRepository) replay(record journalRecord) error {
+	var auditRecord model.AuditRecord
+	if err := json.Unmarshal(record.Value, &auditRecord); err != nil {
+		return fmt.Errorf("error decoding audit record %s: %w", record.Key, err)
+	}
+	auditRepository.auditRecords = append(auditRepository.auditRecords, auditRecord)
+	return nil
+}
+
+// records returns the records of every audit record in order, the caller
+// must hold the lock.
+func (auditRepository *AuditRepository) records() ([]journalRecord, error) {
+	records := make([]journalRecord, 0, len(auditRepository.auditRecords))
+	for _, auditRecord := range auditRepository.auditRecords {
+		record, err := newRecord(recordKindAuditRecord, auditRecord.ID.String(), auditRecord)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func (auditRepository *AuditRepository) Create(ctx context.Context, auditRecordCreate model.AuditRecordCreate) (string, error) {
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	default:
+	}
+
+	auditRepository.Lock()
+	defer auditRepository.Unlock()
+
+	auditRecordID := uuid.New()
+
+	auditRecord := model.AuditRecord{
+		ID:        auditRecordID,
+		Actor:     auditRecordCreate.Actor,
+		Caller:    auditRecordCreate.Caller,
+		Action:    auditRecordCreate.Action,
+		AccountID: auditRecordCreate.AccountID,
+		Fields:    auditRecordCreate.Fields,
+		CreatedAt: time.Now(),
+	}
+
+	if err := auditRepository.journal.put(recordKindAuditRecord, auditRecordID.String(), auditRecord); err != nil {
+		return "", err
+	}
+	auditRepository.auditRecords = append(auditRepository.auditRecords, auditRecord)
+
+	return auditRecordID.String(), nil
+}
+
+func (auditRepository *AuditRepository) GetAll(ctx context.Context, filter model.AuditFilter) ([]model.AuditRecord, error) {
+	select {
+	case <-ctx.Done():
+		return []model.AuditRecord{}, ctx.Err()
+	default:
+	}
+
+	auditRepository.Lock()
+	defer auditRepository.Unlock()
+
+	auditRecords := make([]model.AuditRecord, 0)
+	for _, auditRecord := range auditRepository.auditRecords {
+		if filter.AccountID != "" && auditRecord.AccountID != filter.AccountID {
+			continue
+		}
+		if filter.Actor != "" && auditRecord.Actor != filter.Actor {
+			continue
+		}
+		if !filter.From.IsZero() && auditRecord.CreatedAt.Before(filter.From) {
+			continue
+		}
+		if !filter.To.IsZero() && !auditRecord.CreatedAt.Before(filter.To) {
+			continue
+		}
+		auditRecords = append(auditRecords, auditRecord)
+	}
+
+	return auditRecords, nil
+}
@@ -0,0 +1,106 @@
+package localstore
+
+import (
+	"account_storage/pkg/model"
+	"context"
+)
+
+// accountSnapshot is what was kept of an account before a batch item changed
+// it, kept to roll back an atomic batch.
+type accountSnapshot struct {
+	id    string
+	entry accountEntry
+}
+
+func (accountRepository *AccountRepository) snapshot(id string) accountSnapshot {
+	entry, _ := accountRepository.entry(id)
+	return accountSnapshot{id: id, entry: entry}
+}
+
+// rollback sets the accounts of an atomic batch back to their snapshots in
+// memory, nothing of the batch was journaled yet.
+func (accountRepository *AccountRepository) rollback(applied []accountSnapshot) {
+	for i := len(applied) - 1; i >= 0; i-- {
+		accountRepository.set(applied[i].id, applied[i].entry)
+	}
+}
+
+func (accountRepository *AccountRepository) CreateBatch(ctx context.Context, accountCreates []model.AccountCreate, mode model.BatchMode) ([]model.BatchResult, error) {
+	return accountRepository.runBatch(ctx, len(accountCreates), mode, func(index int) (model.BatchResult, accountSnapshot, error) {
+		id, err := accountRepository.create(ctx, accountCreates[index])
+		if err != nil {
+			return model.BatchResult{}, accountSnapshot{}, err
+		}
+		return model.BatchResult{ID: id, Version: 1}, accountSnapshot{id: id}, nil
+	})
+}
+
+func (accountRepository *AccountRepository) UpdateBatch(ctx context.Context, accounts []model.Account, mode model.BatchMode) ([]model.BatchResult, error) {
+	return accountRepository.runBatch(ctx, len(accounts), mode, func(index int) (model.BatchResult, accountSnapshot, error) {
+		account := accounts[index]
+		id := account.ID.String()
+		snapshot := accountRepository.snapshot(id)
+		err := accountRepository.update(ctx, account)
+		if err != nil {
+			return model.BatchResult{ID: id}, snapshot, err
+		}
+		return model.BatchResult{ID: id, Version: account.Version + 1}, snapshot, nil
+	})
+}
+
+func (accountRepository *AccountRepository) DeleteBatch(ctx context.Context, accountVersions []model.AccountVersion, mode model.BatchMode) ([]model.BatchResult, error) {
+	return accountRepository.runBatch(ctx, len(accountVersions), mode, func(index int) (model.BatchResult, accountSnapshot, error) {
+		accountVersion := accountVersions[index]
+		snapshot := accountRepository.snapshot(accountVersion.ID)
+		err := accountRepository.delete(accountVersion.ID, accountVersion.Version)
+		return model.BatchResult{ID: accountVersion.ID}, snapshot, err
+	})
+}
+
+// runBatch applies the items of a batch under the lock. Items that fail
+// change nothing. In atomic mode the items are journaled as one record once
+// all of them are applied, and the first failing item rolls back the items
+// applied before it and its error is returned. In best effort mode its
+// error is put into its result.
+func (accountRepository *AccountRepository) runBatch(
+	ctx context.Context, size int, mode model.BatchMode, apply func(index int) (model.BatchResult, accountSnapshot, error)) ([]model.BatchResult, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	accountRepository.Lock()
+	defer accountRepository.Unlock()
+
+	if mode.Atomic() {
+		accountRepository.batch = accountRepository.journal.batch()
+		defer func() { accountRepository.batch = nil }()
+	}
+
+	results := make([]model.BatchResult, size)
+	applied := make([]accountSnapshot, 0, size)
+	for index := range results {
+		result, snapshot, err := apply(index)
+		if err != nil && mode.Atomic() {
+			accountRepository.rollback(applied)
+			return nil, model.BatchItemError(index, err)
+		}
+
+		if err != nil {
+			result.Err = err
+		} else {
+			applied = append(applied, snapshot)
+		}
+		results[index] = result
+	}
+
+	if mode.Atomic() {
+		if err := accountRepository.batch.commit(); err != nil {
+			accountRepository.rollback(applied)
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
@@ -0,0 +1,274 @@
+package localstore
+
+import (
+	"account_storage/internal/app/encryption"
+	"account_storage/pkg/model"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// credentialHistorySize is how many versions are kept per account, older
+// ones are overwritten.
+const credentialHistorySize = 20
+
+type sealedCredentialVersion struct {
+	credentialVersion model.CredentialVersion
+	dataKey           encryption.WrappedKey
+}
+
+// credentialRing is a ring buffer of the last credentialHistorySize
+// versions of an account, next is where the next version goes once it is
+// full.
+type credentialRing struct {
+	versions []sealedCredentialVersion
+	next     int
+}
+
+func (ring *credentialRing) add(version sealedCredentialVersion) {
+	if len(ring.versions) < credentialHistorySize {
+		ring.versions = append(ring.versions, version)
+		return
+	}
+	ring.versions[ring.next] = version
+	ring.next = (ring.next + 1) % credentialHistorySize
+}
+
+// clone returns a copy of the ring to change, nil stands for an empty ring.
+func (ring *credentialRing) clone() *credentialRing {
+	if ring == nil {
+		return &credentialRing{}
+	}
+	return &credentialRing{versions: append([]sealedCredentialVersion{}, ring.versions...), next: ring.next}
+}
+
+// ordered returns the versions oldest first.
+func (ring *credentialRing) ordered() []sealedCredentialVersion {
+	return append(append([]sealedCredentialVersion{}, ring.versions[ring.next:]...), ring.versions[:ring.next]...)
+}
+
+type CredentialHistoryRepository struct {
+	sync.Mutex
+	rings    map[string]*credentialRing
+	envelope *encryption.Envelope
+	journal  *journal
+	logger   *logrus.Logger
+}
+
+// journaledCredentialVersion is a sealed credential version as it is
+// journaled.
+type journaledCredentialVersion struct {
+	CredentialVersion model.CredentialVersion `json:"credential_version"`
+	DataKey           encryption.WrappedKey   `json:"data_key"`
+}
+
+// put journals ring as the credential history of an account and only then
+// keeps it, the caller must hold the lock.
+func (credentialHistoryRepository *CredentialHistoryRepository) put(accountID string, ring *credentialRing) error {
+	if err := credentialHistoryRepository.journal.put(recordKindCredentialHistory, accountID, journaledRing(ring)); err != nil {
+		return err
+	}
+	credentialHistoryRepository.rings[accountID] = ring
+	return nil
+}
+
+func journaledRing(ring *credentialRing) []journaledCredentialVersion {
+	versions := ring.ordered()
+	journaled := make([]journaledCredentialVersion, len(versions))
+	for i, version := range versions {
+		journaled[i] = journaledCredentialVersion{CredentialVersion: version.credentialVersion, DataKey: version.dataKey}
+	}
+	return journaled
+}
+
+func (credentialHistoryRepository *CredentialHistoryRepository) replay(record journalRecord) error {
+	if record.Value == nil {
+		delete(credentialHistoryRepository.rings, record.Key)
+		return nil
+	}
+
+	var journaled []journaledCredentialVersion
+	if err := json.Unmarshal(record.Value, &journaled); err != nil {
+		return fmt.Errorf("error decoding credential history of account %s: %w", record.Key, err)
+	}
+
+	ring := &credentialRing{}
+	for _, version := range journaled {
+		ring.add(sealedCredentialVersion{credentialVersion: version.CredentialVersion, dataKey: version.DataKey})
+	}
+	credentialHistoryRepository.rings[record.Key] = ring
+	return nil
+}
+
+// purge removes the credential history of a purged account. The account
+// repository lock is held by the caller and taken before this one.
+func (credentialHistoryRepository *CredentialHistoryRepository) purge(accountID string) error {
+	credentialHistoryRepository.Lock()
+	defer credentialHistoryRepository.Unlock()
+
+	if _, ok := credentialHistoryRepository.rings[accountID]; !ok {
+		return nil
+	}
+	if err := credentialHistoryRepository.journal.remove(recordKindCredentialHistory, accountID); err != nil {
+		return err
+	}
+	delete(credentialHistoryRepository.rings, accountID)
+	return nil
+}
+
+// records returns the records of every ring, the caller must hold the lock.
+func (credentialHistoryRepository *CredentialHistoryRepository) records() ([]journalRecord, error) {
+	var records []journalRecord
+	for accountID, ring := range credentialHistoryRepository.rings {
+		record, err := newRecord(recordKindCredentialHistory, accountID, journaledRing(ring))
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// Create keeps a credential version. A version that is already kept is left
+// as it is, it holds the same secrets.
+func (credentialHistoryRepository *CredentialHistoryRepository) Create(ctx context.Context, credentialVersion model.CredentialVersion) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	credentialVersion.CreatedAt = time.Now()
+
+	dataKey, err := credentialHistoryRepository.envelope.Seal(ctx, credentialVersion.Secrets()...)
+	if err != nil {
+		return fmt.Errorf("error encrypting credential version: %w", err)
+	}
+
+	credentialHistoryRepository.Lock()
+	defer credentialHistoryRepository.Unlock()
+
+	if _, ok := credentialHistoryRepository.find(credentialVersion.AccountID, credentialVersion.Version); ok {
+		return nil
+	}
+
+	ring := credentialHistoryRepository.rings[credentialVersion.AccountID].clone()
+	ring.add(sealedCredentialVersion{credentialVersion: credentialVersion, dataKey: dataKey})
+
+	return credentialHistoryRepository.put(credentialVersion.AccountID, ring)
+}
+
+func (credentialHistoryRepository *CredentialHistoryRepository) GetByAccountID(ctx context.Context, accountID string) ([]model.CredentialVersion, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	credentialHistoryRepository.Lock()
+	var versions []sealedCredentialVersion
+	if ring, ok := credentialHistoryRepository.rings[accountID]; ok {
+		versions = ring.ordered()
+	}
+	credentialHistoryRepository.Unlock()
+
+	credentialVersions := make([]model.CredentialVersion, 0, len(versions))
+	for _, version := range versions {
+		credentialVersion, err := credentialHistoryRepository.open(ctx, version)
+		if err != nil {
+			return nil, err
+		}
+		credentialVersions = append(credentialVersions, credentialVersion)
+	}
+
+	return credentialVersions, nil
+}
+
+func (credentialHistoryRepository *CredentialHistoryRepository) GetVersion(ctx context.Context, accountID string, version int64) (model.CredentialVersion, error) {
+	select {
+	case <-ctx.Done():
+		return model.CredentialVersion{}, ctx.Err()
+	default:
+	}
+
+	credentialHistoryRepository.Lock()
+	found, ok := credentialHistoryRepository.find(accountID, version)
+	credentialHistoryRepository.Unlock()
+
+	if !ok {
+		return model.CredentialVersion{}, model.Errorf(model.ErrNotFound, "no credential version %d of account with id %s", version, accountID)
+	}
+
+	return credentialHistoryRepository.open(ctx, found)
+}
+
+// find looks up a version of an account, the caller must hold the lock.
+func (credentialHistoryRepository *CredentialHistoryRepository) find(accountID string, version int64) (sealedCredentialVersion, bool) {
+	ring, ok := credentialHistoryRepository.rings[accountID]
+	if !ok {
+		return sealedCredentialVersion{}, false
+	}
+	for _, sealed := range ring.versions {
+		if sealed.credentialVersion.Version == version {
+			return sealed, true
+		}
+	}
+	return sealedCredentialVersion{}, false
+}
+
+func (credentialHistoryRepository *CredentialHistoryRepository) open(ctx context.Context, version sealedCredentialVersion) (model.CredentialVersion, error) {
+	credentialVersion := version.credentialVersion
+	err := credentialHistoryRepository.envelope.Open(ctx, version.dataKey, credentialVersion.Secrets()...)
+	if err != nil {
+		return model.CredentialVersion{}, fmt.Errorf("error decrypting credential version %d of account with id %s: %w", credentialVersion.Version, credentialVersion.AccountID, err)
+	}
+	return credentialVersion, nil
+}
+
+func (credentialHistoryRepository *CredentialHistoryRepository) Rewrap(ctx context.Context) (int, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+
+	currentVersion, err := credentialHistoryRepository.envelope.Refresh(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	credentialHistoryRepository.Lock()
+	defer credentialHistoryRepository.Unlock()
+
+	rewrapped := 0
+	for accountID, existing := range credentialHistoryRepository.rings {
+		ring := existing.clone()
+		ringRewrapped := 0
+		for i, version := range ring.versions {
+			if version.dataKey.Version == currentVersion {
+				continue
+			}
+
+			rewrappedKey, err := credentialHistoryRepository.envelope.Rewrap(ctx, version.dataKey)
+			if err != nil {
+				return rewrapped, fmt.Errorf("error rewrapping data key of credential version %d of account with id %s: %w", version.credentialVersion.Version, accountID, err)
+			}
+
+			ring.versions[i].dataKey = rewrappedKey
+			ringRewrapped++
+		}
+
+		if ringRewrapped > 0 {
+			if err := credentialHistoryRepository.put(accountID, ring); err != nil {
+				return rewrapped, err
+			}
+			rewrapped += ringRewrapped
+		}
+	}
+
+	return rewrapped, nil
+}
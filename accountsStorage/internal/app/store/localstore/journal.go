@@ -0,0 +1,503 @@
+package localstore
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FsyncPolicy is when the write-ahead log is synced to disk.
+type FsyncPolicy string
+
+const (
+	// FsyncAlways syncs every record before the change is acknowledged.
+	FsyncAlways FsyncPolicy = "always"
+	// FsyncInterval syncs in the background every Options.FsyncInterval
+	// if records were written since the last sync, a crash loses the
+	// changes since then.
+	FsyncInterval FsyncPolicy = "interval"
+	// FsyncNever leaves syncing to the operating system.
+	FsyncNever FsyncPolicy = "never"
+)
+
+// ParseFsyncPolicy parses a fsync policy, the default is FsyncAlways.
+func ParseFsyncPolicy(policy string) (FsyncPolicy, error) {
+	switch FsyncPolicy(policy) {
+	case "", FsyncAlways:
+		return FsyncAlways, nil
+	case FsyncInterval, FsyncNever:
+		return FsyncPolicy(policy), nil
+	default:
+		return "", fmt.Errorf("unknown fsync policy %s, expected always, interval or never", policy)
+	}
+}
+
+// Options configure where the store keeps its data. Without a Path
+// everything is kept in memory only.
+type Options struct {
+	// Path is the directory of the write-ahead log and the snapshot.
+	Path          string
+	Fsync         FsyncPolicy
+	FsyncInterval time.Duration
+}
+
+const (
+	journalFile  = "wal.log"
+	snapshotFile = "snapshot.log"
+
+	// recordHeaderSize is the length and the CRC-32 of the payload that
+	// precede every record.
+	recordHeaderSize = 8
+	maxRecordSize    = 64 << 20
+
+	// defaultFsyncInterval is used by FsyncInterval without an interval.
+	defaultFsyncInterval = time.Second
+)
+
+// Kinds of the records, one per kind of entity.
+const (
+	recordKindSnapshot          = "snapshot"
+	recordKindAccount           = "account"
+	recordKindAPIKey            = "api_key"
+	recordKindAuditRecord       = "audit_record"
+	recordKindStatusTransition  = "status_transition"
+	recordKindAccountType       = "account_type"
+	recordKindCredentialHistory = "credential_history"
+	// recordKindBatch holds the records of an atomic batch.
+	recordKindBatch = "batch"
+)
+
+// journalRecord is the whole state of one entity after a change, a record
+// without a value removes the entity. Seq numbers the records of the log,
+// so those a snapshot already contains are skipped when it is replayed.
+type journalRecord struct {
+	Seq   uint64          `json:"seq"`
+	Kind  string          `json:"kind"`
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// snapshotHeader starts a snapshot, Seq is the last record of the log the
+// snapshot contains.
+type snapshotHeader struct {
+	Seq uint64 `json:"seq"`
+}
+
+var errCorruptRecord = errors.New("corrupt record")
+
+// journal is an append-only write-ahead log of journalRecords. A failed
+// write leaves the log in an unknown state, so every later write fails
+// with the same error and the store stops accepting changes.
+type journal struct {
+	sync.Mutex
+	dir   string
+	file  *os.File
+	seq   uint64
+	fsync FsyncPolicy
+	// dirty is set when records were written since the last sync.
+	dirty bool
+	err   error
+	// stop ends the background sync of FsyncInterval, done is closed when
+	// it has ended.
+	stop chan struct{}
+	done chan struct{}
+}
+
+// put records the state of an entity. A nil journal keeps nothing, as the
+// store does without a path.
+func (journal *journal) put(kind, key string, value interface{}) error {
+	if journal == nil {
+		return nil
+	}
+
+	record, err := newRecord(kind, key, value)
+	if err != nil {
+		return err
+	}
+
+	return journal.write(record)
+}
+
+// remove records that an entity is gone.
+func (journal *journal) remove(kind, key string) error {
+	if journal == nil {
+		return nil
+	}
+
+	return journal.write(journalRecord{Kind: kind, Key: key})
+}
+
+// recordWriter takes the records of changes, a journal writes them right
+// away and a journalBatch once the batch is done.
+type recordWriter interface {
+	put(kind, key string, value interface{}) error
+	remove(kind, key string) error
+}
+
+// journalBatch collects the records of an atomic batch. commit writes them
+// as one record, so a crash never leaves part of the batch.
+type journalBatch struct {
+	journal *journal
+	records []journalRecord
+}
+
+func (journal *journal) batch() *journalBatch {
+	return &journalBatch{journal: journal}
+}
+
+func (batch *journalBatch) put(kind, key string, value interface{}) error {
+	if batch.journal == nil {
+		return nil
+	}
+
+	record, err := newRecord(kind, key, value)
+	if err != nil {
+		return err
+	}
+
+	batch.records = append(batch.records, record)
+	return nil
+}
+
+func (batch *journalBatch) remove(kind, key string) error {
+	if batch.journal == nil {
+		return nil
+	}
+
+	batch.records = append(batch.records, journalRecord{Kind: kind, Key: key})
+	return nil
+}
+
+func (batch *journalBatch) commit() error {
+	if batch.journal == nil || len(batch.records) == 0 {
+		return nil
+	}
+
+	record, err := newRecord(recordKindBatch, "", batch.records)
+	if err != nil {
+		return err
+	}
+
+	return batch.journal.write(record)
+}
+
+func (journal *journal) write(record journalRecord) error {
+	journal.Lock()
+	defer journal.Unlock()
+
+	if journal.err != nil {
+		return journal.err
+	}
+
+	record.Seq = journal.seq + 1
+	frame, err := encodeRecord(record)
+	if err != nil {
+		return err
+	}
+
+	if _, err := journal.file.Write(frame); err != nil {
+		journal.err = fmt.Errorf("error writing to the write-ahead log: %w", err)
+		return journal.err
+	}
+	journal.seq = record.Seq
+
+	journal.dirty = true
+	if journal.fsync == FsyncAlways {
+		return journal.sync()
+	}
+
+	return nil
+}
+
+// sync syncs the log, the caller must hold the lock.
+func (journal *journal) sync() error {
+	if err := journal.file.Sync(); err != nil {
+		journal.err = fmt.Errorf("error syncing the write-ahead log: %w", err)
+		return journal.err
+	}
+	journal.dirty = false
+	return nil
+}
+
+// syncEvery syncs the log every interval if records were written since the
+// last sync, until stop is closed. A failed sync fails the later writes.
+func (journal *journal) syncEvery(interval time.Duration) {
+	defer close(journal.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-journal.stop:
+			return
+		case <-ticker.C:
+			journal.Lock()
+			if journal.dirty && journal.err == nil {
+				journal.sync()
+			}
+			journal.Unlock()
+		}
+	}
+}
+
+// compact writes records, the whole state of the store, to a new snapshot
+// and empties the log. The caller must make sure no records are written
+// meanwhile.
+func (journal *journal) compact(records []journalRecord) error {
+	journal.Lock()
+	defer journal.Unlock()
+
+	if journal.err != nil {
+		return journal.err
+	}
+
+	header, err := json.Marshal(snapshotHeader{Seq: journal.seq})
+	if err != nil {
+		return fmt.Errorf("error encoding snapshot header: %w", err)
+	}
+	records = append([]journalRecord{{Kind: recordKindSnapshot, Value: header}}, records...)
+
+	temporaryPath := filepath.Join(journal.dir, snapshotFile+".tmp")
+	if err := writeRecords(temporaryPath, records); err != nil {
+		return err
+	}
+	if err := os.Rename(temporaryPath, filepath.Join(journal.dir, snapshotFile)); err != nil {
+		return fmt.Errorf("error replacing snapshot: %w", err)
+	}
+	if err := syncDir(journal.dir); err != nil {
+		return err
+	}
+
+	// The snapshot has every record of the log from here on, a crash
+	// before the log is emptied only replays them again.
+	if err := journal.file.Truncate(0); err != nil {
+		journal.err = fmt.Errorf("error truncating the write-ahead log: %w", err)
+		return journal.err
+	}
+	return journal.sync()
+}
+
+// close stops the background sync, then syncs and closes the log.
+func (journal *journal) close() error {
+	if journal.stop != nil {
+		close(journal.stop)
+		<-journal.done
+	}
+
+	journal.Lock()
+	defer journal.Unlock()
+
+	if err := journal.file.Sync(); err != nil {
+		journal.file.Close()
+		return fmt.Errorf("error syncing the write-ahead log: %w", err)
+	}
+	return journal.file.Close()
+}
+
+// openJournal replays the snapshot and then the log in dir with apply and
+// opens the log for writing. A torn or corrupt record at the end of the
+// log, as left by a crash during a write, is cut off together with
+// everything after it.
+func openJournal(options Options, apply func(journalRecord) error) (*journal, int64, error) {
+	if err := os.MkdirAll(options.Path, 0o700); err != nil {
+		return nil, 0, fmt.Errorf("error creating data directory: %w", err)
+	}
+
+	var seq uint64
+	snapshot, err := os.Open(filepath.Join(options.Path, snapshotFile))
+	switch {
+	case err == nil:
+		_, err = readRecords(snapshot, func(record journalRecord) error {
+			if record.Kind == recordKindSnapshot {
+				var header snapshotHeader
+				if err := json.Unmarshal(record.Value, &header); err != nil {
+					return fmt.Errorf("error decoding snapshot header: %w", err)
+				}
+				seq = header.Seq
+				return nil
+			}
+			return apply(record)
+		})
+		snapshot.Close()
+		if err != nil {
+			return nil, 0, fmt.Errorf("error reading snapshot: %w", err)
+		}
+	case !os.IsNotExist(err):
+		return nil, 0, fmt.Errorf("error opening snapshot: %w", err)
+	}
+
+	file, err := os.OpenFile(filepath.Join(options.Path, journalFile), os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error opening the write-ahead log: %w", err)
+	}
+
+	valid, err := readRecords(file, func(record journalRecord) error {
+		if record.Seq <= seq {
+			return nil
+		}
+		seq = record.Seq
+		return apply(record)
+	})
+
+	var cut int64
+	if errors.Is(err, errCorruptRecord) {
+		end, seekErr := file.Seek(0, io.SeekEnd)
+		if seekErr != nil {
+			file.Close()
+			return nil, 0, fmt.Errorf("error reading the write-ahead log: %w", seekErr)
+		}
+		cut = end - valid
+
+		if err := file.Truncate(valid); err != nil {
+			file.Close()
+			return nil, 0, fmt.Errorf("error truncating the write-ahead log: %w", err)
+		}
+		if err := file.Sync(); err != nil {
+			file.Close()
+			return nil, 0, fmt.Errorf("error syncing the write-ahead log: %w", err)
+		}
+	} else if err != nil {
+		file.Close()
+		return nil, 0, fmt.Errorf("error reading the write-ahead log: %w", err)
+	}
+
+	journal := &journal{
+		dir:   options.Path,
+		file:  file,
+		seq:   seq,
+		fsync: options.Fsync,
+	}
+	if options.Fsync == FsyncInterval {
+		interval := options.FsyncInterval
+		if interval <= 0 {
+			interval = defaultFsyncInterval
+		}
+		journal.stop = make(chan struct{})
+		journal.done = make(chan struct{})
+		go journal.syncEvery(interval)
+	}
+
+	return journal, cut, nil
+}
+
+// encodeRecord frames a record as its length, the CRC-32 of its payload and
+// the payload.
+func encodeRecord(record journalRecord) ([]byte, error) {
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding %s %s: %w", record.Kind, record.Key, err)
+	}
+
+	frame := make([]byte, recordHeaderSize, recordHeaderSize+len(payload))
+	binary.LittleEndian.PutUint32(frame[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(frame[4:8], crc32.ChecksumIEEE(payload))
+	return append(frame, payload...), nil
+}
+
+// readRecords calls fn with every record of r and returns the offset after
+// the last complete, intact record. A record that is torn or does not
+// match its checksum fails with errCorruptRecord.
+func readRecords(r io.Reader, fn func(journalRecord) error) (int64, error) {
+	reader := bufio.NewReader(r)
+	header := make([]byte, recordHeaderSize)
+
+	var offset int64
+	for {
+		_, err := io.ReadFull(reader, header)
+		if err == io.EOF {
+			return offset, nil
+		}
+		if err == io.ErrUnexpectedEOF {
+			return offset, fmt.Errorf("%w: torn header at offset %d", errCorruptRecord, offset)
+		}
+		if err != nil {
+			return offset, err
+		}
+
+		size := binary.LittleEndian.Uint32(header[0:4])
+		if size > maxRecordSize {
+			return offset, fmt.Errorf("%w: record of %d bytes at offset %d", errCorruptRecord, size, offset)
+		}
+
+		payload := make([]byte, size)
+		_, err = io.ReadFull(reader, payload)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return offset, fmt.Errorf("%w: torn record at offset %d", errCorruptRecord, offset)
+		}
+		if err != nil {
+			return offset, err
+		}
+		if crc32.ChecksumIEEE(payload) != binary.LittleEndian.Uint32(header[4:8]) {
+			return offset, fmt.Errorf("%w: checksum mismatch at offset %d", errCorruptRecord, offset)
+		}
+
+		var record journalRecord
+		if err := json.Unmarshal(payload, &record); err != nil {
+			return offset, fmt.Errorf("%w: %v at offset %d", errCorruptRecord, err, offset)
+		}
+
+		if err := fn(record); err != nil {
+			return offset, err
+		}
+		offset += int64(recordHeaderSize + len(payload))
+	}
+}
+
+// writeRecords writes records to a new file at path and syncs it.
+func writeRecords(path string, records []journalRecord) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("error creating snapshot: %w", err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	for _, record := range records {
+		frame, err := encodeRecord(record)
+		if err != nil {
+			return err
+		}
+		if _, err := writer.Write(frame); err != nil {
+			return fmt.Errorf("error writing snapshot: %w", err)
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("error writing snapshot: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("error syncing snapshot: %w", err)
+	}
+	return file.Close()
+}
+
+func syncDir(dir string) error {
+	file, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("error opening data directory: %w", err)
+	}
+	defer file.Close()
+
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("error syncing data directory: %w", err)
+	}
+	return nil
+}
+
+// newRecord returns the record of an entity for a snapshot.
+func newRecord(kind, key string, value interface{}) (journalRecord, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return journalRecord{}, fmt.Errorf("error encoding %s %s: %w", kind, key, err)
+	}
+	return journalRecord{Kind: kind, Key: key, Value: data}, nil
+}
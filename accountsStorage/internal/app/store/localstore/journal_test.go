@@ -0,0 +1,181 @@
+package localstore_test
+
+import (
+	"account_storage/internal/app/encryption/encryptiontest"
+	"account_storage/pkg/model"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// lastRecord returns the offset and the payload size of the last record of
+// the write-ahead log at path.
+func lastRecord(t *testing.T, path string) (int64, int64) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading write-ahead log: %v", err)
+	}
+
+	var offset, size int64
+	for next := int64(0); next < int64(len(data)); next += 8 + size {
+		offset = next
+		size = int64(binary.LittleEndian.Uint32(data[next : next+4]))
+	}
+	return offset, size
+}
+
+func TestReplayCutsOffTornWriteAheadLog(t *testing.T) {
+	tests := []struct {
+		name string
+		// keep returns how much of the last record is left, out of its
+		// header of 8 bytes and its payload.
+		keep func(size int64) int64
+	}{
+		{
+			name: "torn header",
+			keep: func(size int64) int64 { return 3 },
+		},
+		{
+			name: "torn payload",
+			keep: func(size int64) int64 { return 8 + size/2 },
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			envelope := encryptiontest.NewEnvelope(t)
+			path := t.TempDir()
+
+			store := openTestStore(t, newTestLogger(), envelope, path)
+			kept, err := store.Account().Create(ctx, testAccountCreate())
+			if err != nil {
+				t.Fatalf("creating account: %v", err)
+			}
+			torn, err := store.Account().Create(ctx, testAccountCreate())
+			if err != nil {
+				t.Fatalf("creating account: %v", err)
+			}
+			if err := store.Close(); err != nil {
+				t.Fatalf("closing local store: %v", err)
+			}
+
+			walPath := filepath.Join(path, "wal.log")
+			offset, size := lastRecord(t, walPath)
+			if err := os.Truncate(walPath, offset+test.keep(size)); err != nil {
+				t.Fatalf("truncating write-ahead log: %v", err)
+			}
+
+			var output bytes.Buffer
+			logger := logrus.New()
+			logger.SetOutput(&output)
+
+			reopened := openTestStore(t, logger, envelope, path)
+			defer reopened.Close()
+
+			if !strings.Contains(output.String(), "cut off a torn write-ahead log") {
+				t.Errorf("no warning about the torn write-ahead log, logged %q", output.String())
+			}
+			if _, err := reopened.Account().GetByID(ctx, kept); err != nil {
+				t.Errorf("getting account written before the torn record: %v", err)
+			}
+			if _, err := reopened.Account().GetByID(ctx, torn); !errors.Is(err, model.ErrNotFound) {
+				t.Errorf("getting account of the torn record: got %v, want not found", err)
+			}
+
+			info, err := os.Stat(walPath)
+			if err != nil {
+				t.Fatalf("reading write-ahead log: %v", err)
+			}
+			if info.Size() != offset {
+				t.Errorf("write-ahead log is %d bytes, want it cut to %d", info.Size(), offset)
+			}
+		})
+	}
+}
+
+func TestReplayDropsTornBatch(t *testing.T) {
+	ctx := context.Background()
+	envelope := encryptiontest.NewEnvelope(t)
+	path := t.TempDir()
+
+	store := openTestStore(t, newTestLogger(), envelope, path)
+	kept, err := store.Account().Create(ctx, testAccountCreate())
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+	accountCreates := []model.AccountCreate{testAccountCreate(), testAccountCreate(), testAccountCreate()}
+	results, err := store.Account().CreateBatch(ctx, accountCreates, model.BatchModeAtomic)
+	if err != nil {
+		t.Fatalf("creating accounts: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("closing local store: %v", err)
+	}
+
+	// The batch is the last record, a crash while it is written tears it
+	// after some of its items.
+	walPath := filepath.Join(path, "wal.log")
+	offset, size := lastRecord(t, walPath)
+	if err := os.Truncate(walPath, offset+8+size*2/3); err != nil {
+		t.Fatalf("truncating write-ahead log: %v", err)
+	}
+
+	reopened := openTestStore(t, newTestLogger(), envelope, path)
+	defer reopened.Close()
+
+	if _, err := reopened.Account().GetByID(ctx, kept); err != nil {
+		t.Errorf("getting account written before the batch: %v", err)
+	}
+	for i, result := range results {
+		if _, err := reopened.Account().GetByID(ctx, result.ID); !errors.Is(err, model.ErrNotFound) {
+			t.Errorf("getting account %d of the torn batch: got %v, want not found", i, err)
+		}
+	}
+}
+
+func TestFailedBatchIsNotJournaled(t *testing.T) {
+	ctx := context.Background()
+	envelope := encryptiontest.NewEnvelope(t)
+	path := t.TempDir()
+
+	store := openTestStore(t, newTestLogger(), envelope, path)
+	id, err := store.Account().Create(ctx, testAccountCreate())
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+	account, err := store.Account().GetByID(ctx, id)
+	if err != nil {
+		t.Fatalf("getting account: %v", err)
+	}
+
+	renamed := account
+	renamed.Name = "renamed"
+	stale := account
+	stale.Version = 7
+	_, err = store.Account().UpdateBatch(ctx, []model.Account{renamed, stale}, model.BatchModeAtomic)
+	if !errors.Is(err, model.ErrPreconditionFailed) {
+		t.Fatalf("updating accounts: got %v, want precondition failed", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("closing local store: %v", err)
+	}
+
+	reopened := openTestStore(t, newTestLogger(), envelope, path)
+	defer reopened.Close()
+
+	after, err := reopened.Account().GetByID(ctx, id)
+	if err != nil {
+		t.Fatalf("getting account: %v", err)
+	}
+	if after.Name != account.Name || after.Version != account.Version {
+		t.Errorf("account = %q at version %d after a failed batch, want %q at %d", after.Name, after.Version, account.Name, account.Version)
+	}
+}
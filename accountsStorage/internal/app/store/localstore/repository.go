@@ -1,12 +1,17 @@
 package localstore
 
 import (
+	"account_storage/internal/app/encryption"
 	"account_storage/pkg/model"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -17,7 +22,117 @@ import (
 type AccountRepository struct {
 	sync.Mutex
 	accounts map[string]model.Account
-	logger   *logrus.Logger
+	// trash holds the deleted accounts until they are restored or purged,
+	// their data keys stay in dataKeys.
+	trash    map[string]model.Account
+	dataKeys map[string]encryption.WrappedKey
+	leases   map[string]model.Lease
+	// statusHistory and credentialHistory drop the history of the accounts
+	// that are purged.
+	statusHistory     *StatusHistoryRepository
+	credentialHistory *CredentialHistoryRepository
+	envelope          *encryption.Envelope
+	journal           *journal
+	// batch takes the records in place of journal while an atomic batch
+	// runs.
+	batch  *journalBatch
+	logger *logrus.Logger
+}
+
+// accountEntry is everything kept of an account, as it is journaled.
+type accountEntry struct {
+	Account *model.Account         `json:"account,omitempty"`
+	Deleted *model.Account         `json:"deleted,omitempty"`
+	DataKey *encryption.WrappedKey `json:"data_key,omitempty"`
+	Lease   *model.Lease           `json:"lease,omitempty"`
+}
+
+// entry returns what is kept of an account, the caller must hold the lock.
+func (accountRepository *AccountRepository) entry(id string) (accountEntry, bool) {
+	var entry accountEntry
+	if account, ok := accountRepository.accounts[id]; ok {
+		entry.Account = &account
+	}
+	if account, ok := accountRepository.trash[id]; ok {
+		entry.Deleted = &account
+	}
+	if dataKey, ok := accountRepository.dataKeys[id]; ok {
+		entry.DataKey = &dataKey
+	}
+	if lease, ok := accountRepository.leases[id]; ok {
+		entry.Lease = &lease
+	}
+	return entry, entry != accountEntry{}
+}
+
+// set makes entry what is kept of an account, an empty entry removes it.
+// The caller must hold the lock.
+func (accountRepository *AccountRepository) set(id string, entry accountEntry) {
+	delete(accountRepository.accounts, id)
+	delete(accountRepository.trash, id)
+	delete(accountRepository.dataKeys, id)
+	delete(accountRepository.leases, id)
+	if entry.Account != nil {
+		accountRepository.accounts[id] = *entry.Account
+	}
+	if entry.Deleted != nil {
+		accountRepository.trash[id] = *entry.Deleted
+	}
+	if entry.DataKey != nil {
+		accountRepository.dataKeys[id] = *entry.DataKey
+	}
+	if entry.Lease != nil {
+		accountRepository.leases[id] = *entry.Lease
+	}
+}
+
+// put journals entry as the new state of an account and only then sets it,
+// so a change the journal fails to take is not made in memory either. The
+// caller must hold the lock.
+func (accountRepository *AccountRepository) put(id string, entry accountEntry) error {
+	var journal recordWriter = accountRepository.journal
+	if accountRepository.batch != nil {
+		journal = accountRepository.batch
+	}
+
+	var err error
+	if entry == (accountEntry{}) {
+		err = journal.remove(recordKindAccount, id)
+	} else {
+		err = journal.put(recordKindAccount, id, entry)
+	}
+	if err != nil {
+		return err
+	}
+
+	accountRepository.set(id, entry)
+	return nil
+}
+
+func (accountRepository *AccountRepository) replay(record journalRecord) error {
+	var entry accountEntry
+	if record.Value != nil {
+		if err := json.Unmarshal(record.Value, &entry); err != nil {
+			return fmt.Errorf("error decoding account %s: %w", record.Key, err)
+		}
+	}
+	accountRepository.set(record.Key, entry)
+	return nil
+}
+
+// records returns the records of every account, the caller must hold the
+// lock.
+func (accountRepository *AccountRepository) records() ([]journalRecord, error) {
+	var records []journalRecord
+	for id := range accountRepository.dataKeys {
+		entry, _ := accountRepository.entry(id)
+		record, err := newRecord(recordKindAccount, id, entry)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
 }
 
 func (accountRepository *AccountRepository) Create(ctx context.Context, accountCreate model.AccountCreate) (string, error) {
@@ -30,26 +145,31 @@ func (accountRepository *AccountRepository) Create(ctx context.Context, accountC
 	accountRepository.Lock()
 	defer accountRepository.Unlock()
 
+	return accountRepository.create(ctx, accountCreate)
+}
+
+// create adds an account, the caller must hold the lock.
+func (accountRepository *AccountRepository) create(ctx context.Context, accountCreate model.AccountCreate) (string, error) {
+	cookiesExpireAt := model.CookiesExpireAt(accountCreate.Cookie)
+
+	dataKey, err := accountRepository.envelope.Seal(ctx, accountCreate.Secrets()...)
+	if err != nil {
+		return "", fmt.Errorf("error encrypting account: %w", err)
+	}
+
 	accountID := uuid.New()
 	accountCreatedAt := time.Now()
 
-	account := model.Account{
-		ID:                    accountID,
-		Name:                  accountCreate.Name,
-		AccountType:           accountCreate.AccountType,
-		Login:                 accountCreate.Login,
-		Password:              accountCreate.Password,
-		Email:                 accountCreate.Email,
-		EmailPassword:         accountCreate.EmailPassword,
-		RecoveryEmail:         accountCreate.RecoveryEmail,
-		RecoveryEmailPassword: accountCreate.RecoveryEmailPassword,
-		Cookie:                accountCreate.Cookie,
-		Status:                accountCreate.Status,
-		CreatedAt:             accountCreatedAt,
-	}
+	account := accountCreate.Account()
+	account.ID = accountID
+	account.CreatedAt = accountCreatedAt
+	account.Version = 1
+	account.CookiesExpireAt = cookiesExpireAt
 
 	stringAccountID := accountID.String()
-	accountRepository.accounts[stringAccountID] = account
+	if err := accountRepository.put(stringAccountID, accountEntry{Account: &account, DataKey: &dataKey}); err != nil {
+		return "", err
+	}
 
 	return stringAccountID, nil
 }
@@ -66,7 +186,12 @@ func (accountRepository *AccountRepository) GetByID(ctx context.Context, id stri
 
 	account, ok := accountRepository.accounts[id]
 	if !ok {
-		return model.Account{}, fmt.Errorf("no account with id %s", id)
+		return model.Account{}, model.Errorf(model.ErrNotFound, "no account with id %s", id)
+	}
+
+	err := accountRepository.envelope.Open(ctx, accountRepository.dataKeys[id], account.Secrets()...)
+	if err != nil {
+		return model.Account{}, fmt.Errorf("error decrypting account with id %s: %w", id, err)
 	}
 
 	return account, nil
@@ -82,84 +207,480 @@ func (accountRepository *AccountRepository) Update(ctx context.Context, accountU
 	accountRepository.Lock()
 	defer accountRepository.Unlock()
 
+	return accountRepository.update(ctx, accountUpdate)
+}
+
+// update replaces an account if it is at the expected version, the caller
+// must hold the lock.
+func (accountRepository *AccountRepository) update(ctx context.Context, accountUpdate model.Account) error {
 	strID := accountUpdate.ID.String()
-	account, ok := accountRepository.accounts[strID]
+	existing, ok := accountRepository.accounts[strID]
 	if !ok {
-		return fmt.Errorf("no account with id %s", strID)
+		return model.Errorf(model.ErrNotFound, "no account with id %s", strID)
+	}
+	if existing.Version != accountUpdate.Version {
+		return versionMismatch(existing, accountUpdate.Version)
+	}
+
+	account := accountUpdate
+	account.CreatedAt = existing.CreatedAt
+	account.Tags = existing.Tags
+	account.Version = existing.Version + 1
+	account.CookiesExpireAt = model.CookiesExpireAt(account.Cookie)
+
+	dataKey, err := accountRepository.envelope.Seal(ctx, account.Secrets()...)
+	if err != nil {
+		return fmt.Errorf("error encrypting account: %w", err)
 	}
 
-	if accountUpdate.Name != "" {
-		account.Name = accountUpdate.Name
+	entry, _ := accountRepository.entry(strID)
+	entry.Account = &account
+	entry.DataKey = &dataKey
+	return accountRepository.put(strID, entry)
+}
+
+func (accountRepository *AccountRepository) Delete(ctx context.Context, id string, version int64) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
 	}
-	if accountUpdate.AccountType != "" {
-		account.AccountType = accountUpdate.AccountType
+
+	accountRepository.Lock()
+	defer accountRepository.Unlock()
+
+	return accountRepository.delete(id, version)
+}
+
+// delete moves an account to the trash if it is at the expected version,
+// the caller must hold the lock.
+func (accountRepository *AccountRepository) delete(id string, version int64) error {
+	account, ok := accountRepository.accounts[id]
+	if !ok {
+		return model.Errorf(model.ErrNotFound, "no account with id %s", id)
 	}
-	if accountUpdate.Login != "" {
-		account.Login = accountUpdate.Login
+	if account.Version != version {
+		return versionMismatch(account, version)
 	}
-	if accountUpdate.Password != "" {
-		account.Password = accountUpdate.Password
+
+	deletedAt := time.Now().UTC()
+	account.DeletedAt = &deletedAt
+	account.Version++
+
+	entry, _ := accountRepository.entry(id)
+	entry.Account = nil
+	entry.Lease = nil
+	entry.Deleted = &account
+	return accountRepository.put(id, entry)
+}
+
+// Restore takes an account out of the trash and returns its new version.
+func (accountRepository *AccountRepository) Restore(ctx context.Context, id string) (int64, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
 	}
-	if accountUpdate.Email != "" {
-		account.Email = accountUpdate.Email
+
+	accountRepository.Lock()
+	defer accountRepository.Unlock()
+
+	account, ok := accountRepository.trash[id]
+	if !ok {
+		return 0, model.Errorf(model.ErrNotFound, "no deleted account with id %s", id)
 	}
-	if accountUpdate.EmailPassword != "" {
-		account.EmailPassword = accountUpdate.EmailPassword
+
+	account.DeletedAt = nil
+	account.Version++
+
+	entry, _ := accountRepository.entry(id)
+	entry.Deleted = nil
+	entry.Account = &account
+	if err := accountRepository.put(id, entry); err != nil {
+		return 0, err
 	}
-	if accountUpdate.RecoveryEmail != "" {
-		account.RecoveryEmail = accountUpdate.RecoveryEmail
+
+	return account.Version, nil
+}
+
+// Purge removes the accounts deleted before deletedBefore for good, with
+// their status and credential history, like the foreign keys of the sql
+// store cascade, and returns their IDs. The history goes first, so a crash
+// in between never leaves the secrets of a purged account.
+func (accountRepository *AccountRepository) Purge(ctx context.Context, deletedBefore time.Time) ([]string, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
 	}
-	if accountUpdate.RecoveryEmailPassword != "" {
-		account.RecoveryEmailPassword = accountUpdate.RecoveryEmailPassword
+
+	accountRepository.Lock()
+	defer accountRepository.Unlock()
+
+	var ids []string
+	for id, account := range accountRepository.trash {
+		if account.DeletedAt.Before(deletedBefore) {
+			if err := accountRepository.statusHistory.purge(id); err != nil {
+				return ids, err
+			}
+			if err := accountRepository.credentialHistory.purge(id); err != nil {
+				return ids, err
+			}
+			entry, _ := accountRepository.entry(id)
+			entry.Deleted = nil
+			entry.DataKey = nil
+			if err := accountRepository.put(id, entry); err != nil {
+				return ids, err
+			}
+			ids = append(ids, id)
+		}
 	}
-	if accountUpdate.Cookie != "" {
-		account.Cookie = accountUpdate.Cookie
+
+	return ids, nil
+}
+
+// filtered returns the accounts filter looks into, the caller must hold the
+// lock.
+func (accountRepository *AccountRepository) filtered(filter model.AccountFilter) map[string]model.Account {
+	if filter.Deleted {
+		return accountRepository.trash
 	}
-	if accountUpdate.Status != "" {
-		account.Status = accountUpdate.Status
+	return accountRepository.accounts
+}
+
+func (accountRepository *AccountRepository) GetAll(ctx context.Context, filter model.AccountFilter) (model.AccountPage, error) {
+	select {
+	case <-ctx.Done():
+		return model.AccountPage{}, ctx.Err()
+	default:
 	}
 
-	accountRepository.accounts[strID] = account
+	var after *model.Account
+	if filter.Cursor != "" {
+		cursorAccount, err := model.ParseAccountCursor(filter.Cursor, filter.Sort)
+		if err != nil {
+			return model.AccountPage{}, err
+		}
+		after = &cursorAccount
+	}
 
-	return nil
+	accountRepository.Lock()
+	defer accountRepository.Unlock()
+
+	accounts := make([]model.Account, 0)
+	for _, account := range accountRepository.filtered(filter) {
+		if !matchesAccountFilter(account, filter) {
+			continue
+		}
+		if after != nil && filter.Sort.Compare(account, *after) <= 0 {
+			continue
+		}
+		accounts = append(accounts, account)
+	}
+
+	sort.Slice(accounts, func(i, j int) bool {
+		return filter.Sort.Compare(accounts[i], accounts[j]) < 0
+	})
+
+	page := model.AccountPage{Accounts: accounts}
+	if pageSize := filter.PageSize(); len(accounts) > pageSize {
+		page.Accounts = accounts[:pageSize]
+		page.NextCursor = model.NewAccountCursor(page.Accounts[pageSize-1], filter.Sort)
+	}
+
+	for i := range page.Accounts {
+		id := page.Accounts[i].ID.String()
+		err := accountRepository.envelope.Open(ctx, accountRepository.dataKeys[id], page.Accounts[i].Secrets()...)
+		if err != nil {
+			return model.AccountPage{}, fmt.Errorf("error decrypting account with id %s: %w", id, err)
+		}
+	}
+
+	return page, nil
 }
 
-func (accountRepository *AccountRepository) Delete(ctx context.Context, id string) error {
+func versionMismatch(account model.Account, version int64) error {
+	return model.Errorf(model.ErrPreconditionFailed, "account with id %s is at version %d, not %d", account.ID, account.Version, version)
+}
+
+// Export calls fn with every account matching filter, in creation order.
+// The lock is only held while the matching accounts are copied, not while
+// fn runs.
+func (accountRepository *AccountRepository) Export(ctx context.Context, filter model.AccountFilter, fn func(model.Account) error) error {
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
 	default:
 	}
 
+	accountRepository.Lock()
+	accounts := make([]model.Account, 0)
+	dataKeys := make([]encryption.WrappedKey, 0)
+	for id, account := range accountRepository.filtered(filter) {
+		if matchesAccountFilter(account, filter) {
+			accounts = append(accounts, account)
+			dataKeys = append(dataKeys, accountRepository.dataKeys[id])
+		}
+	}
+	accountRepository.Unlock()
+
+	order := make([]int, len(accounts))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return model.AccountSort{Field: model.SortByCreatedAt}.Compare(accounts[order[i]], accounts[order[j]]) < 0
+	})
+
+	for _, i := range order {
+		account := accounts[i]
+		err := accountRepository.envelope.Open(ctx, dataKeys[i], account.Secrets()...)
+		if err != nil {
+			return fmt.Errorf("error decrypting account with id %s: %w", account.ID, err)
+		}
+
+		if err := fn(account); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func matchesAccountFilter(account model.Account, filter model.AccountFilter) bool {
+	if filter.AccountType != "" && account.AccountType != filter.AccountType {
+		return false
+	}
+	if filter.Status != "" && account.Status != filter.Status {
+		return false
+	}
+	if filter.Email != "" && !strings.EqualFold(account.Email, filter.Email) {
+		return false
+	}
+	if !filter.CreatedAfter.IsZero() && account.CreatedAt.Before(filter.CreatedAfter) {
+		return false
+	}
+	if !filter.CreatedBefore.IsZero() && !account.CreatedAt.Before(filter.CreatedBefore) {
+		return false
+	}
+	if !filter.CookiesExpiringBefore.IsZero() &&
+		(account.CookiesExpireAt == nil || !account.CookiesExpireAt.Before(filter.CookiesExpiringBefore)) {
+		return false
+	}
+	if len(filter.Tags) > 0 {
+		matches := func(tag string) bool {
+			return slices.Contains(account.Tags, tag)
+		}
+		if filter.TagMatch == model.TagMatchAny && !slices.ContainsFunc(filter.Tags, matches) {
+			return false
+		}
+		if filter.TagMatch != model.TagMatchAny && slices.ContainsFunc(filter.Tags, func(tag string) bool { return !matches(tag) }) {
+			return false
+		}
+	}
+	for name, value := range filter.Attributes {
+		attribute, ok := account.Attributes[name]
+		if !ok || attribute.Secret || attribute.Value != value {
+			return false
+		}
+	}
+	return true
+}
+
+func (accountRepository *AccountRepository) Rewrap(ctx context.Context) (int, error) {
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	default:
+	}
+
+	currentVersion, err := accountRepository.envelope.Refresh(ctx)
+	if err != nil {
+		return 0, err
+	}
+
 	accountRepository.Lock()
 	defer accountRepository.Unlock()
 
-	_, ok := accountRepository.accounts[id]
-	if !ok {
-		return fmt.Errorf("no account with id %s", id)
+	rewrapped := 0
+	for id, dataKey := range accountRepository.dataKeys {
+		if dataKey.Version == currentVersion {
+			continue
+		}
+
+		rewrappedKey, err := accountRepository.envelope.Rewrap(ctx, dataKey)
+		if err != nil {
+			return rewrapped, fmt.Errorf("error rewrapping data key of account with id %s: %w", id, err)
+		}
+
+		entry, _ := accountRepository.entry(id)
+		entry.DataKey = &rewrappedKey
+		if err := accountRepository.put(id, entry); err != nil {
+			return rewrapped, err
+		}
+		rewrapped++
 	}
 
-	delete(accountRepository.accounts, id)
+	return rewrapped, nil
+}
 
-	return nil
+func (accountRepository *AccountRepository) ExpireSessions(ctx context.Context, now time.Time, keep []string) ([]model.StatusTransitionCreate, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	accountRepository.Lock()
+	defer accountRepository.Unlock()
+
+	kept := make(map[string]bool, len(keep))
+	for _, status := range keep {
+		kept[status] = true
+	}
+
+	var statusTransitions []model.StatusTransitionCreate
+	for id, account := range accountRepository.accounts {
+		if account.CookiesExpireAt == nil || account.CookiesExpireAt.After(now) ||
+			account.Status == model.AccountStatusSessionExpired || kept[account.Status] {
+			continue
+		}
+
+		statusTransitions = append(statusTransitions, model.StatusTransitionCreate{
+			AccountID: id,
+			From:      account.Status,
+			To:        model.AccountStatusSessionExpired,
+		})
+
+		account.Status = model.AccountStatusSessionExpired
+		account.Version++
+		entry, _ := accountRepository.entry(id)
+		entry.Account = &account
+		if err := accountRepository.put(id, entry); err != nil {
+			return statusTransitions, err
+		}
+	}
+
+	return statusTransitions, nil
 }
 
-func (accountRepository *AccountRepository) GetAll(ctx context.Context) ([]model.Account, error) {
+func (accountRepository *AccountRepository) Lease(ctx context.Context, leaseCreate model.LeaseCreate) (model.Lease, model.Account, error) {
 	select {
 	case <-ctx.Done():
-		return []model.Account{}, ctx.Err()
+		return model.Lease{}, model.Account{}, ctx.Err()
 	default:
 	}
 
 	accountRepository.Lock()
 	defer accountRepository.Unlock()
 
-	accounts := make([]model.Account, 0, len(accountRepository.accounts))
-	for _, account := range accountRepository.accounts {
-		accounts = append(accounts, account)
+	now := time.Now()
+
+	var id string
+	for candidate, account := range accountRepository.accounts {
+		if lease, ok := accountRepository.leases[candidate]; ok && lease.ExpiresAt.After(now) {
+			continue
+		}
+		if leaseCreate.AccountType != "" && account.AccountType != leaseCreate.AccountType {
+			continue
+		}
+		if leaseCreate.Status != "" && account.Status != leaseCreate.Status {
+			continue
+		}
+		if id == "" || accountRepository.leasesBefore(candidate, id) {
+			id = candidate
+		}
+	}
+	if id == "" {
+		return model.Lease{}, model.Account{}, model.ErrNoAccountAvailable
+	}
+
+	account := accountRepository.accounts[id]
+	err := accountRepository.envelope.Open(ctx, accountRepository.dataKeys[id], account.Secrets()...)
+	if err != nil {
+		return model.Lease{}, model.Account{}, fmt.Errorf("error decrypting account with id %s: %w", id, err)
+	}
+
+	lease := model.Lease{
+		ID:        uuid.New(),
+		AccountID: account.ID,
+		Holder:    leaseCreate.Holder,
+		ExpiresAt: now.Add(model.LeaseDuration(leaseCreate.TTL)),
+	}
+	entry, _ := accountRepository.entry(id)
+	entry.Lease = &lease
+	if err := accountRepository.put(id, entry); err != nil {
+		return model.Lease{}, model.Account{}, err
+	}
+
+	return lease, account, nil
+}
+
+// leasesBefore reports whether the account with id is leased before the one
+// with other, in the order of the sql store: accounts that were never leased
+// first, then by the expiry of their last lease, then by creation.
+func (accountRepository *AccountRepository) leasesBefore(id, other string) bool {
+	lease, leased := accountRepository.leases[id]
+	otherLease, otherLeased := accountRepository.leases[other]
+	if leased != otherLeased {
+		return !leased
+	}
+	if leased && !lease.ExpiresAt.Equal(otherLease.ExpiresAt) {
+		return lease.ExpiresAt.Before(otherLease.ExpiresAt)
+	}
+
+	createdAt, otherCreatedAt := accountRepository.accounts[id].CreatedAt, accountRepository.accounts[other].CreatedAt
+	if !createdAt.Equal(otherCreatedAt) {
+		return createdAt.Before(otherCreatedAt)
+	}
+	return id < other
+}
+
+func (accountRepository *AccountRepository) RenewLease(ctx context.Context, accountID string, leaseRenew model.LeaseRenew) (model.Lease, error) {
+	select {
+	case <-ctx.Done():
+		return model.Lease{}, ctx.Err()
+	default:
+	}
+
+	accountRepository.Lock()
+	defer accountRepository.Unlock()
+
+	now := time.Now()
+
+	lease, ok := accountRepository.leases[accountID]
+	if !ok || lease.ID.String() != leaseRenew.LeaseID || !lease.ExpiresAt.After(now) {
+		return model.Lease{}, fmt.Errorf("%w: lease %s on account %s", model.ErrLeaseNotHeld, leaseRenew.LeaseID, accountID)
+	}
+
+	lease.ExpiresAt = now.Add(model.LeaseDuration(leaseRenew.TTL))
+	entry, _ := accountRepository.entry(accountID)
+	entry.Lease = &lease
+	if err := accountRepository.put(accountID, entry); err != nil {
+		return model.Lease{}, err
+	}
+
+	return lease, nil
+}
+
+func (accountRepository *AccountRepository) ReleaseLease(ctx context.Context, accountID, leaseID string) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	accountRepository.Lock()
+	defer accountRepository.Unlock()
+
+	lease, ok := accountRepository.leases[accountID]
+	if !ok || lease.ID.String() != leaseID {
+		return fmt.Errorf("%w: lease %s on account %s", model.ErrLeaseNotHeld, leaseID, accountID)
 	}
 
-	return accounts, nil
+	entry, _ := accountRepository.entry(accountID)
+	entry.Lease = nil
+	return accountRepository.put(accountID, entry)
 }
 
 func (accountRepository *AccountRepository) Nginx(ctx context.Context) (string, error) {
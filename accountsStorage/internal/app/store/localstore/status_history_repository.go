@@ -0,0 +1,121 @@
+package localstore
+
+import (
+	"account_storage/pkg/model"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type StatusHistoryRepository struct {
+	sync.Mutex
+	statusTransitions []model.StatusTransition
+	journal           *journal
+	logger            *logrus.Logger
+}
+
+func (statusHistoryRepository *StatusHistoryRepository) replay(record journalRecord) error {
+	if record.Value == nil {
+		statusHistoryRepository.statusTransitions = slices.DeleteFunc(statusHistoryRepository.statusTransitions, func(statusTransition model.StatusTransition) bool {
+			return statusTransition.ID.String() == record.Key
+		})
+		return nil
+	}
+
+	var statusTransition model.StatusTransition
+	if err := json.Unmarshal(record.Value, &statusTransition); err != nil {
+		return fmt.Errorf("error decoding status transition %s: %w", record.Key, err)
+	}
+	statusHistoryRepository.statusTransitions = append(statusHistoryRepository.statusTransitions, statusTransition)
+	return nil
+}
+
+// records returns the records of every status transition in order, the
+// caller must hold the lock.
+func (statusHistoryRepository *StatusHistoryRepository) records() ([]journalRecord, error) {
+	records := make([]journalRecord, 0, len(statusHistoryRepository.statusTransitions))
+	for _, statusTransition := range statusHistoryRepository.statusTransitions {
+		record, err := newRecord(recordKindStatusTransition, statusTransition.ID.String(), statusTransition)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func (statusHistoryRepository *StatusHistoryRepository) Create(ctx context.Context, statusTransitionCreate model.StatusTransitionCreate) (string, error) {
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	default:
+	}
+
+	statusHistoryRepository.Lock()
+	defer statusHistoryRepository.Unlock()
+
+	statusTransitionID := uuid.New()
+
+	statusTransition := model.StatusTransition{
+		ID:        statusTransitionID,
+		AccountID: statusTransitionCreate.AccountID,
+		From:      statusTransitionCreate.From,
+		To:        statusTransitionCreate.To,
+		Reason:    statusTransitionCreate.Reason,
+		Actor:     statusTransitionCreate.Actor,
+		CreatedAt: time.Now(),
+	}
+
+	if err := statusHistoryRepository.journal.put(recordKindStatusTransition, statusTransitionID.String(), statusTransition); err != nil {
+		return "", err
+	}
+	statusHistoryRepository.statusTransitions = append(statusHistoryRepository.statusTransitions, statusTransition)
+
+	return statusTransitionID.String(), nil
+}
+
+func (statusHistoryRepository *StatusHistoryRepository) GetByAccountID(ctx context.Context, accountID string) ([]model.StatusTransition, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	statusHistoryRepository.Lock()
+	defer statusHistoryRepository.Unlock()
+
+	statusTransitions := make([]model.StatusTransition, 0)
+	for _, statusTransition := range statusHistoryRepository.statusTransitions {
+		if statusTransition.AccountID == accountID {
+			statusTransitions = append(statusTransitions, statusTransition)
+		}
+	}
+
+	return statusTransitions, nil
+}
+
+// purge removes the transitions of a purged account. The account
+// repository lock is held by the caller and taken before this one.
+func (statusHistoryRepository *StatusHistoryRepository) purge(accountID string) error {
+	statusHistoryRepository.Lock()
+	defer statusHistoryRepository.Unlock()
+
+	for _, statusTransition := range statusHistoryRepository.statusTransitions {
+		if statusTransition.AccountID != accountID {
+			continue
+		}
+		if err := statusHistoryRepository.journal.remove(recordKindStatusTransition, statusTransition.ID.String()); err != nil {
+			return err
+		}
+	}
+	statusHistoryRepository.statusTransitions = slices.DeleteFunc(statusHistoryRepository.statusTransitions, func(statusTransition model.StatusTransition) bool {
+		return statusTransition.AccountID == accountID
+	})
+	return nil
+}
@@ -1,30 +1,187 @@
 package localstore
 
 import (
+	"account_storage/internal/app/encryption"
 	"account_storage/internal/app/store"
 	"account_storage/pkg/model"
+	"encoding/json"
+	"fmt"
+	"sync"
 
 	"github.com/sirupsen/logrus"
 )
 
 type Store struct {
-	logger            *logrus.Logger
-	accountRepository store.AccountRepository
+	logger                      *logrus.Logger
+	journal                     *journal
+	accountRepository           *AccountRepository
+	apiKeyRepository            *APIKeyRepository
+	auditRepository             *AuditRepository
+	statusHistoryRepository     *StatusHistoryRepository
+	accountTypeRepository       *AccountTypeRepository
+	credentialHistoryRepository *CredentialHistoryRepository
 }
 
-func New(logger *logrus.Logger) *Store {
-	return &Store{
+// New returns a store that keeps everything in memory. With a path in
+// options every change is also written to a write-ahead log there, and the
+// snapshot and the log are replayed first.
+func New(logger *logrus.Logger, envelope *encryption.Envelope, options Options) (*Store, error) {
+	statusHistoryRepository := &StatusHistoryRepository{
 		logger: logger,
 	}
+	credentialHistoryRepository := &CredentialHistoryRepository{
+		rings:    make(map[string]*credentialRing),
+		envelope: envelope,
+		logger:   logger,
+	}
+
+	store := &Store{
+		logger: logger,
+		accountRepository: &AccountRepository{
+			accounts:          make(map[string]model.Account),
+			trash:             make(map[string]model.Account),
+			dataKeys:          make(map[string]encryption.WrappedKey),
+			leases:            make(map[string]model.Lease),
+			statusHistory:     statusHistoryRepository,
+			credentialHistory: credentialHistoryRepository,
+			envelope:          envelope,
+			logger:            logger,
+		},
+		apiKeyRepository: &APIKeyRepository{
+			apiKeys: make(map[string]model.APIKey),
+			logger:  logger,
+		},
+		auditRepository: &AuditRepository{
+			logger: logger,
+		},
+		statusHistoryRepository: statusHistoryRepository,
+		accountTypeRepository: &AccountTypeRepository{
+			accountTypes: make(map[string]model.AccountType),
+			logger:       logger,
+		},
+		credentialHistoryRepository: credentialHistoryRepository,
+	}
+
+	if options.Path == "" {
+		return store, nil
+	}
+
+	journal, cut, err := openJournal(options, store.replay)
+	if err != nil {
+		return nil, err
+	}
+	if cut > 0 {
+		logger.WithFields(logrus.Fields{
+			"package":  "localstore",
+			"function": "New",
+			"path":     options.Path,
+			"bytes":    cut,
+		}).Warn("cut off a torn write-ahead log")
+	}
+
+	store.journal = journal
+	store.accountRepository.journal = journal
+	store.apiKeyRepository.journal = journal
+	store.auditRepository.journal = journal
+	store.statusHistoryRepository.journal = journal
+	store.accountTypeRepository.journal = journal
+	store.credentialHistoryRepository.journal = journal
+
+	return store, nil
 }
 
-func (store Store) Account() store.AccountRepository {
-	if store.accountRepository != nil {
-		return store.accountRepository
+func (store *Store) replay(record journalRecord) error {
+	switch record.Kind {
+	case recordKindAccount:
+		return store.accountRepository.replay(record)
+	case recordKindAPIKey:
+		return store.apiKeyRepository.replay(record)
+	case recordKindAuditRecord:
+		return store.auditRepository.replay(record)
+	case recordKindStatusTransition:
+		return store.statusHistoryRepository.replay(record)
+	case recordKindAccountType:
+		return store.accountTypeRepository.replay(record)
+	case recordKindCredentialHistory:
+		return store.credentialHistoryRepository.replay(record)
+	case recordKindBatch:
+		var records []journalRecord
+		if err := json.Unmarshal(record.Value, &records); err != nil {
+			return fmt.Errorf("error decoding batch: %w", err)
+		}
+		for _, record := range records {
+			if err := store.replay(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown record kind %s", record.Kind)
 	}
+}
+
+// Snapshot writes the whole state to a new snapshot and empties the
+// write-ahead log. Changes wait until it is done.
+func (store *Store) Snapshot() error {
+	if store.journal == nil {
+		return nil
+	}
+
+	sources := []struct {
+		sync.Locker
+		records func() ([]journalRecord, error)
+	}{
+		{store.accountRepository, store.accountRepository.records},
+		{store.apiKeyRepository, store.apiKeyRepository.records},
+		{store.auditRepository, store.auditRepository.records},
+		{store.statusHistoryRepository, store.statusHistoryRepository.records},
+		{store.accountTypeRepository, store.accountTypeRepository.records},
+		{store.credentialHistoryRepository, store.credentialHistoryRepository.records},
+	}
+
+	var records []journalRecord
+	for _, source := range sources {
+		source.Lock()
+		defer source.Unlock()
 
-	return &AccountRepository{
-		accounts: make(map[string]model.Account),
-		logger:   store.logger,
+		sourceRecords, err := source.records()
+		if err != nil {
+			return err
+		}
+		records = append(records, sourceRecords...)
 	}
+
+	return store.journal.compact(records)
+}
+
+// Close syncs and closes the write-ahead log.
+func (store *Store) Close() error {
+	if store.journal == nil {
+		return nil
+	}
+	return store.journal.close()
+}
+
+func (store Store) Account() store.AccountRepository {
+	return store.accountRepository
+}
+
+func (store Store) APIKey() store.APIKeyRepository {
+	return store.apiKeyRepository
+}
+
+func (store Store) Audit() store.AuditRepository {
+	return store.auditRepository
+}
+
+func (store Store) StatusHistory() store.StatusHistoryRepository {
+	return store.statusHistoryRepository
+}
+
+func (store Store) AccountType() store.AccountTypeRepository {
+	return store.accountTypeRepository
+}
+
+func (store Store) CredentialHistory() store.CredentialHistoryRepository {
+	return store.credentialHistoryRepository
 }
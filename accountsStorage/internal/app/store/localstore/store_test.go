@@ -0,0 +1,97 @@
+package localstore_test
+
+import (
+	"account_storage/internal/app/encryption"
+	"account_storage/internal/app/encryption/encryptiontest"
+	"account_storage/internal/app/store/localstore"
+	"account_storage/pkg/model"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func newTestLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+func openTestStore(t *testing.T, logger *logrus.Logger, envelope *encryption.Envelope, path string) *localstore.Store {
+	store, err := localstore.New(logger, envelope, localstore.Options{Path: path, Fsync: localstore.FsyncAlways})
+	if err != nil {
+		t.Fatalf("opening local store: %v", err)
+	}
+	return store
+}
+
+func testAccountCreate() model.AccountCreate {
+	return model.AccountCreate{
+		Name:     "account",
+		Login:    "login",
+		Password: "password",
+		Email:    "account@example.com",
+		Status:   "active",
+	}
+}
+
+func TestPurgeDropsHistory(t *testing.T) {
+	ctx := context.Background()
+	logger := newTestLogger()
+	envelope := encryptiontest.NewEnvelope(t)
+	path := t.TempDir()
+
+	store := openTestStore(t, logger, envelope, path)
+	id, err := store.Account().Create(ctx, testAccountCreate())
+	if err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+	err = store.CredentialHistory().Create(ctx, model.CredentialVersion{AccountID: id, Version: 1, Password: "password"})
+	if err != nil {
+		t.Fatalf("keeping credential version: %v", err)
+	}
+	_, err = store.StatusHistory().Create(ctx, model.StatusTransitionCreate{AccountID: id, From: "active", To: "banned"})
+	if err != nil {
+		t.Fatalf("keeping status transition: %v", err)
+	}
+	if err := store.Account().Delete(ctx, id, 1); err != nil {
+		t.Fatalf("deleting account: %v", err)
+	}
+
+	ids, err := store.Account().Purge(ctx, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("purging accounts: %v", err)
+	}
+	if len(ids) != 1 || ids[0] != id {
+		t.Fatalf("purged %v, want [%s]", ids, id)
+	}
+
+	assertNoHistory := func(store *localstore.Store) {
+		t.Helper()
+		versions, err := store.CredentialHistory().GetByAccountID(ctx, id)
+		if err != nil {
+			t.Fatalf("getting credential history: %v", err)
+		}
+		if len(versions) != 0 {
+			t.Fatalf("purged account keeps %d credential versions", len(versions))
+		}
+		statusTransitions, err := store.StatusHistory().GetByAccountID(ctx, id)
+		if err != nil {
+			t.Fatalf("getting status history: %v", err)
+		}
+		if len(statusTransitions) != 0 {
+			t.Fatalf("purged account keeps %d status transitions", len(statusTransitions))
+		}
+	}
+
+	assertNoHistory(store)
+	if err := store.Close(); err != nil {
+		t.Fatalf("closing local store: %v", err)
+	}
+
+	reopened := openTestStore(t, logger, envelope, path)
+	defer reopened.Close()
+	assertNoHistory(reopened)
+}
@@ -0,0 +1,95 @@
+package localstore
+
+import (
+	"account_storage/pkg/model"
+	"context"
+	"slices"
+	"strings"
+)
+
+// Tag adds the tags to the accounts. Nothing changes when one of the
+// accounts does not exist.
+func (accountRepository *AccountRepository) Tag(ctx context.Context, accountTags model.AccountTags) error {
+	return accountRepository.changeTags(ctx, accountTags, func(tags []string) []string {
+		tags = append(tags, accountTags.Tags...)
+		slices.Sort(tags)
+		return slices.Compact(tags)
+	})
+}
+
+// Untag removes the tags from the accounts. Nothing changes when one of the
+// accounts does not exist.
+func (accountRepository *AccountRepository) Untag(ctx context.Context, accountTags model.AccountTags) error {
+	return accountRepository.changeTags(ctx, accountTags, func(tags []string) []string {
+		return slices.DeleteFunc(tags, func(tag string) bool {
+			return slices.Contains(accountTags.Tags, tag)
+		})
+	})
+}
+
+// changeTags replaces the tags of the accounts with what change returns for
+// a copy of them.
+func (accountRepository *AccountRepository) changeTags(ctx context.Context, accountTags model.AccountTags, change func([]string) []string) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	accountRepository.Lock()
+	defer accountRepository.Unlock()
+
+	var missing []string
+	for _, id := range accountTags.AccountIDs {
+		if _, ok := accountRepository.accounts[id.String()]; !ok {
+			missing = append(missing, id.String())
+		}
+	}
+	if len(missing) > 0 {
+		return model.Errorf(model.ErrNotFound, "no accounts with ids %s", strings.Join(missing, ", "))
+	}
+
+	for _, id := range accountTags.AccountIDs {
+		account := accountRepository.accounts[id.String()]
+		account.Tags = change(slices.Clone(account.Tags))
+		if len(account.Tags) == 0 {
+			account.Tags = nil
+		}
+		entry, _ := accountRepository.entry(id.String())
+		entry.Account = &account
+		if err := accountRepository.put(id.String(), entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Tags returns every tag with the number of accounts that have it.
+func (accountRepository *AccountRepository) Tags(ctx context.Context) ([]model.TagCount, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	accountRepository.Lock()
+	defer accountRepository.Unlock()
+
+	counts := make(map[string]int)
+	for _, account := range accountRepository.accounts {
+		for _, tag := range account.Tags {
+			counts[tag]++
+		}
+	}
+
+	tagCounts := make([]model.TagCount, 0, len(counts))
+	for tag, count := range counts {
+		tagCounts = append(tagCounts, model.TagCount{Tag: tag, Count: count})
+	}
+	slices.SortFunc(tagCounts, func(a, b model.TagCount) int {
+		return strings.Compare(a.Tag, b.Tag)
+	})
+
+	return tagCounts, nil
+}
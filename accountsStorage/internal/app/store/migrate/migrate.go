@@ -0,0 +1,327 @@
+// Package migrate applies the embedded migrations to a sql database and
+// records them in the schema_migrations table.
+package migrate
+
+import (
+	"account_storage/migrations"
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// advisoryLockKey is the Postgres advisory lock held while migrating, so
+// replicas starting at the same time apply every migration once. SQLite
+// needs none, its migration transactions lock the whole database.
+const advisoryLockKey = 7283641092
+
+// Status is a migration and when it was applied, AppliedAt is nil while it
+// is pending.
+type Status struct {
+	Version   string     `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// Hook changes data in the transaction of a migration, for what its SQL
+// can not do on its own.
+type Hook func(ctx context.Context, tx *sql.Tx) error
+
+type Migrator struct {
+	db         *sql.DB
+	dialect    string
+	migrations []migrations.Migration
+	beforeUp   map[string]Hook
+	logger     *logrus.Logger
+}
+
+func New(db *sql.DB, dialect string, logger *logrus.Logger) (*Migrator, error) {
+	loaded, err := migrations.Load(dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		dialect:    dialect,
+		migrations: loaded,
+		beforeUp:   make(map[string]Hook),
+		logger:     logger,
+	}, nil
+}
+
+// BeforeUp runs hook before the up SQL of the migration with version, in
+// the same transaction.
+func (migrator *Migrator) BeforeUp(version string, hook Hook) {
+	migrator.beforeUp[version] = hook
+}
+
+// Up applies the pending migrations, oldest first, and returns how many it
+// applied.
+func (migrator *Migrator) Up(ctx context.Context) (int, error) {
+	applied := 0
+	err := migrator.locked(ctx, func(conn *sql.Conn) error {
+		for _, migration := range migrator.migrations {
+			ok, err := migrator.apply(ctx, conn, migration, true)
+			if err != nil {
+				return fmt.Errorf("error applying migration %s_%s: %w", migration.Version, migration.Name, err)
+			}
+			if ok {
+				applied++
+			}
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the last steps applied migrations, newest first, and returns
+// how many it reverted.
+func (migrator *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	reverted := 0
+	err := migrator.locked(ctx, func(conn *sql.Conn) error {
+		for i := len(migrator.migrations) - 1; i >= 0 && reverted < steps; i-- {
+			migration := migrator.migrations[i]
+			ok, err := migrator.apply(ctx, conn, migration, false)
+			if err != nil {
+				return fmt.Errorf("error reverting migration %s_%s: %w", migration.Version, migration.Name, err)
+			}
+			if ok {
+				reverted++
+			}
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status returns every migration, oldest first, with when it was applied.
+func (migrator *Migrator) Status(ctx context.Context) ([]Status, error) {
+	appliedAt := make(map[string]time.Time)
+	err := migrator.locked(ctx, func(conn *sql.Conn) error {
+		rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+		if err != nil {
+			return fmt.Errorf("error getting applied migrations: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var version string
+			var at time.Time
+			if err := rows.Scan(&version, &at); err != nil {
+				return fmt.Errorf("error getting applied migrations: %w", err)
+			}
+			appliedAt[version] = at
+		}
+
+		if err = rows.Err(); err != nil {
+			return fmt.Errorf("error getting applied migrations: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, len(migrator.migrations))
+	for i, migration := range migrator.migrations {
+		statuses[i] = Status{Version: migration.Version, Name: migration.Name}
+		if at, ok := appliedAt[migration.Version]; ok {
+			statuses[i].AppliedAt = &at
+		}
+	}
+
+	return statuses, nil
+}
+
+// Close closes the database.
+func (migrator *Migrator) Close() error {
+	return migrator.db.Close()
+}
+
+const createTableQuery = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version TEXT PRIMARY KEY,
+	applied_at TIMESTAMP NOT NULL
+)`
+
+// createTable creates the schema_migrations table, converting the one
+// golang-migrate keeps first.
+func (migrator *Migrator) createTable(ctx context.Context, conn *sql.Conn) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := migrator.convertGolangMigrate(ctx, tx); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, createTableQuery); err != nil {
+		return fmt.Errorf("error creating schema_migrations: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// convertGolangMigrate replaces the schema_migrations table of
+// golang-migrate, which holds the version of the last migration applied and
+// whether it failed half way, with one that records every migration up to
+// that version as applied. A migration golang-migrate left dirty fails, it
+// has to be fixed by hand first.
+func (migrator *Migrator) convertGolangMigrate(ctx context.Context, tx *sql.Tx) error {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = 'schema_migrations')`
+	if migrator.dialect == migrations.DialectSQLite {
+		query = `SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations')`
+	}
+	if err := tx.QueryRowContext(ctx, query).Scan(&exists); err != nil {
+		return fmt.Errorf("error looking for schema_migrations: %w", err)
+	}
+	if !exists {
+		return nil
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT * FROM schema_migrations LIMIT 0`)
+	if err != nil {
+		return fmt.Errorf("error reading schema_migrations: %w", err)
+	}
+	columns, err := rows.Columns()
+	rows.Close()
+	if err != nil {
+		return fmt.Errorf("error reading schema_migrations: %w", err)
+	}
+	if !slices.Contains(columns, "dirty") {
+		return nil
+	}
+
+	var version int64
+	var dirty bool
+	err = tx.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations`).Scan(&version, &dirty)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("error reading schema_migrations: %w", err)
+	}
+	if dirty {
+		return fmt.Errorf("golang-migrate left migration %d dirty in schema_migrations", version)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DROP TABLE schema_migrations`); err != nil {
+		return fmt.Errorf("error dropping schema_migrations of golang-migrate: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, createTableQuery); err != nil {
+		return fmt.Errorf("error creating schema_migrations: %w", err)
+	}
+
+	now := time.Now().UTC()
+	for _, migration := range migrator.migrations {
+		migrationVersion, err := strconv.ParseInt(migration.Version, 10, 64)
+		if err != nil {
+			return fmt.Errorf("error parsing version of migration %s_%s: %w", migration.Version, migration.Name, err)
+		}
+		if migrationVersion > version {
+			continue
+		}
+		_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, applied_at) VALUES ($1, $2)`, migration.Version, now)
+		if err != nil {
+			return fmt.Errorf("error recording migration %s_%s: %w", migration.Version, migration.Name, err)
+		}
+	}
+
+	migrator.logger.WithFields(logrus.Fields{
+		"package":  "migrate",
+		"function": "convertGolangMigrate",
+		"version":  version,
+	}).Info("converted the schema_migrations table of golang-migrate")
+
+	return nil
+}
+
+// locked runs fn on a single connection, holding the advisory lock on
+// Postgres.
+func (migrator *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := migrator.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting migration connection: %w", err)
+	}
+	defer conn.Close()
+
+	if migrator.dialect == migrations.DialectPostgres {
+		if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, advisoryLockKey); err != nil {
+			return fmt.Errorf("error taking migration lock: %w", err)
+		}
+		defer func() {
+			_, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, advisoryLockKey)
+			if err != nil {
+				migrator.logger.WithFields(logrus.Fields{
+					"package":  "migrate",
+					"function": "locked",
+					"error":    err,
+				}).Error("releasing migration lock failed")
+			}
+		}()
+	}
+
+	if err := migrator.createTable(ctx, conn); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+// apply runs the up or down half of a migration in a transaction and
+// records it, unless it is already applied or reverted. That is checked in
+// the same transaction, so a concurrent run can not apply it twice.
+func (migrator *Migrator) apply(ctx context.Context, conn *sql.Conn, migration migrations.Migration, up bool) (bool, error) {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var applied bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`, migration.Version).Scan(&applied)
+	if err != nil || applied == up {
+		return false, err
+	}
+
+	if up {
+		if hook, ok := migrator.beforeUp[migration.Version]; ok {
+			if err := hook(ctx, tx); err != nil {
+				return false, err
+			}
+		}
+		if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+			return false, err
+		}
+		_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, applied_at) VALUES ($1, $2)`, migration.Version, time.Now().UTC())
+	} else {
+		if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+			return false, err
+		}
+		_, err = tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	action := "migration applied"
+	if !up {
+		action = "migration reverted"
+	}
+	migrator.logger.WithFields(logrus.Fields{
+		"package":  "migrate",
+		"function": "apply",
+		"version":  migration.Version,
+		"name":     migration.Name,
+	}).Info(action)
+
+	return true, nil
+}
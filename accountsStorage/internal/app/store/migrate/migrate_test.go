@@ -0,0 +1,110 @@
+package migrate_test
+
+import (
+	"account_storage/internal/app/store/migrate"
+	"account_storage/internal/app/store/sqlitestore"
+	"account_storage/migrations"
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestStatusTakesOverSchemaMigrations(t *testing.T) {
+	tests := []struct {
+		name string
+		// legacy creates and fills the schema_migrations table.
+		legacy  []string
+		applied []string
+		wantErr bool
+	}{
+		{
+			name:   "no legacy table",
+			legacy: nil,
+		},
+		{
+			name: "golang-migrate",
+			legacy: []string{
+				`CREATE TABLE schema_migrations (version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL)`,
+				`INSERT INTO schema_migrations (version, dirty) VALUES (20240415103000, false)`,
+			},
+			applied: []string{"20240401092459", "20240415103000"},
+		},
+		{
+			name: "golang-migrate without a version",
+			legacy: []string{
+				`CREATE TABLE schema_migrations (version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL)`,
+			},
+		},
+		{
+			name: "dirty golang-migrate",
+			legacy: []string{
+				`CREATE TABLE schema_migrations (version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL)`,
+				`INSERT INTO schema_migrations (version, dirty) VALUES (20240415103000, true)`,
+			},
+			wantErr: true,
+		},
+		{
+			name: "sqlite store",
+			legacy: []string{
+				`CREATE TABLE schema_migrations (version TEXT PRIMARY KEY, applied_at TIMESTAMP NOT NULL)`,
+				`INSERT INTO schema_migrations (version, applied_at) VALUES ('20240401092459', '2024-04-01 09:24:59')`,
+				`INSERT INTO schema_migrations (version, applied_at) VALUES ('20240422141500', '2024-04-22 14:15:00')`,
+			},
+			applied: []string{"20240401092459", "20240422141500"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			logger := logrus.New()
+			logger.SetOutput(io.Discard)
+
+			db, err := sqlitestore.Open(ctx, filepath.Join(t.TempDir(), "accounts.db"))
+			if err != nil {
+				t.Fatalf("opening sqlite database: %v", err)
+			}
+			defer db.Close()
+
+			for _, query := range test.legacy {
+				if _, err := db.ExecContext(ctx, query); err != nil {
+					t.Fatalf("creating schema_migrations: %v", err)
+				}
+			}
+
+			migrator, err := migrate.New(db, migrations.DialectSQLite, logger)
+			if err != nil {
+				t.Fatalf("loading migrations: %v", err)
+			}
+
+			statuses, err := migrator.Status(ctx)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("got no error, want one")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("getting migration status: %v", err)
+			}
+
+			var applied []string
+			for _, status := range statuses {
+				if status.AppliedAt != nil {
+					applied = append(applied, status.Version)
+				}
+			}
+			if len(applied) != len(test.applied) {
+				t.Fatalf("applied %v, want %v", applied, test.applied)
+			}
+			for i := range applied {
+				if applied[i] != test.applied[i] {
+					t.Fatalf("applied %v, want %v", applied, test.applied)
+				}
+			}
+		})
+	}
+}
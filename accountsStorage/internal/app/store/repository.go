@@ -3,13 +3,60 @@ package store
 import (
 	"account_storage/pkg/model"
 	"context"
+	"time"
 )
 
 type AccountRepository interface {
 	Create(ctx context.Context, account model.AccountCreate) (string, error)
 	GetByID(ctx context.Context, id string) (model.Account, error)
 	Update(ctx context.Context, account model.Account) error
-	Delete(ctx context.Context, id string) error
-	GetAll(ctx context.Context) ([]model.Account, error)
+	Delete(ctx context.Context, id string, version int64) error
+	Restore(ctx context.Context, id string) (int64, error)
+	Purge(ctx context.Context, deletedBefore time.Time) ([]string, error)
+	CreateBatch(ctx context.Context, accountCreates []model.AccountCreate, mode model.BatchMode) ([]model.BatchResult, error)
+	UpdateBatch(ctx context.Context, accounts []model.Account, mode model.BatchMode) ([]model.BatchResult, error)
+	DeleteBatch(ctx context.Context, accountVersions []model.AccountVersion, mode model.BatchMode) ([]model.BatchResult, error)
+	GetAll(ctx context.Context, filter model.AccountFilter) (model.AccountPage, error)
+	Export(ctx context.Context, filter model.AccountFilter, fn func(model.Account) error) error
+	Rewrap(ctx context.Context) (int, error)
+	ExpireSessions(ctx context.Context, now time.Time, keep []string) ([]model.StatusTransitionCreate, error)
+	Lease(ctx context.Context, lease model.LeaseCreate) (model.Lease, model.Account, error)
+	RenewLease(ctx context.Context, accountID string, leaseRenew model.LeaseRenew) (model.Lease, error)
+	ReleaseLease(ctx context.Context, accountID, leaseID string) error
+	Tag(ctx context.Context, accountTags model.AccountTags) error
+	Untag(ctx context.Context, accountTags model.AccountTags) error
+	Tags(ctx context.Context) ([]model.TagCount, error)
 	Nginx(ctx context.Context) (string, error)
 }
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, apiKey model.APIKeyCreate) (string, error)
+	GetByHash(ctx context.Context, keyHash string) (model.APIKey, error)
+	GetAll(ctx context.Context) ([]model.APIKey, error)
+	Delete(ctx context.Context, id string) error
+}
+
+type AuditRepository interface {
+	Create(ctx context.Context, auditRecord model.AuditRecordCreate) (string, error)
+	GetAll(ctx context.Context, filter model.AuditFilter) ([]model.AuditRecord, error)
+}
+
+type StatusHistoryRepository interface {
+	Create(ctx context.Context, statusTransition model.StatusTransitionCreate) (string, error)
+	GetByAccountID(ctx context.Context, accountID string) ([]model.StatusTransition, error)
+}
+
+type AccountTypeRepository interface {
+	Create(ctx context.Context, accountType model.AccountType) error
+	GetByName(ctx context.Context, name string) (model.AccountType, error)
+	GetAll(ctx context.Context) ([]model.AccountType, error)
+	Update(ctx context.Context, accountType model.AccountType) error
+	Delete(ctx context.Context, name string) error
+}
+
+type CredentialHistoryRepository interface {
+	Create(ctx context.Context, credentialVersion model.CredentialVersion) error
+	GetByAccountID(ctx context.Context, accountID string) ([]model.CredentialVersion, error)
+	GetVersion(ctx context.Context, accountID string, version int64) (model.CredentialVersion, error)
+	Rewrap(ctx context.Context) (int, error)
+}
@@ -0,0 +1,47 @@
+// Package sqlitestore opens the embedded SQLite database of the sql store.
+// The queries are shared with Postgres, see sqlstore.NewSQLite.
+package sqlitestore
+
+import (
+	"account_storage/internal/app/encryption"
+	"account_storage/internal/app/store/sqlstore"
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	_ "modernc.org/sqlite"
+)
+
+const busyTimeout = 5 * time.Second
+
+// Open opens the SQLite database at path, creating it when missing. Writes
+// take the database lock when their transaction begins and wait up to
+// busyTimeout for it, so concurrent writers queue up instead of failing.
+func Open(ctx context.Context, path string) (*sql.DB, error) {
+	query := url.Values{}
+	query.Add("_pragma", "foreign_keys(1)")
+	query.Add("_pragma", "journal_mode(WAL)")
+	query.Add("_pragma", "synchronous(NORMAL)")
+	query.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", busyTimeout.Milliseconds()))
+	query.Set("_txlock", "immediate")
+	query.Set("_time_format", "sqlite")
+
+	db, err := sql.Open("sqlite", "file:"+path+"?"+query.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("error opening sqlite database %s: %w", path, err)
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error opening sqlite database %s: %w", path, err)
+	}
+
+	return db, nil
+}
+
+func New(db *sql.DB, logger *logrus.Logger, envelope *encryption.Envelope) *sqlstore.Store {
+	return sqlstore.NewSQLite(db, logger, envelope)
+}
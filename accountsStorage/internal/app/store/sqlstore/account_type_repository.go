@@ -0,0 +1,185 @@
+package sqlstore
+
+import (
+	"account_storage/pkg/model"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type AccountTypeRepository struct {
+	db      *sql.DB
+	dialect *dialect
+	logger  *logrus.Logger
+}
+
+const accountTypeColumns = `name, description, required_fields, allowed_statuses, attributes, created_at, updated_at`
+
+func (accountTypeRepository *AccountTypeRepository) Create(ctx context.Context, accountType model.AccountType) error {
+	query := `INSERT INTO account_types (` + accountTypeColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $6)`
+
+	attributes, err := marshalAttributes(accountType.Attributes)
+	if err != nil {
+		return err
+	}
+
+	_, err = accountTypeRepository.db.ExecContext(ctx, query,
+		accountType.Name,
+		accountType.Description,
+		accountTypeRepository.dialect.array(accountType.RequiredFields),
+		accountTypeRepository.dialect.array(accountType.AllowedStatuses),
+		attributes,
+		time.Now().UTC())
+
+	if err != nil {
+		accountTypeRepository.logger.WithError(err).Error("Failed to create account type")
+		return fmt.Errorf("error creating account type %s: %w", accountType.Name, withKind(err))
+	}
+
+	return nil
+}
+
+func (accountTypeRepository *AccountTypeRepository) GetByName(ctx context.Context, name string) (model.AccountType, error) {
+	query := `SELECT ` + accountTypeColumns + ` FROM account_types WHERE name = $1`
+
+	accountType, err := scanAccountType(accountTypeRepository.db.QueryRowContext(ctx, query, name), accountTypeRepository.dialect)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return model.AccountType{}, model.Errorf(model.ErrNotFound, "no account type %s", name)
+		}
+		accountTypeRepository.logger.WithError(err).Error("Failed to get account type")
+		return model.AccountType{}, fmt.Errorf("error getting account type %s: %w", name, withKind(err))
+	}
+
+	return accountType, nil
+}
+
+func (accountTypeRepository *AccountTypeRepository) GetAll(ctx context.Context) ([]model.AccountType, error) {
+	query := `SELECT ` + accountTypeColumns + ` FROM account_types ORDER BY name`
+
+	rows, err := accountTypeRepository.db.QueryContext(ctx, query)
+	if err != nil {
+		accountTypeRepository.logger.WithError(err).Error("Failed to get all account types")
+		return nil, fmt.Errorf("error getting all account types: %w", withKind(err))
+	}
+	defer rows.Close()
+
+	accountTypes := []model.AccountType{}
+
+	for rows.Next() {
+		accountType, err := scanAccountType(rows, accountTypeRepository.dialect)
+		if err != nil {
+			accountTypeRepository.logger.WithError(err).Error("Failed to get all account types")
+			return nil, fmt.Errorf("error getting all account types: %w", err)
+		}
+		accountTypes = append(accountTypes, accountType)
+	}
+
+	if err = rows.Err(); err != nil {
+		accountTypeRepository.logger.WithError(err).Error("Failed to get all account types")
+		return nil, fmt.Errorf("error getting all account types: %w", err)
+	}
+
+	return accountTypes, nil
+}
+
+func (accountTypeRepository *AccountTypeRepository) Update(ctx context.Context, accountType model.AccountType) error {
+	query := `UPDATE account_types
+		SET description = $2, required_fields = $3, allowed_statuses = $4, attributes = $5, updated_at = $6
+		WHERE name = $1`
+
+	attributes, err := marshalAttributes(accountType.Attributes)
+	if err != nil {
+		return err
+	}
+
+	result, err := accountTypeRepository.db.ExecContext(ctx, query,
+		accountType.Name,
+		accountType.Description,
+		accountTypeRepository.dialect.array(accountType.RequiredFields),
+		accountTypeRepository.dialect.array(accountType.AllowedStatuses),
+		attributes,
+		time.Now().UTC())
+	if err != nil {
+		accountTypeRepository.logger.WithError(err).Error("Failed to update account type")
+		return fmt.Errorf("error updating account type %s: %w", accountType.Name, withKind(err))
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		accountTypeRepository.logger.WithError(err).Error("Failed to update account type")
+		return fmt.Errorf("error updating account type %s: %w", accountType.Name, err)
+	}
+
+	if affected == 0 {
+		return model.Errorf(model.ErrNotFound, "no account type %s", accountType.Name)
+	}
+
+	return nil
+}
+
+func (accountTypeRepository *AccountTypeRepository) Delete(ctx context.Context, name string) error {
+	query := `DELETE FROM account_types WHERE name = $1`
+
+	result, err := accountTypeRepository.db.ExecContext(ctx, query, name)
+	if err != nil {
+		accountTypeRepository.logger.WithError(err).Error("Failed to delete account type")
+		return fmt.Errorf("error deleting account type %s: %w", name, withKind(err))
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		accountTypeRepository.logger.WithError(err).Error("Failed to delete account type")
+		return fmt.Errorf("error deleting account type %s: %w", name, err)
+	}
+
+	if affected == 0 {
+		return model.Errorf(model.ErrNotFound, "no account type %s", name)
+	}
+
+	return nil
+}
+
+func scanAccountType(row rowScanner, dialect *dialect) (model.AccountType, error) {
+	var accountType model.AccountType
+	var description sql.NullString
+	var attributes []byte
+
+	err := row.Scan(
+		&accountType.Name,
+		&description,
+		dialect.scanArray(&accountType.RequiredFields),
+		dialect.scanArray(&accountType.AllowedStatuses),
+		&attributes,
+		&accountType.CreatedAt,
+		&accountType.UpdatedAt)
+	if err != nil {
+		return model.AccountType{}, err
+	}
+
+	accountType.Description = description.String
+	if err := json.Unmarshal(attributes, &accountType.Attributes); err != nil {
+		return model.AccountType{}, fmt.Errorf("error decoding attributes of account type %s: %w", accountType.Name, err)
+	}
+	if len(accountType.Attributes) == 0 {
+		accountType.Attributes = nil
+	}
+
+	return accountType, nil
+}
+
+func marshalAttributes(attributes map[string]model.AttributeDefinition) (string, error) {
+	if attributes == nil {
+		attributes = map[string]model.AttributeDefinition{}
+	}
+	data, err := json.Marshal(attributes)
+	if err != nil {
+		return "", fmt.Errorf("error encoding attributes: %w", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,114 @@
+package sqlstore
+
+import (
+	"account_storage/pkg/model"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type APIKeyRepository struct {
+	db     *sql.DB
+	logger *logrus.Logger
+}
+
+func (apiKeyRepository *APIKeyRepository) Create(ctx context.Context, apiKeyCreate model.APIKeyCreate) (string, error) {
+	query := `INSERT INTO api_keys (id, name, key_hash, created_at)
+		VALUES ($1, $2, $3, $4) RETURNING id`
+
+	var id string
+	err := apiKeyRepository.db.QueryRowContext(ctx, query,
+		uuid.New(),
+		apiKeyCreate.Name,
+		apiKeyCreate.KeyHash,
+		time.Now().UTC()).Scan(&id)
+
+	if err != nil {
+		apiKeyRepository.logger.WithError(err).Error("Failed to create api key")
+		return "", fmt.Errorf("error creating api key: %w", withKind(err))
+	}
+
+	return id, nil
+}
+
+func (apiKeyRepository *APIKeyRepository) GetByHash(ctx context.Context, keyHash string) (model.APIKey, error) {
+	query := `SELECT id, name, key_hash, created_at FROM api_keys WHERE key_hash = $1`
+
+	var apiKey model.APIKey
+	err := apiKeyRepository.db.QueryRowContext(ctx, query, keyHash).Scan(
+		&apiKey.ID,
+		&apiKey.Name,
+		&apiKey.KeyHash,
+		&apiKey.CreatedAt)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return model.APIKey{}, model.NewError(model.ErrNotFound, "no api key with given hash")
+		}
+		apiKeyRepository.logger.WithError(err).Error("Failed to get api key by hash")
+		return model.APIKey{}, fmt.Errorf("error getting api key by hash: %w", err)
+	}
+
+	return apiKey, nil
+}
+
+func (apiKeyRepository *APIKeyRepository) GetAll(ctx context.Context) ([]model.APIKey, error) {
+	query := "SELECT id, name, key_hash, created_at FROM api_keys"
+
+	rows, err := apiKeyRepository.db.QueryContext(ctx, query)
+	if err != nil {
+		apiKeyRepository.logger.WithError(err).Error("Failed to get all api keys")
+		return nil, fmt.Errorf("error getting all api keys: %w", err)
+	}
+	defer rows.Close()
+
+	var apiKeys []model.APIKey
+
+	for rows.Next() {
+		var apiKey model.APIKey
+		err := rows.Scan(
+			&apiKey.ID,
+			&apiKey.Name,
+			&apiKey.KeyHash,
+			&apiKey.CreatedAt,
+		)
+		if err != nil {
+			apiKeyRepository.logger.WithError(err).Error("Failed to get all api keys")
+			return nil, fmt.Errorf("error getting all api keys: %w", err)
+		}
+		apiKeys = append(apiKeys, apiKey)
+	}
+
+	if err = rows.Err(); err != nil {
+		apiKeyRepository.logger.WithError(err).Error("Failed to get all api keys")
+		return nil, fmt.Errorf("error getting all api keys: %w", err)
+	}
+
+	return apiKeys, nil
+}
+
+func (apiKeyRepository *APIKeyRepository) Delete(ctx context.Context, id string) error {
+	query := `DELETE FROM api_keys WHERE id = $1`
+
+	result, err := apiKeyRepository.db.ExecContext(ctx, query, id)
+	if err != nil {
+		apiKeyRepository.logger.WithError(err).Error("Failed to delete api key")
+		return fmt.Errorf("error deleting api key with id %s: %w", id, withKind(err))
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		apiKeyRepository.logger.WithError(err).Error("Failed to delete api key")
+		return fmt.Errorf("error deleting api key with id %s: %w", id, err)
+	}
+
+	if affected == 0 {
+		return model.Errorf(model.ErrNotFound, "no api key with id %s", id)
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package sqlstore
+
+import (
+	"account_storage/pkg/model"
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type AuditRepository struct {
+	db     *sql.DB
+	logger *logrus.Logger
+}
+
+func (auditRepository *AuditRepository) Create(ctx context.Context, auditRecordCreate model.AuditRecordCreate) (string, error) {
+	query := `INSERT INTO audit_log (id, actor, caller, action, account_id, fields, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+
+	var id string
+	err := auditRepository.db.QueryRowContext(ctx, query,
+		uuid.New(),
+		auditRecordCreate.Actor,
+		auditRecordCreate.Caller,
+		auditRecordCreate.Action,
+		auditRecordCreate.AccountID,
+		strings.Join(auditRecordCreate.Fields, ","),
+		time.Now().UTC()).Scan(&id)
+
+	if err != nil {
+		auditRepository.logger.WithError(err).Error("Failed to create audit record")
+		return "", fmt.Errorf("error creating audit record: %w", err)
+	}
+
+	return id, nil
+}
+
+func (auditRepository *AuditRepository) GetAll(ctx context.Context, filter model.AuditFilter) ([]model.AuditRecord, error) {
+	var conditions []string
+	var args []interface{}
+
+	if filter.AccountID != "" {
+		args = append(args, filter.AccountID)
+		conditions = append(conditions, fmt.Sprintf("account_id = $%d", len(args)))
+	}
+	if filter.Actor != "" {
+		args = append(args, filter.Actor)
+		conditions = append(conditions, fmt.Sprintf("actor = $%d", len(args)))
+	}
+	if !filter.From.IsZero() {
+		args = append(args, filter.From.UTC())
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if !filter.To.IsZero() {
+		args = append(args, filter.To.UTC())
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+
+	query := "SELECT id, actor, caller, action, account_id, fields, created_at FROM audit_log"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY created_at"
+
+	rows, err := auditRepository.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		auditRepository.logger.WithError(err).Error("Failed to get audit records")
+		return nil, fmt.Errorf("error getting audit records: %w", withKind(err))
+	}
+	defer rows.Close()
+
+	auditRecords := make([]model.AuditRecord, 0)
+
+	for rows.Next() {
+		var auditRecord model.AuditRecord
+		var caller, accountID, fields sql.NullString
+		err := rows.Scan(
+			&auditRecord.ID,
+			&auditRecord.Actor,
+			&caller,
+			&auditRecord.Action,
+			&accountID,
+			&fields,
+			&auditRecord.CreatedAt,
+		)
+		if err != nil {
+			auditRepository.logger.WithError(err).Error("Failed to get audit records")
+			return nil, fmt.Errorf("error getting audit records: %w", err)
+		}
+
+		auditRecord.Caller = caller.String
+		auditRecord.AccountID = accountID.String
+		if fields.String != "" {
+			auditRecord.Fields = strings.Split(fields.String, ",")
+		}
+
+		auditRecords = append(auditRecords, auditRecord)
+	}
+
+	if err = rows.Err(); err != nil {
+		auditRepository.logger.WithError(err).Error("Failed to get audit records")
+		return nil, fmt.Errorf("error getting audit records: %w", err)
+	}
+
+	return auditRecords, nil
+}
@@ -0,0 +1,63 @@
+package sqlstore
+
+import (
+	"account_storage/internal/app/encryption"
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// BackfillDataKeys returns a migration hook that encrypts the secrets of the
+// accounts written before envelope encryption, which still hold them in
+// plain text without a data key.
+func BackfillDataKeys(envelope *encryption.Envelope) func(ctx context.Context, tx *sql.Tx) error {
+	return func(ctx context.Context, tx *sql.Tx) error {
+		query := `SELECT id, COALESCE(password, ''), COALESCE(email_password, ''),
+			COALESCE(recovery_email_password, ''), COALESCE(cookie, '')
+			FROM accounts WHERE data_key IS NULL OR key_version IS NULL`
+
+		rows, err := tx.QueryContext(ctx, query)
+		if err != nil {
+			return fmt.Errorf("error getting accounts without data key: %w", err)
+		}
+
+		type plainAccount struct {
+			id      string
+			secrets [4]string
+		}
+		var accounts []plainAccount
+		for rows.Next() {
+			var account plainAccount
+			err := rows.Scan(&account.id, &account.secrets[0], &account.secrets[1], &account.secrets[2], &account.secrets[3])
+			if err != nil {
+				rows.Close()
+				return fmt.Errorf("error getting accounts without data key: %w", err)
+			}
+			accounts = append(accounts, account)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error getting accounts without data key: %w", err)
+		}
+
+		query = `UPDATE accounts SET password = $2, email_password = $3, recovery_email_password = $4, cookie = $5,
+			data_key = $6, key_version = $7
+			WHERE id = $1`
+
+		for _, account := range accounts {
+			secrets := account.secrets
+			dataKey, err := envelope.Seal(ctx, &secrets[0], &secrets[1], &secrets[2], &secrets[3])
+			if err != nil {
+				return fmt.Errorf("error encrypting account with id %s: %w", account.id, err)
+			}
+
+			_, err = tx.ExecContext(ctx, query, account.id, secrets[0], secrets[1], secrets[2], secrets[3],
+				dataKey.Ciphertext, dataKey.Version)
+			if err != nil {
+				return fmt.Errorf("error backfilling data key of account with id %s: %w", account.id, err)
+			}
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,95 @@
+package sqlstore
+
+import (
+	"account_storage/pkg/model"
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// querier is satisfied by both *sql.DB and *sql.Tx, so the same queries run
+// on their own or as part of a batch transaction.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func (accountRepository *AccountRepository) CreateBatch(ctx context.Context, accountCreates []model.AccountCreate, mode model.BatchMode) ([]model.BatchResult, error) {
+	return accountRepository.runBatch(ctx, len(accountCreates), mode, func(tx *sql.Tx, index int) (model.BatchResult, error) {
+		id, err := accountRepository.create(ctx, tx, accountCreates[index])
+		if err != nil {
+			return model.BatchResult{}, err
+		}
+		return model.BatchResult{ID: id, Version: 1}, nil
+	})
+}
+
+func (accountRepository *AccountRepository) UpdateBatch(ctx context.Context, accounts []model.Account, mode model.BatchMode) ([]model.BatchResult, error) {
+	return accountRepository.runBatch(ctx, len(accounts), mode, func(tx *sql.Tx, index int) (model.BatchResult, error) {
+		account := accounts[index]
+		err := accountRepository.update(ctx, tx, account)
+		if err != nil {
+			return model.BatchResult{ID: account.ID.String()}, err
+		}
+		return model.BatchResult{ID: account.ID.String(), Version: account.Version + 1}, nil
+	})
+}
+
+func (accountRepository *AccountRepository) DeleteBatch(ctx context.Context, accountVersions []model.AccountVersion, mode model.BatchMode) ([]model.BatchResult, error) {
+	return accountRepository.runBatch(ctx, len(accountVersions), mode, func(tx *sql.Tx, index int) (model.BatchResult, error) {
+		accountVersion := accountVersions[index]
+		err := accountRepository.delete(ctx, tx, accountVersion.ID, accountVersion.Version)
+		return model.BatchResult{ID: accountVersion.ID}, err
+	})
+}
+
+// runBatch applies the items of a batch in a single transaction. In atomic
+// mode the first failing item rolls back the whole batch and its error is
+// returned. In best effort mode every item runs in a savepoint, so a failing
+// item is rolled back alone and its error is put into its result.
+func (accountRepository *AccountRepository) runBatch(
+	ctx context.Context, size int, mode model.BatchMode, apply func(tx *sql.Tx, index int) (model.BatchResult, error)) ([]model.BatchResult, error) {
+	tx, err := accountRepository.db.BeginTx(ctx, nil)
+	if err != nil {
+		accountRepository.logger.WithError(err).Error("Failed to begin batch transaction")
+		return nil, fmt.Errorf("error beginning batch transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	results := make([]model.BatchResult, size)
+	for index := range results {
+		if mode.Atomic() {
+			results[index], err = apply(tx, index)
+			if err != nil {
+				return nil, model.BatchItemError(index, err)
+			}
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, "SAVEPOINT batch_item"); err != nil {
+			accountRepository.logger.WithError(err).Error("Failed to create batch item savepoint")
+			return nil, fmt.Errorf("error creating savepoint for batch item %d: %w", index, err)
+		}
+
+		result, err := apply(tx, index)
+		if err != nil {
+			if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT batch_item"); err != nil {
+				accountRepository.logger.WithError(err).Error("Failed to roll back batch item")
+				return nil, fmt.Errorf("error rolling back batch item %d: %w", index, err)
+			}
+			result.Err = err
+		} else if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT batch_item"); err != nil {
+			accountRepository.logger.WithError(err).Error("Failed to release batch item savepoint")
+			return nil, fmt.Errorf("error releasing savepoint for batch item %d: %w", index, err)
+		}
+		results[index] = result
+	}
+
+	if err := tx.Commit(); err != nil {
+		accountRepository.logger.WithError(err).Error("Failed to commit batch transaction")
+		return nil, fmt.Errorf("error committing batch transaction: %w", err)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,230 @@
+package sqlstore
+
+import (
+	"account_storage/internal/app/encryption"
+	"account_storage/pkg/model"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// CredentialHistoryRepository keeps every replaced version of the secret
+// fields of the accounts, each sealed with its own data key.
+type CredentialHistoryRepository struct {
+	db       *sql.DB
+	envelope *encryption.Envelope
+	logger   *logrus.Logger
+}
+
+// Create keeps a credential version. A version that is already kept is left
+// as it is, it holds the same secrets.
+func (credentialHistoryRepository *CredentialHistoryRepository) Create(ctx context.Context, credentialVersion model.CredentialVersion) error {
+	query := `INSERT INTO account_credential_history (account_id, version, password, email_password, recovery_email_password, cookie, attributes, data_key, key_version, actor, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (account_id, version) DO NOTHING`
+
+	dataKey, err := credentialHistoryRepository.envelope.Seal(ctx, credentialVersion.Secrets()...)
+	if err != nil {
+		credentialHistoryRepository.logger.WithError(err).Error("Failed to encrypt credential version")
+		return fmt.Errorf("error encrypting credential version: %w", err)
+	}
+
+	attributes, err := attributesValue(credentialVersion.Attributes)
+	if err != nil {
+		return err
+	}
+
+	_, err = credentialHistoryRepository.db.ExecContext(ctx, query,
+		credentialVersion.AccountID,
+		credentialVersion.Version,
+		credentialVersion.Password,
+		credentialVersion.EmailPassword,
+		credentialVersion.RecoveryEmailPassword,
+		credentialVersion.Cookie,
+		attributes,
+		dataKey.Ciphertext,
+		dataKey.Version,
+		credentialVersion.Actor,
+		time.Now().UTC())
+
+	if err != nil {
+		credentialHistoryRepository.logger.WithError(err).Error("Failed to create credential version")
+		return fmt.Errorf("error creating credential version: %w", withKind(err))
+	}
+
+	return nil
+}
+
+const credentialVersionColumns = `account_id, version, password, email_password, recovery_email_password, cookie, attributes, data_key, key_version, actor, created_at`
+
+// GetByAccountID returns the credential versions of an account, oldest
+// first.
+func (credentialHistoryRepository *CredentialHistoryRepository) GetByAccountID(ctx context.Context, accountID string) ([]model.CredentialVersion, error) {
+	query := `SELECT ` + credentialVersionColumns + `
+		FROM account_credential_history WHERE account_id = $1 ORDER BY version`
+
+	rows, err := credentialHistoryRepository.db.QueryContext(ctx, query, accountID)
+	if err != nil {
+		credentialHistoryRepository.logger.WithError(err).Error("Failed to get credential history")
+		return nil, fmt.Errorf("error getting credential history of account with id %s: %w", accountID, withKind(err))
+	}
+	defer rows.Close()
+
+	credentialVersions := make([]model.CredentialVersion, 0)
+
+	for rows.Next() {
+		credentialVersion, err := credentialHistoryRepository.scan(ctx, rows)
+		if err != nil {
+			credentialHistoryRepository.logger.WithError(err).Error("Failed to get credential history")
+			return nil, fmt.Errorf("error getting credential history of account with id %s: %w", accountID, err)
+		}
+
+		credentialVersions = append(credentialVersions, credentialVersion)
+	}
+
+	if err = rows.Err(); err != nil {
+		credentialHistoryRepository.logger.WithError(err).Error("Failed to get credential history")
+		return nil, fmt.Errorf("error getting credential history of account with id %s: %w", accountID, err)
+	}
+
+	return credentialVersions, nil
+}
+
+func (credentialHistoryRepository *CredentialHistoryRepository) GetVersion(ctx context.Context, accountID string, version int64) (model.CredentialVersion, error) {
+	query := `SELECT ` + credentialVersionColumns + `
+		FROM account_credential_history WHERE account_id = $1 AND version = $2`
+
+	row := credentialHistoryRepository.db.QueryRowContext(ctx, query, accountID, version)
+
+	credentialVersion, err := credentialHistoryRepository.scan(ctx, row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return model.CredentialVersion{}, model.Errorf(model.ErrNotFound, "no credential version %d of account with id %s", version, accountID)
+		}
+		credentialHistoryRepository.logger.WithError(err).Error("Failed to get credential version")
+		return model.CredentialVersion{}, fmt.Errorf("error getting credential version %d of account with id %s: %w", version, accountID, withKind(err))
+	}
+
+	return credentialVersion, nil
+}
+
+// scan reads the credentialVersionColumns of a row and opens the secrets.
+func (credentialHistoryRepository *CredentialHistoryRepository) scan(ctx context.Context, row rowScanner) (model.CredentialVersion, error) {
+	var credentialVersion model.CredentialVersion
+	var password, emailPassword, recoveryEmailPassword, cookie, actor sql.NullString
+	var attributes []byte
+	var dataKey encryption.WrappedKey
+	err := row.Scan(
+		&credentialVersion.AccountID,
+		&credentialVersion.Version,
+		&password,
+		&emailPassword,
+		&recoveryEmailPassword,
+		&cookie,
+		&attributes,
+		&dataKey.Ciphertext,
+		&dataKey.Version,
+		&actor,
+		&credentialVersion.CreatedAt,
+	)
+	if err != nil {
+		return model.CredentialVersion{}, err
+	}
+
+	credentialVersion.Password = password.String
+	credentialVersion.EmailPassword = emailPassword.String
+	credentialVersion.RecoveryEmailPassword = recoveryEmailPassword.String
+	credentialVersion.Cookie = cookie.String
+	credentialVersion.Actor = actor.String
+
+	if len(attributes) > 0 {
+		if err := json.Unmarshal(attributes, &credentialVersion.Attributes); err != nil {
+			return model.CredentialVersion{}, fmt.Errorf("error decoding attributes: %w", err)
+		}
+	}
+
+	err = credentialHistoryRepository.envelope.Open(ctx, dataKey, credentialVersion.Secrets()...)
+	if err != nil {
+		return model.CredentialVersion{}, fmt.Errorf("error decrypting credential version: %w", err)
+	}
+
+	return credentialVersion, nil
+}
+
+// Rewrap re-encrypts the data keys that are not wrapped with the current
+// master key, like AccountRepository.Rewrap.
+func (credentialHistoryRepository *CredentialHistoryRepository) Rewrap(ctx context.Context) (int, error) {
+	currentVersion, err := credentialHistoryRepository.envelope.Refresh(ctx)
+	if err != nil {
+		credentialHistoryRepository.logger.WithError(err).Error("Failed to refresh master keys")
+		return 0, err
+	}
+
+	query := "SELECT account_id, version, data_key, key_version FROM account_credential_history WHERE key_version <> $1"
+
+	rows, err := credentialHistoryRepository.db.QueryContext(ctx, query, currentVersion)
+	if err != nil {
+		credentialHistoryRepository.logger.WithError(err).Error("Failed to get credential versions to rewrap")
+		return 0, fmt.Errorf("error getting credential versions to rewrap: %w", err)
+	}
+	defer rows.Close()
+
+	type credentialKey struct {
+		accountID string
+		version   int64
+		dataKey   encryption.WrappedKey
+	}
+	var credentialKeys []credentialKey
+
+	for rows.Next() {
+		var key credentialKey
+		err := rows.Scan(&key.accountID, &key.version, &key.dataKey.Ciphertext, &key.dataKey.Version)
+		if err != nil {
+			credentialHistoryRepository.logger.WithError(err).Error("Failed to get credential versions to rewrap")
+			return 0, fmt.Errorf("error getting credential versions to rewrap: %w", err)
+		}
+		credentialKeys = append(credentialKeys, key)
+	}
+
+	if err = rows.Err(); err != nil {
+		credentialHistoryRepository.logger.WithError(err).Error("Failed to get credential versions to rewrap")
+		return 0, fmt.Errorf("error getting credential versions to rewrap: %w", err)
+	}
+
+	query = `UPDATE account_credential_history SET data_key = $3, key_version = $4
+		WHERE account_id = $1 AND version = $2 AND key_version = $5`
+
+	rewrapped := 0
+	for _, key := range credentialKeys {
+		rewrappedKey, err := credentialHistoryRepository.envelope.Rewrap(ctx, key.dataKey)
+		if err != nil {
+			credentialHistoryRepository.logger.WithError(err).Error("Failed to rewrap data key")
+			return rewrapped, fmt.Errorf("error rewrapping data key of credential version %d of account with id %s: %w", key.version, key.accountID, err)
+		}
+
+		result, err := credentialHistoryRepository.db.ExecContext(ctx, query,
+			key.accountID,
+			key.version,
+			rewrappedKey.Ciphertext,
+			rewrappedKey.Version,
+			key.dataKey.Version,
+		)
+		if err != nil {
+			credentialHistoryRepository.logger.WithError(err).Error("Failed to rewrap data key")
+			return rewrapped, fmt.Errorf("error updating data key of credential version %d of account with id %s: %w", key.version, key.accountID, err)
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			credentialHistoryRepository.logger.WithError(err).Error("Failed to rewrap data key")
+			return rewrapped, fmt.Errorf("error updating data key of credential version %d of account with id %s: %w", key.version, key.accountID, err)
+		}
+		rewrapped += int(affected)
+	}
+
+	return rewrapped, nil
+}
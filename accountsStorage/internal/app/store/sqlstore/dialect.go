@@ -0,0 +1,126 @@
+package sqlstore
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// dialect is the SQL that differs between Postgres and SQLite. Both take
+// $N placeholders, the queries are otherwise shared.
+type dialect struct {
+	// array binds a list of strings as one argument.
+	array func(values []string) interface{}
+	// scanArray scans a column written by array or by tagsColumn.
+	scanArray func(values *[]string) interface{}
+	// list selects the elements of the list bound as argument n in a column
+	// named value. Postgres needs their type, text or uuid.
+	list func(n int, elementType string) string
+	// tagsColumn selects the sorted tags of the accounts row.
+	tagsColumn string
+	// attributeCondition matches the accounts with a non-secret attribute
+	// name of value and appends its arguments.
+	attributeCondition func(name, value string, args []interface{}) (string, []interface{})
+	// lockRows, skipLocked and shareRows end the SELECTs whose rows a
+	// transaction goes on to change or depends on. SQLite needs none of
+	// them, it runs one write transaction at a time.
+	lockRows   string
+	skipLocked string
+	shareRows  string
+}
+
+var postgresDialect = &dialect{
+	array: func(values []string) interface{} {
+		if values == nil {
+			values = []string{}
+		}
+		return pq.Array(values)
+	},
+	scanArray: func(values *[]string) interface{} {
+		return pq.Array(values)
+	},
+	list: func(n int, elementType string) string {
+		return fmt.Sprintf("SELECT unnest($%d::%s[]) AS value", n, elementType)
+	},
+	tagsColumn: "ARRAY(SELECT tag FROM account_tags WHERE account_tags.account_id = accounts.id ORDER BY tag)",
+	attributeCondition: func(name, value string, args []interface{}) (string, []interface{}) {
+		args = append(args, name, value)
+		return fmt.Sprintf(
+			"attributes @> jsonb_build_object($%[1]d::text, jsonb_build_object('value', $%[2]d::text)) AND NOT attributes @> jsonb_build_object($%[1]d::text, '{\"secret\": true}'::jsonb)",
+			len(args)-1, len(args)), args
+	},
+	lockRows:   " FOR UPDATE",
+	skipLocked: " FOR UPDATE SKIP LOCKED",
+	shareRows:  " FOR SHARE",
+}
+
+var sqliteDialect = &dialect{
+	array: func(values []string) interface{} {
+		return stringArray(values)
+	},
+	scanArray: func(values *[]string) interface{} {
+		return (*stringArray)(values)
+	},
+	list: func(n int, _ string) string {
+		return fmt.Sprintf("SELECT value FROM json_each($%d)", n)
+	},
+	tagsColumn: "(SELECT json_group_array(tag) FROM (SELECT tag FROM account_tags WHERE account_tags.account_id = accounts.id ORDER BY tag))",
+	attributeCondition: func(name, value string, args []interface{}) (string, []interface{}) {
+		// Attribute names are restricted to letters, digits, - and _, so
+		// they need no escaping in a quoted JSON path.
+		path := fmt.Sprintf(`$."%s"`, name)
+		args = append(args, path+".value", value, path+".secret")
+		return fmt.Sprintf(
+			"json_extract(attributes, $%d) = $%d AND NOT COALESCE(json_extract(attributes, $%d), 0)",
+			len(args)-2, len(args)-1, len(args)), args
+	},
+}
+
+// accountColumns are the columns scanAccount reads, in order.
+func (dialect *dialect) accountColumns() string {
+	return "id, name, account_type, login, password, email, email_password, recovery_email, recovery_email_password, " +
+		"cookie, status, created_at, data_key, key_version, version, cookies_expire_at, attributes, deleted_at, " + dialect.tagsColumn
+}
+
+// stringArray is a list of strings kept as a JSON array, SQLite has no
+// array type. Like pq.Array it is bound as an argument and scanned into.
+type stringArray []string
+
+func (array stringArray) Value() (driver.Value, error) {
+	if array == nil {
+		array = stringArray{}
+	}
+	data, err := json.Marshal([]string(array))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (array *stringArray) Scan(src interface{}) error {
+	var data []byte
+	switch src := src.(type) {
+	case nil:
+		*array = nil
+		return nil
+	case string:
+		data = []byte(src)
+	case []byte:
+		data = src
+	default:
+		return fmt.Errorf("cannot scan %T into a string array", src)
+	}
+	return json.Unmarshal(data, (*[]string)(array))
+}
+
+// utcTime returns t in UTC, NULL when t is nil. SQLite stores times as text,
+// so they must all be in the same zone to compare right.
+func utcTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
@@ -0,0 +1,41 @@
+package sqlstore
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+
+	"account_storage/pkg/model"
+)
+
+const (
+	uniqueViolation           = "23505"
+	invalidTextRepresentation = "22P02"
+)
+
+// withKind gives postgres and SQLite errors caused by the caller's input the
+// matching model error kind, so they are not reported as internal errors.
+func withKind(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case uniqueViolation:
+			return model.Errorf(model.ErrConflict, "%w", err)
+		case invalidTextRepresentation:
+			return model.Errorf(model.ErrInvalidArgument, "%w", err)
+		}
+		return err
+	}
+
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code() {
+		case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+			return model.Errorf(model.ErrConflict, "%w", err)
+		}
+	}
+
+	return err
+}
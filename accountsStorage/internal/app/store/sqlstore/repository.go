@@ -1,12 +1,15 @@
 package sqlstore
 
 import (
+	"account_storage/internal/app/encryption"
 	"account_storage/pkg/model"
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,19 +17,38 @@ import (
 )
 
 type AccountRepository struct {
-	db     *sql.DB
-	logger *logrus.Logger
+	db       *sql.DB
+	dialect  *dialect
+	envelope *encryption.Envelope
+	logger   *logrus.Logger
 }
 
 func (accountRepository *AccountRepository) Create(ctx context.Context, accountCreate model.AccountCreate) (string, error) {
-	query := `INSERT INTO accounts (id, name, account_type, login, password, email, email_password, recovery_email, recovery_email_password, cookie, status, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id`
+	return accountRepository.create(ctx, accountRepository.db, accountCreate)
+}
+
+func (accountRepository *AccountRepository) create(ctx context.Context, db querier, accountCreate model.AccountCreate) (string, error) {
+	query := `INSERT INTO accounts (id, name, account_type, login, password, email, email_password, recovery_email, recovery_email_password, cookie, status, created_at, data_key, key_version, cookies_expire_at, attributes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16) RETURNING id`
+
+	cookiesExpireAt := model.CookiesExpireAt(accountCreate.Cookie)
+
+	dataKey, err := accountRepository.envelope.Seal(ctx, accountCreate.Secrets()...)
+	if err != nil {
+		accountRepository.logger.WithError(err).Error("Failed to encrypt account")
+		return "", fmt.Errorf("error encrypting account: %w", err)
+	}
+
+	attributes, err := attributesValue(accountCreate.Attributes)
+	if err != nil {
+		return "", err
+	}
 
 	accountID := uuid.New()
-	accountCreatedAt := time.Now()
+	accountCreatedAt := time.Now().UTC()
 
 	var id string
-	err := accountRepository.db.QueryRowContext(ctx, query,
+	err = db.QueryRowContext(ctx, query,
 		accountID,
 		accountCreate.Name,
 		accountCreate.AccountType,
@@ -38,7 +60,11 @@ func (accountRepository *AccountRepository) Create(ctx context.Context, accountC
 		accountCreate.RecoveryEmailPassword,
 		accountCreate.Cookie,
 		accountCreate.Status,
-		accountCreatedAt).Scan(&id)
+		accountCreatedAt,
+		dataKey.Ciphertext,
+		dataKey.Version,
+		utcTime(cookiesExpireAt),
+		attributes).Scan(&id)
 
 	if err != nil {
 		accountRepository.logger.WithError(err).Error("Failed to create account")
@@ -49,42 +75,52 @@ func (accountRepository *AccountRepository) Create(ctx context.Context, accountC
 }
 
 func (accountRepository *AccountRepository) GetByID(ctx context.Context, id string) (model.Account, error) {
-	query := `SELECT id, name, account_type, login, password, email, email_password, recovery_email, recovery_email_password, cookie, status, created_at
-		FROM accounts WHERE id = $1`
+	query := "SELECT " + accountRepository.dialect.accountColumns() + " FROM accounts WHERE id = $1 AND deleted_at IS NULL"
 
-	var account model.Account
-	err := accountRepository.db.QueryRowContext(ctx, query, id).Scan(
-		&account.ID,
-		&account.Name,
-		&account.AccountType,
-		&account.Login,
-		&account.Password,
-		&account.Email,
-		&account.EmailPassword,
-		&account.RecoveryEmail,
-		&account.RecoveryEmailPassword,
-		&account.Cookie,
-		&account.Status,
-		&account.CreatedAt)
+	account, dataKey, err := scanAccount(accountRepository.db.QueryRowContext(ctx, query, id), accountRepository.dialect)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
 			accountRepository.logger.WithError(err).Error("Failed to get account by id")
-			return model.Account{}, fmt.Errorf("no account with id %s", id)
+			return model.Account{}, model.Errorf(model.ErrNotFound, "no account with id %s", id)
 		}
 		accountRepository.logger.WithError(err).Error("Failed to get account by id")
-		return model.Account{}, fmt.Errorf("error getting account by id: %w", err)
+		return model.Account{}, fmt.Errorf("error getting account by id: %w", withKind(err))
+	}
+
+	err = accountRepository.envelope.Open(ctx, dataKey, account.Secrets()...)
+	if err != nil {
+		accountRepository.logger.WithError(err).Error("Failed to decrypt account")
+		return model.Account{}, fmt.Errorf("error decrypting account with id %s: %w", id, err)
 	}
 
 	return account, nil
 }
 
 func (accountRepository *AccountRepository) Update(ctx context.Context, account model.Account) error {
+	return accountRepository.update(ctx, accountRepository.db, account)
+}
+
+func (accountRepository *AccountRepository) update(ctx context.Context, db querier, account model.Account) error {
 	query := `UPDATE accounts SET name = $2, account_type = $3, login = $4, password = $5, email = $6, email_password = $7, 
-		recovery_email = $8, recovery_email_password = $9, cookie = $10, status = $11
-		WHERE id = $1`
+		recovery_email = $8, recovery_email_password = $9, cookie = $10, status = $11, data_key = $12, key_version = $13,
+		cookies_expire_at = $15, attributes = $16, version = version + 1
+		WHERE id = $1 AND version = $14 AND deleted_at IS NULL`
+
+	cookiesExpireAt := model.CookiesExpireAt(account.Cookie)
+
+	dataKey, err := accountRepository.envelope.Seal(ctx, account.Secrets()...)
+	if err != nil {
+		accountRepository.logger.WithError(err).Error("Failed to encrypt account")
+		return fmt.Errorf("error encrypting account: %w", err)
+	}
 
-	_, err := accountRepository.db.ExecContext(ctx, query,
+	attributes, err := attributesValue(account.Attributes)
+	if err != nil {
+		return err
+	}
+
+	result, err := db.ExecContext(ctx, query,
 		account.ID,
 		account.Name,
 		account.AccountType,
@@ -96,66 +132,610 @@ func (accountRepository *AccountRepository) Update(ctx context.Context, account
 		account.RecoveryEmailPassword,
 		account.Cookie,
 		account.Status,
+		dataKey.Ciphertext,
+		dataKey.Version,
+		account.Version,
+		utcTime(cookiesExpireAt),
+		attributes,
 	)
 
-	accountRepository.logger.WithError(err).Error("Failed to update account")
-	return fmt.Errorf("error updating account with id %s: %w", account.ID, err)
+	if err != nil {
+		accountRepository.logger.WithError(err).Error("Failed to update account")
+		return fmt.Errorf("error updating account with id %s: %w", account.ID, withKind(err))
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		accountRepository.logger.WithError(err).Error("Failed to update account")
+		return fmt.Errorf("error updating account with id %s: %w", account.ID, err)
+	}
+
+	if affected == 0 {
+		return accountRepository.versionMismatch(ctx, db, account.ID.String(), account.Version)
+	}
+
+	return nil
+}
+
+func (accountRepository *AccountRepository) Delete(ctx context.Context, id string, version int64) error {
+	return accountRepository.delete(ctx, accountRepository.db, id, version)
 }
 
-func (accountRepository *AccountRepository) Delete(ctx context.Context, id string) error {
-	query := `DELETE FROM accounts WHERE id = $1`
+// delete moves an account to the trash. Its lease ends, its secrets and
+// tags are kept until it is purged.
+func (accountRepository *AccountRepository) delete(ctx context.Context, db querier, id string, version int64) error {
+	query := `UPDATE accounts SET deleted_at = $3, lease_id = NULL, leased_by = NULL, lease_expires_at = NULL, version = version + 1
+		WHERE id = $1 AND version = $2 AND deleted_at IS NULL`
 
-	_, err := accountRepository.db.ExecContext(ctx, query, id)
+	result, err := db.ExecContext(ctx, query, id, version, time.Now().UTC())
+	if err != nil {
+		accountRepository.logger.WithError(err).Error("Failed to delete account")
+		return fmt.Errorf("error deleting account with id %s: %w", id, withKind(err))
+	}
 
+	affected, err := result.RowsAffected()
 	if err != nil {
 		accountRepository.logger.WithError(err).Error("Failed to delete account")
 		return fmt.Errorf("error deleting account with id %s: %w", id, err)
 	}
 
+	if affected == 0 {
+		return accountRepository.versionMismatch(ctx, db, id, version)
+	}
+
 	return nil
 }
 
-func (accountRepository *AccountRepository) GetAll(ctx context.Context) ([]model.Account, error) {
-	query := "SELECT id, name, account_type, login, password, email, email_password, recovery_email, recovery_email_password, cookie, status, created_at FROM accounts"
+// Restore takes an account out of the trash and returns its new version.
+func (accountRepository *AccountRepository) Restore(ctx context.Context, id string) (int64, error) {
+	query := `UPDATE accounts SET deleted_at = NULL, version = version + 1
+		WHERE id = $1 AND deleted_at IS NOT NULL
+		RETURNING version`
+
+	var version int64
+	err := accountRepository.db.QueryRowContext(ctx, query, id).Scan(&version)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, model.Errorf(model.ErrNotFound, "no deleted account with id %s", id)
+		}
+		accountRepository.logger.WithError(err).Error("Failed to restore account")
+		return 0, fmt.Errorf("error restoring account with id %s: %w", id, withKind(err))
+	}
+
+	return version, nil
+}
+
+// Purge removes the accounts deleted before deletedBefore for good and
+// returns their IDs.
+func (accountRepository *AccountRepository) Purge(ctx context.Context, deletedBefore time.Time) ([]string, error) {
+	query := `DELETE FROM accounts WHERE deleted_at < $1 RETURNING id`
+
+	rows, err := accountRepository.db.QueryContext(ctx, query, deletedBefore.UTC())
+	if err != nil {
+		accountRepository.logger.WithError(err).Error("Failed to purge accounts")
+		return nil, fmt.Errorf("error purging accounts: %w", withKind(err))
+	}
+	defer rows.Close()
+
+	var ids []string
+
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			accountRepository.logger.WithError(err).Error("Failed to purge accounts")
+			return nil, fmt.Errorf("error purging accounts: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		accountRepository.logger.WithError(err).Error("Failed to purge accounts")
+		return nil, fmt.Errorf("error purging accounts: %w", err)
+	}
+
+	return ids, nil
+}
+
+// versionMismatch tells a missing account from a stale version after a
+// compare-and-swap matched no row.
+func (accountRepository *AccountRepository) versionMismatch(ctx context.Context, db querier, id string, version int64) error {
+	query := `SELECT version FROM accounts WHERE id = $1 AND deleted_at IS NULL`
 
-	rows, err := accountRepository.db.QueryContext(ctx, query)
+	var current int64
+	err := db.QueryRowContext(ctx, query, id).Scan(&current)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return model.Errorf(model.ErrNotFound, "no account with id %s", id)
+		}
+		accountRepository.logger.WithError(err).Error("Failed to get account version")
+		return fmt.Errorf("error getting version of account with id %s: %w", id, err)
+	}
+
+	return model.Errorf(model.ErrPreconditionFailed, "account with id %s is at version %d, not %d", id, current, version)
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanAccount reads the accountColumns of a row. The secrets of the account
+// are still sealed with the returned data key.
+func scanAccount(row rowScanner, dialect *dialect) (model.Account, encryption.WrappedKey, error) {
+	var account model.Account
+	var dataKey encryption.WrappedKey
+	var cookiesExpireAt sql.NullTime
+	var attributes []byte
+	var deletedAt sql.NullTime
+	err := row.Scan(
+		&account.ID,
+		&account.Name,
+		&account.AccountType,
+		&account.Login,
+		&account.Password,
+		&account.Email,
+		&account.EmailPassword,
+		&account.RecoveryEmail,
+		&account.RecoveryEmailPassword,
+		&account.Cookie,
+		&account.Status,
+		&account.CreatedAt,
+		&dataKey.Ciphertext,
+		&dataKey.Version,
+		&account.Version,
+		&cookiesExpireAt,
+		&attributes,
+		&deletedAt,
+		dialect.scanArray(&account.Tags))
+	if err != nil {
+		return model.Account{}, encryption.WrappedKey{}, err
+	}
+	if cookiesExpireAt.Valid {
+		account.CookiesExpireAt = &cookiesExpireAt.Time
+	}
+	if deletedAt.Valid {
+		account.DeletedAt = &deletedAt.Time
+	}
+	if attributes != nil {
+		if err := json.Unmarshal(attributes, &account.Attributes); err != nil {
+			return model.Account{}, encryption.WrappedKey{}, fmt.Errorf("error decoding attributes of account with id %s: %w", account.ID, err)
+		}
+	}
+	return account, dataKey, nil
+}
+
+// attributesValue returns the attributes as stored in the attributes
+// column, NULL when there are none. The JSON goes in as text, SQLite would
+// take a blob for its binary JSON format.
+func attributesValue(attributes model.Attributes) (interface{}, error) {
+	if len(attributes) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(attributes)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding attributes: %w", err)
+	}
+	return string(data), nil
+}
+
+var accountSortColumns = map[string]string{
+	model.SortByCreatedAt: "created_at",
+	model.SortByName:      "COALESCE(name, '')",
+}
+
+// GetAll returns one page of accounts. Pages are continued with keyset
+// pagination on (sort column, id), so deep pages are as cheap as the first.
+func (accountRepository *AccountRepository) GetAll(ctx context.Context, filter model.AccountFilter) (model.AccountPage, error) {
+	sortColumn, ok := accountSortColumns[filter.Sort.Field]
+	if !ok {
+		sortColumn = accountSortColumns[model.SortByCreatedAt]
+	}
+
+	direction, comparison := "ASC", ">"
+	if filter.Sort.Descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	conditions, args := accountRepository.filterConditions(filter)
+
+	if filter.Cursor != "" {
+		after, err := model.ParseAccountCursor(filter.Cursor, filter.Sort)
+		if err != nil {
+			return model.AccountPage{}, err
+		}
+
+		if filter.Sort.Field == model.SortByName {
+			args = append(args, after.Name)
+		} else {
+			args = append(args, after.CreatedAt.UTC())
+		}
+		args = append(args, after.ID)
+		conditions = append(conditions, fmt.Sprintf("(%s, id) %s ($%d, $%d)", sortColumn, comparison, len(args)-1, len(args)))
+	}
+
+	pageSize := filter.PageSize()
+
+	query := "SELECT " + accountRepository.dialect.accountColumns() + " FROM accounts WHERE " + strings.Join(conditions, " AND ")
+	query += fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT %d", sortColumn, direction, direction, pageSize+1)
+
+	rows, err := accountRepository.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		accountRepository.logger.WithError(err).Error("Failed to get all accounts")
-		return nil, fmt.Errorf("error getting all accounts: %w", err)
+		return model.AccountPage{}, fmt.Errorf("error getting all accounts: %w", err)
 	}
 	defer rows.Close()
 
-	var accounts []model.Account
+	accounts := make([]model.Account, 0, pageSize)
 
 	for rows.Next() {
-		var account model.Account
-		err := rows.Scan(
-			&account.ID,
-			&account.Name,
-			&account.AccountType,
-			&account.Login,
-			&account.Password,
-			&account.Email,
-			&account.EmailPassword,
-			&account.RecoveryEmail,
-			&account.RecoveryEmailPassword,
-			&account.Cookie,
-			&account.Status,
-			&account.CreatedAt,
-		)
+		account, dataKey, err := scanAccount(rows, accountRepository.dialect)
 		if err != nil {
 			accountRepository.logger.WithError(err).Error("Failed to get all accounts")
-			return nil, fmt.Errorf("error getting all accounts: %w", err)
+			return model.AccountPage{}, fmt.Errorf("error getting all accounts: %w", err)
+		}
+
+		err = accountRepository.envelope.Open(ctx, dataKey, account.Secrets()...)
+		if err != nil {
+			accountRepository.logger.WithError(err).Error("Failed to decrypt account")
+			return model.AccountPage{}, fmt.Errorf("error decrypting account with id %s: %w", account.ID, err)
 		}
+
 		accounts = append(accounts, account)
 	}
 
 	if err = rows.Err(); err != nil {
 		accountRepository.logger.WithError(err).Error("Failed to get all accounts")
-		return nil, fmt.Errorf("error getting all accounts: %w", err)
+		return model.AccountPage{}, fmt.Errorf("error getting all accounts: %w", err)
+	}
+
+	page := model.AccountPage{Accounts: accounts}
+	if len(accounts) > pageSize {
+		page.Accounts = accounts[:pageSize]
+		page.NextCursor = model.NewAccountCursor(page.Accounts[pageSize-1], filter.Sort)
 	}
 
-	return accounts, nil
+	return page, nil
+}
+
+// filterConditions returns the WHERE conditions and their arguments for
+// every field of filter but the cursor.
+func (accountRepository *AccountRepository) filterConditions(filter model.AccountFilter) ([]string, []interface{}) {
+	conditions := []string{"deleted_at IS NULL"}
+	if filter.Deleted {
+		conditions[0] = "deleted_at IS NOT NULL"
+	}
+	var args []interface{}
+
+	if filter.AccountType != "" {
+		args = append(args, filter.AccountType)
+		conditions = append(conditions, fmt.Sprintf("account_type = $%d", len(args)))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+	if filter.Email != "" {
+		args = append(args, filter.Email)
+		conditions = append(conditions, fmt.Sprintf("LOWER(email) = LOWER($%d)", len(args)))
+	}
+	if !filter.CreatedAfter.IsZero() {
+		args = append(args, filter.CreatedAfter.UTC())
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if !filter.CreatedBefore.IsZero() {
+		args = append(args, filter.CreatedBefore.UTC())
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+	if !filter.CookiesExpiringBefore.IsZero() {
+		args = append(args, filter.CookiesExpiringBefore.UTC())
+		conditions = append(conditions, fmt.Sprintf("cookies_expire_at < $%d", len(args)))
+	}
+	if len(filter.Tags) > 0 {
+		args = append(args, accountRepository.dialect.array(filter.Tags))
+		tags := accountRepository.dialect.list(len(args), "text")
+		if filter.TagMatch == model.TagMatchAny {
+			conditions = append(conditions,
+				"EXISTS (SELECT 1 FROM account_tags WHERE account_tags.account_id = accounts.id AND tag IN ("+tags+"))")
+		} else {
+			conditions = append(conditions,
+				"NOT EXISTS (SELECT 1 FROM ("+tags+") AS wanted WHERE wanted.value NOT IN (SELECT tag FROM account_tags WHERE account_tags.account_id = accounts.id))")
+		}
+	}
+	for name, value := range filter.Attributes {
+		var condition string
+		condition, args = accountRepository.dialect.attributeCondition(name, value, args)
+		conditions = append(conditions, condition)
+	}
+
+	return conditions, args
+}
+
+// Export calls fn with every account matching filter, in creation order.
+// Rows are streamed from the database, so the accounts are never all in
+// memory at once.
+func (accountRepository *AccountRepository) Export(ctx context.Context, filter model.AccountFilter, fn func(model.Account) error) error {
+	conditions, args := accountRepository.filterConditions(filter)
+
+	query := "SELECT " + accountRepository.dialect.accountColumns() + " FROM accounts WHERE " + strings.Join(conditions, " AND ")
+	query += " ORDER BY created_at, id"
+
+	rows, err := accountRepository.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		accountRepository.logger.WithError(err).Error("Failed to export accounts")
+		return fmt.Errorf("error exporting accounts: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		account, dataKey, err := scanAccount(rows, accountRepository.dialect)
+		if err != nil {
+			accountRepository.logger.WithError(err).Error("Failed to export accounts")
+			return fmt.Errorf("error exporting accounts: %w", err)
+		}
+
+		err = accountRepository.envelope.Open(ctx, dataKey, account.Secrets()...)
+		if err != nil {
+			accountRepository.logger.WithError(err).Error("Failed to decrypt account")
+			return fmt.Errorf("error decrypting account with id %s: %w", account.ID, err)
+		}
+
+		if err := fn(account); err != nil {
+			return err
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		accountRepository.logger.WithError(err).Error("Failed to export accounts")
+		return fmt.Errorf("error exporting accounts: %w", err)
+	}
+
+	return nil
+}
+
+// Rewrap moves every data key still wrapped by an old master key to the
+// current one. Rows are updated one by one and only if their key did not
+// change meanwhile, so concurrent writes are never blocked or overwritten.
+func (accountRepository *AccountRepository) Rewrap(ctx context.Context) (int, error) {
+	currentVersion, err := accountRepository.envelope.Refresh(ctx)
+	if err != nil {
+		accountRepository.logger.WithError(err).Error("Failed to refresh master keys")
+		return 0, err
+	}
+
+	query := "SELECT id, data_key, key_version FROM accounts WHERE key_version <> $1"
+
+	rows, err := accountRepository.db.QueryContext(ctx, query, currentVersion)
+	if err != nil {
+		accountRepository.logger.WithError(err).Error("Failed to get accounts to rewrap")
+		return 0, fmt.Errorf("error getting accounts to rewrap: %w", err)
+	}
+	defer rows.Close()
+
+	dataKeys := make(map[string]encryption.WrappedKey)
+
+	for rows.Next() {
+		var id string
+		var dataKey encryption.WrappedKey
+		err := rows.Scan(&id, &dataKey.Ciphertext, &dataKey.Version)
+		if err != nil {
+			accountRepository.logger.WithError(err).Error("Failed to get accounts to rewrap")
+			return 0, fmt.Errorf("error getting accounts to rewrap: %w", err)
+		}
+		dataKeys[id] = dataKey
+	}
+
+	if err = rows.Err(); err != nil {
+		accountRepository.logger.WithError(err).Error("Failed to get accounts to rewrap")
+		return 0, fmt.Errorf("error getting accounts to rewrap: %w", err)
+	}
+
+	query = `UPDATE accounts SET data_key = $2, key_version = $3
+		WHERE id = $1 AND key_version = $4 AND data_key = $5`
+
+	rewrapped := 0
+	for id, dataKey := range dataKeys {
+		rewrappedKey, err := accountRepository.envelope.Rewrap(ctx, dataKey)
+		if err != nil {
+			accountRepository.logger.WithError(err).Error("Failed to rewrap data key")
+			return rewrapped, fmt.Errorf("error rewrapping data key of account with id %s: %w", id, err)
+		}
+
+		result, err := accountRepository.db.ExecContext(ctx, query,
+			id,
+			rewrappedKey.Ciphertext,
+			rewrappedKey.Version,
+			dataKey.Version,
+			dataKey.Ciphertext,
+		)
+		if err != nil {
+			accountRepository.logger.WithError(err).Error("Failed to rewrap data key")
+			return rewrapped, fmt.Errorf("error updating data key of account with id %s: %w", id, err)
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			accountRepository.logger.WithError(err).Error("Failed to rewrap data key")
+			return rewrapped, fmt.Errorf("error updating data key of account with id %s: %w", id, err)
+		}
+		rewrapped += int(affected)
+	}
+
+	return rewrapped, nil
+}
+
+// ExpireSessions sets the status of accounts whose cookies expired by now to
+// session expired, except for accounts with a status in keep, and returns
+// the status transitions. The cookies themselves are left as they are, so
+// they can still be inspected and refreshed. SQLite cannot return the old
+// status from the update, so the accounts are read and locked first in the
+// same transaction.
+func (accountRepository *AccountRepository) ExpireSessions(ctx context.Context, now time.Time, keep []string) ([]model.StatusTransitionCreate, error) {
+	query := `SELECT id, status FROM accounts
+		WHERE cookies_expire_at <= $2
+			AND deleted_at IS NULL
+			AND status IS DISTINCT FROM $1
+			AND COALESCE(status, '') NOT IN (` + accountRepository.dialect.list(3, "text") + `)` + accountRepository.dialect.lockRows
+
+	tx, err := accountRepository.db.BeginTx(ctx, nil)
+	if err != nil {
+		accountRepository.logger.WithError(err).Error("Failed to begin session expiry transaction")
+		return nil, fmt.Errorf("error beginning session expiry transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, query, model.AccountStatusSessionExpired, now.UTC(), accountRepository.dialect.array(keep))
+	if err != nil {
+		accountRepository.logger.WithError(err).Error("Failed to expire sessions")
+		return nil, fmt.Errorf("error expiring sessions: %w", err)
+	}
+
+	var ids []string
+	var statusTransitions []model.StatusTransitionCreate
+	for rows.Next() {
+		var id string
+		var from sql.NullString
+		if err := rows.Scan(&id, &from); err != nil {
+			rows.Close()
+			accountRepository.logger.WithError(err).Error("Failed to expire sessions")
+			return nil, fmt.Errorf("error expiring sessions: %w", err)
+		}
+		ids = append(ids, id)
+		statusTransitions = append(statusTransitions, model.StatusTransitionCreate{
+			AccountID: id,
+			From:      from.String,
+			To:        model.AccountStatusSessionExpired,
+		})
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		accountRepository.logger.WithError(err).Error("Failed to expire sessions")
+		return nil, fmt.Errorf("error expiring sessions: %w", err)
+	}
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	query = `UPDATE accounts SET status = $1, version = version + 1
+		WHERE id IN (` + accountRepository.dialect.list(2, "uuid") + `)`
+
+	if _, err := tx.ExecContext(ctx, query, model.AccountStatusSessionExpired, accountRepository.dialect.array(ids)); err != nil {
+		accountRepository.logger.WithError(err).Error("Failed to expire sessions")
+		return nil, fmt.Errorf("error expiring sessions: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		accountRepository.logger.WithError(err).Error("Failed to commit session expiry transaction")
+		return nil, fmt.Errorf("error committing session expiry transaction: %w", err)
+	}
+
+	return statusTransitions, nil
+}
+
+// Lease reserves the first free account matching the filter. SKIP LOCKED
+// lets concurrent Postgres callers pass over rows another transaction is
+// leasing instead of waiting for it and then leasing the same account
+// twice. SQLite runs one write at a time, so it needs no lock.
+func (accountRepository *AccountRepository) Lease(ctx context.Context, leaseCreate model.LeaseCreate) (model.Lease, model.Account, error) {
+	query := `UPDATE accounts SET lease_id = $1, leased_by = $2, lease_expires_at = $3
+		WHERE id = (
+			SELECT id FROM accounts
+			WHERE (lease_expires_at IS NULL OR lease_expires_at <= $4)
+				AND deleted_at IS NULL
+				AND ($5 = '' OR account_type = $5)
+				AND ($6 = '' OR status = $6)
+			ORDER BY lease_expires_at NULLS FIRST, created_at, id
+			LIMIT 1` + accountRepository.dialect.skipLocked + `
+		)
+		RETURNING ` + accountRepository.dialect.accountColumns()
+
+	now := time.Now().UTC()
+	lease := model.Lease{
+		ID:        uuid.New(),
+		Holder:    leaseCreate.Holder,
+		ExpiresAt: now.Add(model.LeaseDuration(leaseCreate.TTL)),
+	}
+
+	account, dataKey, err := scanAccount(accountRepository.db.QueryRowContext(ctx, query,
+		lease.ID,
+		lease.Holder,
+		lease.ExpiresAt,
+		now,
+		leaseCreate.AccountType,
+		leaseCreate.Status,
+	), accountRepository.dialect)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return model.Lease{}, model.Account{}, model.ErrNoAccountAvailable
+		}
+		accountRepository.logger.WithError(err).Error("Failed to lease account")
+		return model.Lease{}, model.Account{}, fmt.Errorf("error leasing account: %w", err)
+	}
+
+	err = accountRepository.envelope.Open(ctx, dataKey, account.Secrets()...)
+	if err != nil {
+		accountRepository.logger.WithError(err).Error("Failed to decrypt account")
+		return model.Lease{}, model.Account{}, fmt.Errorf("error decrypting account with id %s: %w", account.ID, err)
+	}
+
+	lease.AccountID = account.ID
+
+	return lease, account, nil
+}
+
+func (accountRepository *AccountRepository) RenewLease(ctx context.Context, accountID string, leaseRenew model.LeaseRenew) (model.Lease, error) {
+	query := `UPDATE accounts SET lease_expires_at = $3
+		WHERE id = $1 AND lease_id = $2 AND lease_expires_at > $4
+		RETURNING id, lease_id, leased_by, lease_expires_at`
+
+	now := time.Now().UTC()
+
+	var lease model.Lease
+	err := accountRepository.db.QueryRowContext(ctx, query,
+		accountID,
+		leaseRenew.LeaseID,
+		now.Add(model.LeaseDuration(leaseRenew.TTL)),
+		now,
+	).Scan(
+		&lease.AccountID,
+		&lease.ID,
+		&lease.Holder,
+		&lease.ExpiresAt)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return model.Lease{}, fmt.Errorf("%w: lease %s on account %s", model.ErrLeaseNotHeld, leaseRenew.LeaseID, accountID)
+		}
+		accountRepository.logger.WithError(err).Error("Failed to renew lease")
+		return model.Lease{}, fmt.Errorf("error renewing lease on account with id %s: %w", accountID, withKind(err))
+	}
+
+	return lease, nil
+}
+
+func (accountRepository *AccountRepository) ReleaseLease(ctx context.Context, accountID, leaseID string) error {
+	query := `UPDATE accounts SET lease_id = NULL, leased_by = NULL, lease_expires_at = NULL
+		WHERE id = $1 AND lease_id = $2`
+
+	result, err := accountRepository.db.ExecContext(ctx, query, accountID, leaseID)
+	if err != nil {
+		accountRepository.logger.WithError(err).Error("Failed to release lease")
+		return fmt.Errorf("error releasing lease on account with id %s: %w", accountID, withKind(err))
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		accountRepository.logger.WithError(err).Error("Failed to release lease")
+		return fmt.Errorf("error releasing lease on account with id %s: %w", accountID, err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("%w: lease %s on account %s", model.ErrLeaseNotHeld, leaseID, accountID)
+	}
+
+	return nil
 }
 
 func (accountRepository *AccountRepository) Nginx(ctx context.Context) (string, error) {
@@ -0,0 +1,85 @@
+package sqlstore
+
+import (
+	"account_storage/pkg/model"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type StatusHistoryRepository struct {
+	db     *sql.DB
+	logger *logrus.Logger
+}
+
+func (statusHistoryRepository *StatusHistoryRepository) Create(ctx context.Context, statusTransitionCreate model.StatusTransitionCreate) (string, error) {
+	query := `INSERT INTO account_status_history (id, account_id, from_status, to_status, reason, actor, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+
+	var id string
+	err := statusHistoryRepository.db.QueryRowContext(ctx, query,
+		uuid.New(),
+		statusTransitionCreate.AccountID,
+		statusTransitionCreate.From,
+		statusTransitionCreate.To,
+		statusTransitionCreate.Reason,
+		statusTransitionCreate.Actor,
+		time.Now().UTC()).Scan(&id)
+
+	if err != nil {
+		statusHistoryRepository.logger.WithError(err).Error("Failed to create status transition")
+		return "", fmt.Errorf("error creating status transition: %w", withKind(err))
+	}
+
+	return id, nil
+}
+
+// GetByAccountID returns the status transitions of an account, oldest first.
+func (statusHistoryRepository *StatusHistoryRepository) GetByAccountID(ctx context.Context, accountID string) ([]model.StatusTransition, error) {
+	query := `SELECT id, account_id, from_status, to_status, reason, actor, created_at
+		FROM account_status_history WHERE account_id = $1 ORDER BY created_at, id`
+
+	rows, err := statusHistoryRepository.db.QueryContext(ctx, query, accountID)
+	if err != nil {
+		statusHistoryRepository.logger.WithError(err).Error("Failed to get status history")
+		return nil, fmt.Errorf("error getting status history of account with id %s: %w", accountID, withKind(err))
+	}
+	defer rows.Close()
+
+	statusTransitions := make([]model.StatusTransition, 0)
+
+	for rows.Next() {
+		var statusTransition model.StatusTransition
+		var from, reason, actor sql.NullString
+		err := rows.Scan(
+			&statusTransition.ID,
+			&statusTransition.AccountID,
+			&from,
+			&statusTransition.To,
+			&reason,
+			&actor,
+			&statusTransition.CreatedAt,
+		)
+		if err != nil {
+			statusHistoryRepository.logger.WithError(err).Error("Failed to get status history")
+			return nil, fmt.Errorf("error getting status history of account with id %s: %w", accountID, err)
+		}
+
+		statusTransition.From = from.String
+		statusTransition.Reason = reason.String
+		statusTransition.Actor = actor.String
+
+		statusTransitions = append(statusTransitions, statusTransition)
+	}
+
+	if err = rows.Err(); err != nil {
+		statusHistoryRepository.logger.WithError(err).Error("Failed to get status history")
+		return nil, fmt.Errorf("error getting status history of account with id %s: %w", accountID, err)
+	}
+
+	return statusTransitions, nil
+}
@@ -1,6 +1,7 @@
 package sqlstore
 
 import (
+	"account_storage/internal/app/encryption"
 	"account_storage/internal/app/store"
 	"database/sql"
 
@@ -8,25 +9,108 @@ import (
 )
 
 type Store struct {
-	db                *sql.DB
-	logger            *logrus.Logger
-	accountRepository store.AccountRepository
+	db                          *sql.DB
+	dialect                     *dialect
+	envelope                    *encryption.Envelope
+	logger                      *logrus.Logger
+	accountRepository           store.AccountRepository
+	apiKeyRepository            store.APIKeyRepository
+	auditRepository             store.AuditRepository
+	statusHistoryRepository     store.StatusHistoryRepository
+	accountTypeRepository       store.AccountTypeRepository
+	credentialHistoryRepository store.CredentialHistoryRepository
 }
 
-func New(db *sql.DB, logger *logrus.Logger) *Store {
+func New(db *sql.DB, logger *logrus.Logger, envelope *encryption.Envelope) *Store {
 	return &Store{
-		db:     db,
-		logger: logger,
+		db:       db,
+		dialect:  postgresDialect,
+		envelope: envelope,
+		logger:   logger,
 	}
 }
 
+// NewSQLite returns a store on a SQLite database, see sqlitestore.Open.
+func NewSQLite(db *sql.DB, logger *logrus.Logger, envelope *encryption.Envelope) *Store {
+	return &Store{
+		db:       db,
+		dialect:  sqliteDialect,
+		envelope: envelope,
+		logger:   logger,
+	}
+}
+
+// Close closes the database.
+func (store *Store) Close() error {
+	return store.db.Close()
+}
+
 func (store Store) Account() store.AccountRepository {
 	if store.accountRepository != nil {
 		return store.accountRepository
 	}
 
 	return &AccountRepository{
+		db:       store.db,
+		dialect:  store.dialect,
+		envelope: store.envelope,
+		logger:   store.logger,
+	}
+}
+
+func (store Store) APIKey() store.APIKeyRepository {
+	if store.apiKeyRepository != nil {
+		return store.apiKeyRepository
+	}
+
+	return &APIKeyRepository{
+		db:     store.db,
+		logger: store.logger,
+	}
+}
+
+func (store Store) Audit() store.AuditRepository {
+	if store.auditRepository != nil {
+		return store.auditRepository
+	}
+
+	return &AuditRepository{
 		db:     store.db,
 		logger: store.logger,
 	}
 }
+
+func (store Store) StatusHistory() store.StatusHistoryRepository {
+	if store.statusHistoryRepository != nil {
+		return store.statusHistoryRepository
+	}
+
+	return &StatusHistoryRepository{
+		db:     store.db,
+		logger: store.logger,
+	}
+}
+
+func (store Store) AccountType() store.AccountTypeRepository {
+	if store.accountTypeRepository != nil {
+		return store.accountTypeRepository
+	}
+
+	return &AccountTypeRepository{
+		db:      store.db,
+		dialect: store.dialect,
+		logger:  store.logger,
+	}
+}
+
+func (store Store) CredentialHistory() store.CredentialHistoryRepository {
+	if store.credentialHistoryRepository != nil {
+		return store.credentialHistoryRepository
+	}
+
+	return &CredentialHistoryRepository{
+		db:       store.db,
+		envelope: store.envelope,
+		logger:   store.logger,
+	}
+}
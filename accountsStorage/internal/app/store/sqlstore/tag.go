@@ -0,0 +1,124 @@
+package sqlstore
+
+import (
+	"account_storage/pkg/model"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Tag adds the tags to the accounts. Nothing changes when one of the
+// accounts does not exist.
+func (accountRepository *AccountRepository) Tag(ctx context.Context, accountTags model.AccountTags) error {
+	query := `INSERT INTO account_tags (account_id, tag, created_at)
+		SELECT ids.value, tags.value, $3 FROM (` + accountRepository.dialect.list(1, "uuid") + `) AS ids
+			CROSS JOIN (` + accountRepository.dialect.list(2, "text") + `) AS tags WHERE true
+		ON CONFLICT DO NOTHING`
+
+	return accountRepository.changeTags(ctx, "tag", query, accountTags, time.Now().UTC())
+}
+
+// Untag removes the tags from the accounts. Nothing changes when one of the
+// accounts does not exist.
+func (accountRepository *AccountRepository) Untag(ctx context.Context, accountTags model.AccountTags) error {
+	query := `DELETE FROM account_tags WHERE account_id IN (` + accountRepository.dialect.list(1, "uuid") + `)
+		AND tag IN (` + accountRepository.dialect.list(2, "text") + `)`
+
+	return accountRepository.changeTags(ctx, "untag", query, accountTags)
+}
+
+// changeTags runs query with the account IDs and the tags as its first
+// arguments, after checking in the same transaction that the accounts exist.
+func (accountRepository *AccountRepository) changeTags(
+	ctx context.Context, action, query string, accountTags model.AccountTags, args ...interface{}) error {
+	ids := make([]string, len(accountTags.AccountIDs))
+	for i, id := range accountTags.AccountIDs {
+		ids[i] = id.String()
+	}
+
+	tx, err := accountRepository.db.BeginTx(ctx, nil)
+	if err != nil {
+		accountRepository.logger.WithError(err).Error("Failed to begin tag transaction")
+		return fmt.Errorf("error beginning %s transaction: %w", action, err)
+	}
+	defer tx.Rollback()
+
+	existing := `SELECT id FROM accounts WHERE id IN (` + accountRepository.dialect.list(1, "uuid") + `) AND deleted_at IS NULL` + accountRepository.dialect.shareRows
+
+	rows, err := tx.QueryContext(ctx, existing, accountRepository.dialect.array(ids))
+	if err != nil {
+		accountRepository.logger.WithError(err).Error("Failed to get accounts to tag")
+		return fmt.Errorf("error getting accounts to %s: %w", action, withKind(err))
+	}
+	found := make(map[string]bool, len(ids))
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			accountRepository.logger.WithError(err).Error("Failed to get accounts to tag")
+			return fmt.Errorf("error getting accounts to %s: %w", action, err)
+		}
+		found[id] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		accountRepository.logger.WithError(err).Error("Failed to get accounts to tag")
+		return fmt.Errorf("error getting accounts to %s: %w", action, err)
+	}
+
+	var missing []string
+	for _, id := range ids {
+		if !found[id] {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		return model.Errorf(model.ErrNotFound, "no accounts with ids %s", strings.Join(missing, ", "))
+	}
+
+	args = append([]interface{}{accountRepository.dialect.array(ids), accountRepository.dialect.array(accountTags.Tags)}, args...)
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		accountRepository.logger.WithError(err).Error("Failed to change account tags")
+		return fmt.Errorf("error changing tags of accounts: %w", withKind(err))
+	}
+
+	if err := tx.Commit(); err != nil {
+		accountRepository.logger.WithError(err).Error("Failed to commit tag transaction")
+		return fmt.Errorf("error committing %s transaction: %w", action, err)
+	}
+
+	return nil
+}
+
+// Tags returns every tag with the number of accounts that have it.
+func (accountRepository *AccountRepository) Tags(ctx context.Context) ([]model.TagCount, error) {
+	query := `SELECT account_tags.tag, COUNT(*) FROM account_tags
+		JOIN accounts ON accounts.id = account_tags.account_id AND accounts.deleted_at IS NULL
+		GROUP BY account_tags.tag ORDER BY account_tags.tag`
+
+	rows, err := accountRepository.db.QueryContext(ctx, query)
+	if err != nil {
+		accountRepository.logger.WithError(err).Error("Failed to get tags")
+		return nil, fmt.Errorf("error getting tags: %w", withKind(err))
+	}
+	defer rows.Close()
+
+	tagCounts := []model.TagCount{}
+
+	for rows.Next() {
+		var tagCount model.TagCount
+		if err := rows.Scan(&tagCount.Tag, &tagCount.Count); err != nil {
+			accountRepository.logger.WithError(err).Error("Failed to get tags")
+			return nil, fmt.Errorf("error getting tags: %w", err)
+		}
+		tagCounts = append(tagCounts, tagCount)
+	}
+
+	if err = rows.Err(); err != nil {
+		accountRepository.logger.WithError(err).Error("Failed to get tags")
+		return nil, fmt.Errorf("error getting tags: %w", err)
+	}
+
+	return tagCounts, nil
+}
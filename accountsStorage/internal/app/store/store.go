@@ -2,4 +2,9 @@ package store
 
 type Store interface {
 	Account() AccountRepository
+	APIKey() APIKeyRepository
+	Audit() AuditRepository
+	StatusHistory() StatusHistoryRepository
+	AccountType() AccountTypeRepository
+	CredentialHistory() CredentialHistoryRepository
 }
@@ -1 +1 @@
-DROP TABLE accounts;
\ No newline at end of file
+DROP TABLE IF EXISTS accounts;
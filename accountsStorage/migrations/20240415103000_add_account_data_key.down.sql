@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS accounts_key_version_idx;
+
+ALTER TABLE accounts
+    DROP COLUMN IF EXISTS key_version,
+    DROP COLUMN IF EXISTS data_key;
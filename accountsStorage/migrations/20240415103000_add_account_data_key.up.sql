@@ -0,0 +1,5 @@
+ALTER TABLE accounts
+    ADD COLUMN IF NOT EXISTS data_key BYTEA,
+    ADD COLUMN IF NOT EXISTS key_version INTEGER;
+
+CREATE INDEX IF NOT EXISTS accounts_key_version_idx ON accounts (key_version);
@@ -0,0 +1,3 @@
+ALTER TABLE accounts
+    ALTER COLUMN key_version DROP NOT NULL,
+    ALTER COLUMN data_key DROP NOT NULL;
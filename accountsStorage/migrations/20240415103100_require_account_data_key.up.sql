@@ -0,0 +1,5 @@
+-- Accounts written before envelope encryption get their data key when the
+-- server starts, so start it once after 20240415103000 before applying this.
+ALTER TABLE accounts
+    ALTER COLUMN data_key SET NOT NULL,
+    ALTER COLUMN key_version SET NOT NULL;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+    id UUID PRIMARY KEY,
+    actor TEXT,
+    action TEXT NOT NULL,
+    account_id UUID,
+    fields TEXT,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_account_id_idx ON audit_log (account_id);
+CREATE INDEX IF NOT EXISTS audit_log_created_at_idx ON audit_log (created_at);
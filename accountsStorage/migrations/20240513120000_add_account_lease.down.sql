@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS accounts_lease_expires_at_idx;
+
+ALTER TABLE accounts
+    DROP COLUMN IF EXISTS lease_expires_at,
+    DROP COLUMN IF EXISTS leased_by,
+    DROP COLUMN IF EXISTS lease_id;
@@ -0,0 +1,6 @@
+ALTER TABLE accounts
+    ADD COLUMN IF NOT EXISTS lease_id UUID,
+    ADD COLUMN IF NOT EXISTS leased_by TEXT,
+    ADD COLUMN IF NOT EXISTS lease_expires_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS accounts_lease_expires_at_idx ON accounts (lease_expires_at);
@@ -0,0 +1,2 @@
+ALTER TABLE accounts
+    ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
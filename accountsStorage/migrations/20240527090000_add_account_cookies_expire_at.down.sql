@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS accounts_cookies_expire_at_idx;
+
+ALTER TABLE accounts
+    DROP COLUMN IF EXISTS cookies_expire_at;
@@ -0,0 +1,4 @@
+ALTER TABLE accounts
+    ADD COLUMN IF NOT EXISTS cookies_expire_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS accounts_cookies_expire_at_idx ON accounts (cookies_expire_at);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS account_status_history (
+    id UUID PRIMARY KEY,
+    account_id UUID NOT NULL,
+    from_status TEXT,
+    to_status TEXT NOT NULL,
+    reason TEXT,
+    actor TEXT,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS account_status_history_account_id_idx ON account_status_history (account_id, created_at);
@@ -0,0 +1,4 @@
+ALTER TABLE accounts
+    ADD COLUMN IF NOT EXISTS attributes JSONB;
+
+CREATE INDEX IF NOT EXISTS accounts_attributes_idx ON accounts USING GIN (attributes jsonb_path_ops);
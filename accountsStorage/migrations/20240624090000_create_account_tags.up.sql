@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS account_tags (
+    account_id UUID NOT NULL REFERENCES accounts (id) ON DELETE CASCADE,
+    tag TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (account_id, tag)
+);
+
+CREATE INDEX IF NOT EXISTS account_tags_tag_idx ON account_tags (tag);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS accounts_deleted_at_idx;
+
+ALTER TABLE accounts
+    DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,4 @@
+ALTER TABLE accounts
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS accounts_deleted_at_idx ON accounts (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE account_status_history
+    DROP CONSTRAINT IF EXISTS account_status_history_account_id_fkey;
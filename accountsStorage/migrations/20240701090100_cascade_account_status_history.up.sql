@@ -0,0 +1,7 @@
+-- Accounts deleted before the trash left their history behind.
+DELETE FROM account_status_history
+WHERE account_id NOT IN (SELECT id FROM accounts);
+
+ALTER TABLE account_status_history
+    ADD CONSTRAINT account_status_history_account_id_fkey
+    FOREIGN KEY (account_id) REFERENCES accounts (id) ON DELETE CASCADE;
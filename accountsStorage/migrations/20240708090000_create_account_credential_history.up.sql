@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS account_credential_history (
+    account_id UUID NOT NULL REFERENCES accounts (id) ON DELETE CASCADE,
+    version BIGINT NOT NULL,
+    password TEXT,
+    email_password TEXT,
+    recovery_email_password TEXT,
+    cookie TEXT,
+    attributes JSONB,
+    data_key BYTEA NOT NULL,
+    key_version INTEGER NOT NULL,
+    actor TEXT,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (account_id, version)
+);
@@ -0,0 +1,93 @@
+// Package migrations embeds the SQL migrations of the sql stores.
+//
+// The migrations in this directory are written for Postgres. SQLite runs
+// most of them as they are, the sqlite directory holds a SQLite version of
+// the ones it does not, under the same file name.
+package migrations
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+const (
+	DialectPostgres = "postgres"
+	DialectSQLite   = "sqlite"
+)
+
+//go:embed *.sql sqlite/*.sql
+var files embed.FS
+
+// Migration is one schema change, Version is the timestamp its file names
+// start with.
+type Migration struct {
+	Version string
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Load returns the migrations for dialect, oldest first.
+func Load(dialect string) ([]Migration, error) {
+	if dialect != DialectPostgres && dialect != DialectSQLite {
+		return nil, fmt.Errorf("unknown migration dialect %s", dialect)
+	}
+
+	names, err := fs.Glob(files, "*.up.sql")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	migrations := make([]Migration, 0, len(names))
+	for _, name := range names {
+		base := strings.TrimSuffix(name, ".up.sql")
+		version, migrationName, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s has no version", name)
+		}
+
+		up, err := read(dialect, base+".up.sql")
+		if err != nil {
+			return nil, err
+		}
+		down, err := read(dialect, base+".down.sql")
+		if err != nil {
+			return nil, err
+		}
+
+		migrations = append(migrations, Migration{
+			Version: version,
+			Name:    migrationName,
+			Up:      up,
+			Down:    down,
+		})
+	}
+
+	return migrations, nil
+}
+
+// read returns the dialect's own version of a migration file if there is
+// one and the shared one otherwise.
+func read(dialect, name string) (string, error) {
+	if dialect != DialectPostgres {
+		data, err := files.ReadFile(path.Join(dialect, name))
+		if err == nil {
+			return string(data), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", fmt.Errorf("error reading migration %s: %w", name, err)
+		}
+	}
+
+	data, err := files.ReadFile(name)
+	if err != nil {
+		return "", fmt.Errorf("error reading migration %s: %w", name, err)
+	}
+	return string(data), nil
+}
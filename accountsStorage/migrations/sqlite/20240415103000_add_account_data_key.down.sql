@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS accounts_key_version_idx;
+
+ALTER TABLE accounts DROP COLUMN key_version;
+ALTER TABLE accounts DROP COLUMN data_key;
@@ -0,0 +1,4 @@
+ALTER TABLE accounts ADD COLUMN data_key BLOB;
+ALTER TABLE accounts ADD COLUMN key_version INTEGER;
+
+CREATE INDEX IF NOT EXISTS accounts_key_version_idx ON accounts (key_version);
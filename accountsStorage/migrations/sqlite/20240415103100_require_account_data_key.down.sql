@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS accounts_require_data_key_update;
+DROP TRIGGER IF EXISTS accounts_require_data_key_insert;
@@ -0,0 +1,15 @@
+-- SQLite can not add NOT NULL to a column without rebuilding the table, so
+-- triggers require the data key instead.
+CREATE TRIGGER IF NOT EXISTS accounts_require_data_key_insert
+BEFORE INSERT ON accounts
+WHEN NEW.data_key IS NULL OR NEW.key_version IS NULL
+BEGIN
+    SELECT RAISE(ABORT, 'accounts.data_key and accounts.key_version may not be NULL');
+END;
+
+CREATE TRIGGER IF NOT EXISTS accounts_require_data_key_update
+BEFORE UPDATE OF data_key, key_version ON accounts
+WHEN NEW.data_key IS NULL OR NEW.key_version IS NULL
+BEGIN
+    SELECT RAISE(ABORT, 'accounts.data_key and accounts.key_version may not be NULL');
+END;
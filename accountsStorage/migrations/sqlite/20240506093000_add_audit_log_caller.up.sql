@@ -0,0 +1,3 @@
+ALTER TABLE audit_log ADD COLUMN caller TEXT;
+
+CREATE INDEX IF NOT EXISTS audit_log_actor_idx ON audit_log (actor);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS accounts_lease_expires_at_idx;
+
+ALTER TABLE accounts DROP COLUMN lease_expires_at;
+ALTER TABLE accounts DROP COLUMN leased_by;
+ALTER TABLE accounts DROP COLUMN lease_id;
@@ -0,0 +1,5 @@
+ALTER TABLE accounts ADD COLUMN lease_id UUID;
+ALTER TABLE accounts ADD COLUMN leased_by TEXT;
+ALTER TABLE accounts ADD COLUMN lease_expires_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS accounts_lease_expires_at_idx ON accounts (lease_expires_at);
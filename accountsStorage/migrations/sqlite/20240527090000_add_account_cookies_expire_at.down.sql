@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS accounts_cookies_expire_at_idx;
+
+ALTER TABLE accounts DROP COLUMN cookies_expire_at;
@@ -0,0 +1,3 @@
+ALTER TABLE accounts ADD COLUMN cookies_expire_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS accounts_cookies_expire_at_idx ON accounts (cookies_expire_at);
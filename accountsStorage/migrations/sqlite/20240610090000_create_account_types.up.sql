@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS account_types (
+    name TEXT PRIMARY KEY,
+    description TEXT,
+    required_fields TEXT NOT NULL DEFAULT '[]',
+    allowed_statuses TEXT NOT NULL DEFAULT '[]',
+    attributes TEXT NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
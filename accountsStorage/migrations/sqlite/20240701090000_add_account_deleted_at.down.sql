@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS accounts_deleted_at_idx;
+
+ALTER TABLE accounts DROP COLUMN deleted_at;
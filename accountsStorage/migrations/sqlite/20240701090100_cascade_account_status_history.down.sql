@@ -0,0 +1,18 @@
+CREATE TABLE account_status_history_plain (
+    id UUID PRIMARY KEY,
+    account_id UUID NOT NULL,
+    from_status TEXT,
+    to_status TEXT NOT NULL,
+    reason TEXT,
+    actor TEXT,
+    created_at TIMESTAMP NOT NULL
+);
+
+INSERT INTO account_status_history_plain
+SELECT id, account_id, from_status, to_status, reason, actor, created_at
+FROM account_status_history;
+
+DROP TABLE account_status_history;
+ALTER TABLE account_status_history_plain RENAME TO account_status_history;
+
+CREATE INDEX IF NOT EXISTS account_status_history_account_id_idx ON account_status_history (account_id, created_at);
@@ -0,0 +1,21 @@
+-- SQLite can not add a foreign key to a table, so the table is rebuilt with
+-- it. Accounts deleted before the trash left their history behind.
+CREATE TABLE account_status_history_cascade (
+    id UUID PRIMARY KEY,
+    account_id UUID NOT NULL REFERENCES accounts (id) ON DELETE CASCADE,
+    from_status TEXT,
+    to_status TEXT NOT NULL,
+    reason TEXT,
+    actor TEXT,
+    created_at TIMESTAMP NOT NULL
+);
+
+INSERT INTO account_status_history_cascade
+SELECT id, account_id, from_status, to_status, reason, actor, created_at
+FROM account_status_history
+WHERE account_id IN (SELECT id FROM accounts);
+
+DROP TABLE account_status_history;
+ALTER TABLE account_status_history_cascade RENAME TO account_status_history;
+
+CREATE INDEX IF NOT EXISTS account_status_history_account_id_idx ON account_status_history (account_id, created_at);
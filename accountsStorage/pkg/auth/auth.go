@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"account_storage/pkg/httperror"
+)
+
+const (
+	APIKeyHeader         = "X-API-Key"
+	CallerIdentityHeader = "X-Caller-Identity"
+)
+
+// Caller sources, used as the prefix of an identity in policy bindings.
+const (
+	SourceAPIKey      = "key"
+	SourceCertificate = "cert"
+	SourceBootstrap   = "bootstrap"
+)
+
+// Caller is the authenticated identity of a request. OnBehalfOf is the
+// unverified X-Caller-Identity header, set by services acting for a person.
+type Caller struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Source     string `json:"source"`
+	OnBehalfOf string `json:"on_behalf_of,omitempty"`
+}
+
+// Identity is the name the caller is bound to a role with, e.g. "key:bot".
+func (caller Caller) Identity() string {
+	return caller.Source + ":" + caller.Name
+}
+
+// Actor is who the request is made for: the caller identity header when
+// present, the authenticated identity otherwise.
+func (caller Caller) Actor() string {
+	if caller.OnBehalfOf != "" {
+		return caller.OnBehalfOf
+	}
+	return caller.Identity()
+}
+
+type Authenticator interface {
+	Authenticate(ctx context.Context, key string) (Caller, error)
+}
+
+type callerContextKey struct{}
+
+func WithCaller(ctx context.Context, caller Caller) context.Context {
+	return context.WithValue(ctx, callerContextKey{}, caller)
+}
+
+func CallerFromContext(ctx context.Context) (Caller, bool) {
+	caller, ok := ctx.Value(callerContextKey{}).(Caller)
+	return caller, ok
+}
+
+// Middleware identifies the caller by a verified client certificate or by
+// an api key given as a bearer token or in the X-API-Key header, and puts
+// the caller into the request context, which go-kit hands to the endpoints.
+func Middleware(authenticator Authenticator, logger *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if tlsState := c.Request.TLS; tlsState != nil && len(tlsState.VerifiedChains) > 0 {
+			certificate := tlsState.VerifiedChains[0][0]
+			caller := Caller{
+				ID:     certificate.SerialNumber.String(),
+				Name:   certificate.Subject.CommonName,
+				Source: SourceCertificate,
+			}
+			caller.OnBehalfOf = c.GetHeader(CallerIdentityHeader)
+
+			c.Request = c.Request.WithContext(WithCaller(c.Request.Context(), caller))
+			c.Next()
+			return
+		}
+
+		key := apiKeyFromRequest(c.Request)
+		if key == "" {
+			httperror.EncodeError(c.Request.Context(), ErrMissingAPIKey, c.Writer)
+			c.Abort()
+			return
+		}
+
+		caller, err := authenticator.Authenticate(c.Request.Context(), key)
+		if err != nil {
+			logger.WithFields(logrus.Fields{
+				"package":  "auth",
+				"function": "Middleware",
+				"error":    err,
+				"path":     c.Request.URL.Path,
+			}).Warn("api key authentication failed")
+
+			httperror.EncodeError(c.Request.Context(), err, c.Writer)
+			c.Abort()
+			return
+		}
+		caller.OnBehalfOf = c.GetHeader(CallerIdentityHeader)
+
+		c.Request = c.Request.WithContext(WithCaller(c.Request.Context(), caller))
+		c.Next()
+	}
+}
+
+func apiKeyFromRequest(r *http.Request) string {
+	authorization := r.Header.Get("Authorization")
+	if token, ok := strings.CutPrefix(authorization, "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+
+	return r.Header.Get(APIKeyHeader)
+}
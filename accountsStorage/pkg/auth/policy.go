@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/endpoint"
+
+	"account_storage/pkg/model"
+)
+
+const allEndpoints = "*"
+
+var (
+	ErrUnauthenticated = model.NewError(model.ErrUnauthorized, "caller is not authenticated")
+	ErrForbidden       = model.NewError(model.ErrForbidden, "caller is not allowed to perform this operation")
+	ErrMissingAPIKey   = model.NewError(model.ErrUnauthorized, "missing api key")
+)
+
+// Masker is implemented by responses that carry secrets and can return a
+// copy with those secrets masked.
+type Masker interface {
+	Masked() interface{}
+}
+
+type Role struct {
+	Endpoints     []string `toml:"endpoints"`
+	RevealSecrets bool     `toml:"reveal_secrets"`
+}
+
+func (role Role) allows(endpointName string) bool {
+	for _, name := range role.Endpoints {
+		if name == allEndpoints || name == endpointName {
+			return true
+		}
+	}
+	return false
+}
+
+// Policy maps caller identities to roles and roles to the endpoints they may
+// call. Identities without a binding get DefaultRole, if any.
+type Policy struct {
+	DefaultRole string            `toml:"default_role"`
+	Roles       map[string]Role   `toml:"roles"`
+	Bindings    map[string]string `toml:"bindings"`
+}
+
+func (policy Policy) RoleOf(caller Caller) (Role, bool) {
+	roleName, ok := policy.Bindings[caller.Identity()]
+	if !ok {
+		roleName = policy.DefaultRole
+	}
+
+	role, ok := policy.Roles[roleName]
+	return role, ok
+}
+
+// Authorize rejects callers whose role does not grant endpointName and masks
+// secrets in the response unless the role may reveal them.
+func (policy Policy) Authorize(endpointName string) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			caller, ok := CallerFromContext(ctx)
+			if !ok {
+				return nil, ErrUnauthenticated
+			}
+
+			role, ok := policy.RoleOf(caller)
+			if !ok || !role.allows(endpointName) {
+				return nil, ErrForbidden
+			}
+
+			response, err := next(ctx, request)
+			if err != nil {
+				return response, err
+			}
+
+			if masker, ok := response.(Masker); ok && !role.RevealSecrets {
+				return masker.Masked(), nil
+			}
+
+			return response, nil
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package httperror
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"account_storage/pkg/model"
+)
+
+const internalMessage = "internal server error"
+
+type Detail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Response is the body of every error response.
+type Response struct {
+	Error Detail `json:"error"`
+}
+
+func StatusCode(err error) int {
+	switch model.ErrorCode(err) {
+	case model.CodeNotFound:
+		return http.StatusNotFound
+	case model.CodeInvalidArgument:
+		return http.StatusBadRequest
+	case model.CodeConflict:
+		return http.StatusConflict
+	case model.CodeUnauthorized:
+		return http.StatusUnauthorized
+	case model.CodeForbidden:
+		return http.StatusForbidden
+	case model.CodePreconditionFailed:
+		return http.StatusPreconditionFailed
+	case model.CodePreconditionRequired:
+		return http.StatusPreconditionRequired
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// NewResponse builds the body for err. Messages of internal errors are not
+// passed to clients, they are only logged.
+func NewResponse(err error) Response {
+	code := model.ErrorCode(err)
+	message := err.Error()
+	if code == model.CodeInternal {
+		message = internalMessage
+	}
+	return Response{Error: Detail{Code: code, Message: message}}
+}
+
+// EncodeError is a go-kit ErrorEncoder writing err as a Response.
+func EncodeError(_ context.Context, err error, w http.ResponseWriter) {
+	if err == nil {
+		panic("encodeError with nil error")
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(StatusCode(err))
+	json.NewEncoder(w).Encode(NewResponse(err))
+}
@@ -0,0 +1,156 @@
+package logadapter
+
+import (
+	"reflect"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Redacter is implemented by values that carry secrets. Fields holding a
+// Redacter, directly or inside slices, maps, pointers and structs, are
+// replaced by its redacted view before an entry is written.
+type Redacter interface {
+	Redacted() interface{}
+}
+
+var redacterType = reflect.TypeOf((*Redacter)(nil)).Elem()
+
+// maxRedactDepth bounds how deep fields are searched, which also stops at
+// cyclic values.
+const maxRedactDepth = 8
+
+type redactHook struct{}
+
+func (h redactHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h redactHook) Fire(entry *logrus.Entry) error {
+	for key, value := range entry.Data {
+		redacted, ok := redact(reflect.ValueOf(value), 0)
+		if !ok {
+			continue
+		}
+		entry.Data[key] = redacted.Interface()
+	}
+
+	return nil
+}
+
+func NewRedactHook() logrus.Hook {
+	return redactHook{}
+}
+
+// redact returns value with every Redacter in it replaced by its redacted
+// view, and whether there was one. What contains a Redacter is copied, the
+// logged value itself never changes.
+func redact(value reflect.Value, depth int) (reflect.Value, bool) {
+	if !value.IsValid() || depth > maxRedactDepth {
+		return value, false
+	}
+
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map:
+		if value.IsNil() {
+			return value, false
+		}
+	}
+
+	if value.Type().Implements(redacterType) && value.CanInterface() {
+		return reflect.ValueOf(value.Interface().(Redacter).Redacted()), true
+	}
+
+	switch value.Kind() {
+	case reflect.Interface:
+		return redact(value.Elem(), depth+1)
+	case reflect.Pointer:
+		elem, ok := redact(value.Elem(), depth+1)
+		if !ok {
+			return value, false
+		}
+		copied := reflect.New(value.Type().Elem())
+		setOrZero(copied.Elem(), elem)
+		return copied, true
+	case reflect.Slice, reflect.Array:
+		var copied reflect.Value
+		for i := 0; i < value.Len(); i++ {
+			elem, ok := redact(value.Index(i), depth+1)
+			if !ok {
+				continue
+			}
+			if !copied.IsValid() {
+				copied = copyOf(value)
+			}
+			setOrZero(copied.Index(i), elem)
+		}
+		return copied, copied.IsValid()
+	case reflect.Map:
+		var copied reflect.Value
+		iter := value.MapRange()
+		for iter.Next() {
+			elem, ok := redact(iter.Value(), depth+1)
+			if !ok {
+				continue
+			}
+			if !copied.IsValid() {
+				copied = reflect.MakeMapWithSize(value.Type(), value.Len())
+				copiedIter := value.MapRange()
+				for copiedIter.Next() {
+					copied.SetMapIndex(copiedIter.Key(), copiedIter.Value())
+				}
+			}
+			field := reflect.New(value.Type().Elem()).Elem()
+			setOrZero(field, elem)
+			copied.SetMapIndex(iter.Key(), field)
+		}
+		return copied, copied.IsValid()
+	case reflect.Struct:
+		var copied reflect.Value
+		for i := 0; i < value.NumField(); i++ {
+			if !value.Type().Field(i).IsExported() {
+				continue
+			}
+			field, ok := redact(value.Field(i), depth+1)
+			if !ok {
+				continue
+			}
+			if !copied.IsValid() {
+				copied = copyOf(value)
+			}
+			setOrZero(copied.Field(i), field)
+		}
+		return copied, copied.IsValid()
+	}
+
+	return value, false
+}
+
+// copyOf returns a settable copy of a slice, array or struct.
+func copyOf(value reflect.Value) reflect.Value {
+	if value.Kind() == reflect.Slice {
+		copied := reflect.MakeSlice(value.Type(), value.Len(), value.Len())
+		reflect.Copy(copied, value)
+		return copied
+	}
+	copied := reflect.New(value.Type()).Elem()
+	copied.Set(value)
+	return copied
+}
+
+// setOrZero sets field to value, or to a pointer to it for the redacted
+// view of a pointer. A view that fits neither leaves the field zero, so the
+// secret is dropped, not logged.
+func setOrZero(field, value reflect.Value) {
+	switch {
+	case !value.IsValid():
+		field.Set(reflect.Zero(field.Type()))
+	case value.Type().AssignableTo(field.Type()):
+		field.Set(value)
+	case field.Kind() == reflect.Pointer && value.Type().AssignableTo(field.Type().Elem()):
+		pointer := reflect.New(field.Type().Elem())
+		pointer.Elem().Set(value)
+		field.Set(pointer)
+	default:
+		field.Set(reflect.Zero(field.Type()))
+	}
+}
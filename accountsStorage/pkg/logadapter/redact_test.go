@@ -0,0 +1,88 @@
+package logadapter_test
+
+import (
+	"account_storage/pkg/logadapter"
+	"account_storage/pkg/model"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const secret = "s3cr3t-value"
+
+func secretAttributes() model.Attributes {
+	return model.Attributes{"pin": {Value: secret, Secret: true}}
+}
+
+func TestRedactHook(t *testing.T) {
+	account := model.Account{
+		ID:                    uuid.New(),
+		Name:                  "account",
+		Password:              secret,
+		EmailPassword:         secret,
+		RecoveryEmailPassword: secret,
+		Cookie:                secret,
+		Attributes:            secretAttributes(),
+	}
+	accountCreate := model.AccountCreate{Name: "account", Password: secret, Cookie: secret, Attributes: secretAttributes()}
+	accountUpdate := model.AccountUpdate{Name: "account", EmailPassword: secret, Attributes: secretAttributes()}
+	credentialVersion := model.CredentialVersion{Version: 1, Password: secret, Attributes: secretAttributes()}
+
+	tests := []struct {
+		name  string
+		value interface{}
+	}{
+		{"account", account},
+		{"account pointer", &account},
+		{"account create", accountCreate},
+		{"account update", accountUpdate},
+		{"credential version", credentialVersion},
+		{"attributes", secretAttributes()},
+		{"account slice", []model.Account{account, account}},
+		{"account create slice", []model.AccountCreate{accountCreate}},
+		{"credential version slice", []model.CredentialVersion{credentialVersion}},
+		{"account pointer slice", []*model.Account{&account, nil}},
+		{"batch update", model.AccountBatchUpdate{ID: account.ID, Version: 1, Account: accountUpdate}},
+		{"batch update slice", []model.AccountBatchUpdate{{ID: account.ID, Account: accountUpdate}}},
+		{"account map", map[string]model.Account{"a": account}},
+		{"interface slice", []interface{}{"plain", accountCreate}},
+		{"nested struct pointer", &struct {
+			Items []struct{ Account *model.Account }
+		}{Items: []struct{ Account *model.Account }{{Account: &account}}}},
+	}
+
+	// The text formatter prints pointers as addresses, only the JSON one
+	// shows that a redacted view was logged in place of the value.
+	formatters := map[string]logrus.Formatter{
+		"text": &logrus.TextFormatter{DisableColors: true},
+		"json": &logrus.JSONFormatter{},
+	}
+
+	for _, test := range tests {
+		for formatterName, formatter := range formatters {
+			t.Run(test.name+" "+formatterName, func(t *testing.T) {
+				var buffer bytes.Buffer
+				logger := logrus.New()
+				logger.SetOutput(&buffer)
+				logger.SetFormatter(formatter)
+				logger.AddHook(logadapter.NewRedactHook())
+
+				logger.WithField("value", test.value).Info("logged")
+
+				if strings.Contains(buffer.String(), secret) {
+					t.Errorf("secret logged: %s", buffer.String())
+				}
+				if formatterName == "json" && !strings.Contains(buffer.String(), "[REDACTED]") {
+					t.Errorf("no redacted field logged: %s", buffer.String())
+				}
+			})
+		}
+	}
+
+	if account.Password != secret || account.Attributes["pin"].Value != secret {
+		t.Errorf("redacting changed the logged account")
+	}
+}
@@ -7,42 +7,211 @@ import (
 )
 
 type Account struct {
-	ID                    uuid.UUID `json:"id,omitempty"`
-	Name                  string    `json:"name,omitempty"`
-	AccountType           string    `json:"account_type,omitempty"`
-	Login                 string    `json:"login,omitempty"`
-	Password              string    `json:"password,omitempty"`
-	Email                 string    `json:"email,omitempty"`
-	EmailPassword         string    `json:"emailPassword,omitempty"`
-	RecoveryEmail         string    `json:"recovery_email,omitempty"`
-	RecoveryEmailPassword string    `json:"recovery_email_password,omitempty"`
-	Cookie                string    `json:"cookie,omitempty"`
-	Status                string    `json:"status,omitempty"`
-	CreatedAt             time.Time `json:"created_at,omitempty"`
+	ID                    uuid.UUID  `json:"id,omitempty"`
+	Name                  string     `json:"name,omitempty"`
+	AccountType           string     `json:"account_type,omitempty"`
+	Login                 string     `json:"login,omitempty"`
+	Password              string     `json:"password,omitempty"`
+	Email                 string     `json:"email,omitempty"`
+	EmailPassword         string     `json:"emailPassword,omitempty"`
+	RecoveryEmail         string     `json:"recovery_email,omitempty"`
+	RecoveryEmailPassword string     `json:"recovery_email_password,omitempty"`
+	Cookie                string     `json:"cookie,omitempty"`
+	Status                string     `json:"status,omitempty"`
+	Attributes            Attributes `json:"attributes,omitempty"`
+	CreatedAt             time.Time  `json:"created_at,omitempty"`
+	Version               int64      `json:"version,omitempty"`
+	// CookiesExpireAt is the earliest expiry of the cookies, derived from
+	// Cookie when the account is stored.
+	CookiesExpireAt *time.Time `json:"cookies_expire_at,omitempty"`
+	// Tags are changed with the tag endpoints only, sorted, and do not
+	// change the version.
+	Tags []string `json:"tags,omitempty"`
+	// DeletedAt is set while the account is in the trash.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
+// AccountStatusSessionExpired is set on accounts once one of their cookies
+// has expired.
+const AccountStatusSessionExpired = "session_expired"
+
 type AccountCreate struct {
-	Name                  string `json:"name,omitempty"`
-	AccountType           string `json:"account_type,omitempty"`
-	Login                 string `json:"login,omitempty"`
-	Password              string `json:"password,omitempty"`
-	Email                 string `json:"email,omitempty"`
-	EmailPassword         string `json:"emailPassword,omitempty"`
-	RecoveryEmail         string `json:"recovery_email,omitempty"`
-	RecoveryEmailPassword string `json:"recovery_email_password,omitempty"`
-	Cookie                string `json:"cookie,omitempty"`
-	Status                string `json:"status,omitempty"`
+	Name                  string     `json:"name,omitempty"`
+	AccountType           string     `json:"account_type,omitempty"`
+	Login                 string     `json:"login,omitempty"`
+	Password              string     `json:"password,omitempty"`
+	Email                 string     `json:"email,omitempty"`
+	EmailPassword         string     `json:"emailPassword,omitempty"`
+	RecoveryEmail         string     `json:"recovery_email,omitempty"`
+	RecoveryEmailPassword string     `json:"recovery_email_password,omitempty"`
+	Cookie                string     `json:"cookie,omitempty"`
+	Status                string     `json:"status,omitempty"`
+	Attributes            Attributes `json:"attributes,omitempty"`
 }
 
 type AccountUpdate struct {
-	Name                  string `json:"name,omitempty"`
-	AccountType           string `json:"account_type,omitempty"`
-	Login                 string `json:"login,omitempty"`
-	Password              string `json:"password,omitempty"`
-	Email                 string `json:"email,omitempty"`
-	EmailPassword         string `json:"emailPassword,omitempty"`
-	RecoveryEmail         string `json:"recovery_email,omitempty"`
-	RecoveryEmailPassword string `json:"recovery_email_password,omitempty"`
-	Cookie                string `json:"cookie,omitempty"`
-	Status                string `json:"status,omitempty"`
+	Name                  string     `json:"name,omitempty"`
+	AccountType           string     `json:"account_type,omitempty"`
+	Login                 string     `json:"login,omitempty"`
+	Password              string     `json:"password,omitempty"`
+	Email                 string     `json:"email,omitempty"`
+	EmailPassword         string     `json:"emailPassword,omitempty"`
+	RecoveryEmail         string     `json:"recovery_email,omitempty"`
+	RecoveryEmailPassword string     `json:"recovery_email_password,omitempty"`
+	Cookie                string     `json:"cookie,omitempty"`
+	Status                string     `json:"status,omitempty"`
+	Attributes            Attributes `json:"attributes,omitempty"`
+}
+
+// SecretFieldNames lists the JSON names of the secret fields in the order
+// Secrets returns them.
+var SecretFieldNames = []string{"password", "emailPassword", "recovery_email_password", "cookie"}
+
+// Secrets returns the secret fields, followed by the values of the secret
+// attributes in name order. The attributes are copied first, so changing
+// the fields never changes other copies of the account.
+func (account *Account) Secrets() []*string {
+	account.Attributes = account.Attributes.clone()
+	return append([]*string{
+		&account.Password,
+		&account.EmailPassword,
+		&account.RecoveryEmailPassword,
+		&account.Cookie,
+	}, account.Attributes.secrets()...)
+}
+
+// PresentSecrets returns the names of the secret fields that are set.
+func (account Account) PresentSecrets() []string {
+	var names []string
+	for i, field := range account.Secrets()[:len(SecretFieldNames)] {
+		if *field != "" {
+			names = append(names, SecretFieldNames[i])
+		}
+	}
+	for _, name := range account.Attributes.secretNames() {
+		if account.Attributes[name].Value != "" {
+			names = append(names, attributeFieldName(name))
+		}
+	}
+	return names
+}
+
+type namedValue struct {
+	name  string
+	value string
+}
+
+func (account Account) namedValues() []namedValue {
+	return []namedValue{
+		{"name", account.Name},
+		{"account_type", account.AccountType},
+		{"login", account.Login},
+		{"password", account.Password},
+		{"email", account.Email},
+		{"emailPassword", account.EmailPassword},
+		{"recovery_email", account.RecoveryEmail},
+		{"recovery_email_password", account.RecoveryEmailPassword},
+		{"cookie", account.Cookie},
+		{"status", account.Status},
+	}
+}
+
+// ChangedFields returns the JSON names of the fields that differ between
+// before and after.
+func ChangedFields(before, after Account) []string {
+	var names []string
+	afterValues := after.namedValues()
+	for i, beforeValue := range before.namedValues() {
+		if beforeValue.value != afterValues[i].value {
+			names = append(names, beforeValue.name)
+		}
+	}
+	return append(names, changedAttributes(before.Attributes, after.Attributes)...)
+}
+
+func (accountCreate *AccountCreate) Secrets() []*string {
+	accountCreate.Attributes = accountCreate.Attributes.clone()
+	return append([]*string{
+		&accountCreate.Password,
+		&accountCreate.EmailPassword,
+		&accountCreate.RecoveryEmailPassword,
+		&accountCreate.Cookie,
+	}, accountCreate.Attributes.secrets()...)
+}
+
+// Account returns the account that is created.
+func (accountCreate AccountCreate) Account() Account {
+	return Account{
+		Name:                  accountCreate.Name,
+		AccountType:           accountCreate.AccountType,
+		Login:                 accountCreate.Login,
+		Password:              accountCreate.Password,
+		Email:                 accountCreate.Email,
+		EmailPassword:         accountCreate.EmailPassword,
+		RecoveryEmail:         accountCreate.RecoveryEmail,
+		RecoveryEmailPassword: accountCreate.RecoveryEmailPassword,
+		Cookie:                accountCreate.Cookie,
+		Status:                accountCreate.Status,
+		Attributes:            accountCreate.Attributes,
+	}
+}
+
+// SetFields returns the JSON names of the fields given on creation.
+func (accountCreate AccountCreate) SetFields() []string {
+	return ChangedFields(Account{}, accountCreate.Account())
+}
+
+func (accountUpdate *AccountUpdate) Secrets() []*string {
+	accountUpdate.Attributes = accountUpdate.Attributes.clone()
+	return append([]*string{
+		&accountUpdate.Password,
+		&accountUpdate.EmailPassword,
+		&accountUpdate.RecoveryEmailPassword,
+		&accountUpdate.Cookie,
+	}, accountUpdate.Attributes.secrets()...)
+}
+
+// Redacted returns a copy that is safe to log.
+func (account Account) Redacted() interface{} {
+	redact(account.Secrets()...)
+	return account
+}
+
+// Redacted returns a copy that is safe to log.
+func (accountCreate AccountCreate) Redacted() interface{} {
+	redact(accountCreate.Secrets()...)
+	return accountCreate
+}
+
+// Redacted returns a copy that is safe to log.
+func (accountUpdate AccountUpdate) Redacted() interface{} {
+	redact(accountUpdate.Secrets()...)
+	return accountUpdate
+}
+
+// Masked returns a copy with secrets hidden, for callers that may not see them.
+func (account Account) Masked() Account {
+	mask(account.Secrets()...)
+	return account
+}
+
+const (
+	redactedValue = "[REDACTED]"
+	maskedValue   = "********"
+)
+
+func redact(fields ...*string) {
+	for _, field := range fields {
+		if *field != "" {
+			*field = redactedValue
+		}
+	}
+}
+
+func mask(fields ...*string) {
+	for _, field := range fields {
+		if *field != "" {
+			*field = maskedValue
+		}
+	}
 }
@@ -1,30 +1,78 @@
 package account
 
 import (
+	"account_storage/pkg/httperror"
 	"account_storage/pkg/model"
 	"context"
+	"encoding/json"
+	"io"
 	"log"
+	"net/http"
 
 	"github.com/go-kit/kit/endpoint"
 )
 
 type Endpoints struct {
-	Create  endpoint.Endpoint
-	GetByID endpoint.Endpoint
-	Update  endpoint.Endpoint
-	Delete  endpoint.Endpoint
-	GetAll  endpoint.Endpoint
-	Nginx   endpoint.Endpoint
+	Create         endpoint.Endpoint
+	GetByID        endpoint.Endpoint
+	Update         endpoint.Endpoint
+	Patch          endpoint.Endpoint
+	Delete         endpoint.Endpoint
+	Trash          endpoint.Endpoint
+	Restore        endpoint.Endpoint
+	BatchCreate    endpoint.Endpoint
+	BatchUpdate    endpoint.Endpoint
+	BatchDelete    endpoint.Endpoint
+	GetAll         endpoint.Endpoint
+	Import         endpoint.Endpoint
+	Export         endpoint.Endpoint
+	Reveal         endpoint.Endpoint
+	GetCookies     endpoint.Endpoint
+	ReplaceCookies endpoint.Endpoint
+	MergeCookies   endpoint.Endpoint
+	StatusHistory  endpoint.Endpoint
+	History        endpoint.Endpoint
+	Rollback       endpoint.Endpoint
+	Tag            endpoint.Endpoint
+	Untag          endpoint.Endpoint
+	GetTags        endpoint.Endpoint
+	Lease          endpoint.Endpoint
+	RenewLease     endpoint.Endpoint
+	ReleaseLease   endpoint.Endpoint
+	RewrapKeys     endpoint.Endpoint
+	Nginx          endpoint.Endpoint
 }
 
 func MakeEndpoints(s Service) Endpoints {
 	return Endpoints{
-		Create:  makeCreateEndpoint(s),
-		GetByID: makeGetByIDEndpoint(s),
-		Update:  makeUpdateEndpoint(s),
-		Delete:  makeDeleteEndpoint(s),
-		GetAll:  makeGetAllEndpoint(s),
-		Nginx:   makeNginxEndpoint(s),
+		Create:         makeCreateEndpoint(s),
+		GetByID:        makeGetByIDEndpoint(s),
+		Update:         makeUpdateEndpoint(s),
+		Patch:          makePatchEndpoint(s),
+		Delete:         makeDeleteEndpoint(s),
+		Trash:          makeTrashEndpoint(s),
+		Restore:        makeRestoreEndpoint(s),
+		BatchCreate:    makeBatchCreateEndpoint(s),
+		BatchUpdate:    makeBatchUpdateEndpoint(s),
+		BatchDelete:    makeBatchDeleteEndpoint(s),
+		GetAll:         makeGetAllEndpoint(s),
+		Import:         makeImportEndpoint(s),
+		Export:         makeExportEndpoint(s),
+		Reveal:         makeRevealEndpoint(s),
+		GetCookies:     makeGetCookiesEndpoint(s),
+		ReplaceCookies: makeReplaceCookiesEndpoint(s),
+		MergeCookies:   makeMergeCookiesEndpoint(s),
+		StatusHistory:  makeStatusHistoryEndpoint(s),
+		History:        makeHistoryEndpoint(s),
+		Rollback:       makeRollbackEndpoint(s),
+		Tag:            makeTagEndpoint(s),
+		Untag:          makeUntagEndpoint(s),
+		GetTags:        makeGetTagsEndpoint(s),
+		Lease:          makeLeaseEndpoint(s),
+		RenewLease:     makeRenewLeaseEndpoint(s),
+		ReleaseLease:   makeReleaseLeaseEndpoint(s),
+		RewrapKeys:     makeRewrapKeysEndpoint(s),
+		Nginx:          makeNginxEndpoint(s),
 	}
 }
 
@@ -38,8 +86,47 @@ func makeCreateEndpoint(s Service) endpoint.Endpoint {
 
 func makeGetAllEndpoint(s Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
-		accounts, err := s.GetAll(ctx)
-		return GetAllResponse{Accounts: accounts, Err: err}, nil
+		req := request.(GetAllRequest)
+		page, err := s.GetAll(ctx, req.Filter)
+		return GetAllResponse{Accounts: page.Accounts, NextCursor: page.NextCursor, Err: err}, nil
+	}
+}
+
+func makeImportEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(ImportRequest)
+		report, err := s.Import(ctx, req.Options, req.Body)
+		response := ImportResponse{
+			DryRun:   report.DryRun,
+			Imported: report.Imported,
+			Failed:   report.Failed,
+			Lines:    make([]ImportLine, len(report.Lines)),
+			Err:      err,
+		}
+		for i, line := range report.Lines {
+			response.Lines[i] = ImportLine{Line: line.Line, ID: line.ID}
+			if line.Err != nil {
+				detail := httperror.NewResponse(line.Err).Error
+				response.Lines[i].Error = &detail
+			}
+		}
+		return response, nil
+	}
+}
+
+// makeExportEndpoint returns the export without running it, the transport
+// streams it to the client.
+func makeExportEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(ExportRequest)
+		return ExportResponse{
+			Columns:   req.Columns,
+			Delimiter: req.Delimiter,
+			Header:    req.Header,
+			export: func(fn func(model.Account) error) error {
+				return s.Export(ctx, req.Filter, req.Columns, fn)
+			},
+		}, nil
 	}
 }
 
@@ -51,21 +138,195 @@ func makeGetByIDEndpoint(s Service) endpoint.Endpoint {
 	}
 }
 
+func makeRevealEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(RevealRequest)
+		accountRes, err := s.Reveal(ctx, req.ID)
+		return RevealResponse{Account: accountRes, Err: err}, nil
+	}
+}
+
+func makeGetCookiesEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(GetCookiesRequest)
+		jar, version, err := s.GetCookies(ctx, req.ID)
+		return GetCookiesResponse{Jar: jar, Format: req.Format, Version: version, Err: err}, nil
+	}
+}
+
+func makeReplaceCookiesEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(SetCookiesRequest)
+		version, err := s.ReplaceCookies(ctx, req.ID, req.Version, req.Jar)
+		return CookiesResponse{Version: version, Err: err}, nil
+	}
+}
+
+func makeMergeCookiesEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(SetCookiesRequest)
+		version, err := s.MergeCookies(ctx, req.ID, req.Version, req.Jar)
+		return CookiesResponse{Version: version, Err: err}, nil
+	}
+}
+
+func makeStatusHistoryEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(StatusHistoryRequest)
+		statusTransitions, err := s.StatusHistory(ctx, req.ID)
+		return StatusHistoryResponse{StatusTransitions: statusTransitions, Err: err}, nil
+	}
+}
+
+func makeHistoryEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(CredentialHistoryRequest)
+		credentialVersions, err := s.CredentialHistory(ctx, req.ID)
+		return CredentialHistoryResponse{CredentialVersions: credentialVersions, Err: err}, nil
+	}
+}
+
+func makeRollbackEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(RollbackRequest)
+		version, err := s.Rollback(ctx, req.ID, req.Version, req.Target)
+		return RollbackResponse{Version: version, Err: err}, nil
+	}
+}
+
+func makeTagEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(TagRequest)
+		err := s.Tag(ctx, req.AccountTags)
+		return TagResponse{Err: err}, nil
+	}
+}
+
+func makeUntagEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(TagRequest)
+		err := s.Untag(ctx, req.AccountTags)
+		return TagResponse{Err: err}, nil
+	}
+}
+
+func makeGetTagsEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		tagCounts, err := s.GetTags(ctx)
+		return GetTagsResponse{Tags: tagCounts, Err: err}, nil
+	}
+}
+
 func makeUpdateEndpoint(s Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(model.Account)
-		err := s.Update(ctx, req)
-		return UpdateResponse{Err: err}, nil
+		version, err := s.Update(ctx, req)
+		return UpdateResponse{Version: version, Err: err}, nil
+	}
+}
+func makePatchEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(PatchRequest)
+		version, err := s.Patch(ctx, req.ID, req.Version, req.Patch)
+		return PatchResponse{Version: version, Err: err}, nil
 	}
 }
+
 func makeDeleteEndpoint(s Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		req := request.(DeleteRequest)
-		err := s.Delete(ctx, req.ID)
+		err := s.Delete(ctx, req.ID, req.Version)
 		return DeleteResponse{Err: err}, nil
 	}
 }
 
+func makeTrashEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(TrashRequest)
+		page, err := s.Trash(ctx, req.Filter)
+		return TrashResponse{Accounts: page.Accounts, NextCursor: page.NextCursor, Err: err}, nil
+	}
+}
+
+func makeRestoreEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(RestoreRequest)
+		version, err := s.Restore(ctx, req.ID)
+		return RestoreResponse{Version: version, Err: err}, nil
+	}
+}
+
+func makeBatchCreateEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(BatchCreateRequest)
+		results, err := s.BatchCreate(ctx, req.Mode, req.Accounts)
+		return newBatchResponse(results, err), nil
+	}
+}
+
+func makeBatchUpdateEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(BatchUpdateRequest)
+		accounts := make([]model.Account, len(req.Accounts))
+		for i, accountUpdate := range req.Accounts {
+			accounts[i] = model.Account{ID: accountUpdate.ID, Version: accountUpdate.Version}.Replace(accountUpdate.Account)
+		}
+		results, err := s.BatchUpdate(ctx, req.Mode, accounts)
+		return newBatchResponse(results, err), nil
+	}
+}
+
+func makeBatchDeleteEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(BatchDeleteRequest)
+		results, err := s.BatchDelete(ctx, req.Mode, req.Accounts)
+		return newBatchResponse(results, err), nil
+	}
+}
+
+func newBatchResponse(results []model.BatchResult, err error) BatchResponse {
+	response := BatchResponse{Results: make([]BatchItemResult, len(results)), Err: err}
+	for i, result := range results {
+		response.Results[i] = BatchItemResult{Index: i, ID: result.ID, Version: result.Version}
+		if result.Err != nil {
+			detail := httperror.NewResponse(result.Err).Error
+			response.Results[i].Error = &detail
+		}
+	}
+	return response
+}
+
+func makeLeaseEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(LeaseRequest)
+		lease, accountRes, err := s.Lease(ctx, req.Lease)
+		return LeaseResponse{Lease: lease, Account: accountRes, Err: err}, nil
+	}
+}
+
+func makeRenewLeaseEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(RenewLeaseRequest)
+		lease, err := s.RenewLease(ctx, req.ID, req.Lease)
+		return RenewLeaseResponse{Lease: lease, Err: err}, nil
+	}
+}
+
+func makeReleaseLeaseEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(ReleaseLeaseRequest)
+		err := s.ReleaseLease(ctx, req.ID, req.LeaseID)
+		return ReleaseLeaseResponse{Err: err}, nil
+	}
+}
+
+func makeRewrapKeysEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		rewrapped, err := s.RewrapKeys(ctx)
+		return RewrapKeysResponse{Rewrapped: rewrapped, Err: err}, nil
+	}
+}
+
 func makeNginxEndpoint(s Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		log.Print("start makeNginxEndpoint func in endpoint")
@@ -84,6 +345,8 @@ type CreateResponse struct {
 	Err error  `json:"error,omitempty"`
 }
 
+func (r CreateResponse) error() error { return r.Err }
+
 type GetByIDRequest struct {
 	ID string `json:"id"`
 }
@@ -93,7 +356,52 @@ type GetByIDResponse struct {
 	Err     error         `json:"error,omitempty"`
 }
 
+func (r GetByIDResponse) error() error { return r.Err }
+
+func (r GetByIDResponse) Headers() http.Header { return versionHeaders(r.Account.Version) }
+
+func (r GetByIDResponse) Masked() interface{} {
+	r.Account = r.Account.Masked()
+	return r
+}
+
 type GetAllRequest struct {
+	Filter model.AccountFilter `json:"filter"`
+}
+
+type ImportRequest struct {
+	Options model.ImportOptions
+	Body    io.Reader
+}
+
+type ImportLine struct {
+	Line  int               `json:"line"`
+	ID    string            `json:"id,omitempty"`
+	Error *httperror.Detail `json:"error,omitempty"`
+}
+
+type ImportResponse struct {
+	DryRun   bool         `json:"dry_run"`
+	Imported int          `json:"imported"`
+	Failed   int          `json:"failed"`
+	Lines    []ImportLine `json:"lines"`
+	Err      error        `json:"error,omitempty"`
+}
+
+func (r ImportResponse) error() error { return r.Err }
+
+type ExportRequest struct {
+	Filter    model.AccountFilter
+	Columns   []string
+	Delimiter rune
+	Header    bool
+}
+
+type ExportResponse struct {
+	Columns   []string
+	Delimiter rune
+	Header    bool
+	export    func(fn func(model.Account) error) error
 }
 
 type NginxRequest struct {
@@ -104,9 +412,23 @@ type NginxResponse struct {
 	Err error  `json:"error,omitempty"`
 }
 
+func (r NginxResponse) error() error { return r.Err }
+
 type GetAllResponse struct {
-	Accounts []model.Account `json:"accounts"`
-	Err      error           `json:"error,omitempty"`
+	Accounts   []model.Account `json:"accounts"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+	Err        error           `json:"error,omitempty"`
+}
+
+func (r GetAllResponse) error() error { return r.Err }
+
+func (r GetAllResponse) Masked() interface{} {
+	accounts := make([]model.Account, 0, len(r.Accounts))
+	for _, account := range r.Accounts {
+		accounts = append(accounts, account.Masked())
+	}
+	r.Accounts = accounts
+	return r
 }
 
 type UpdateRequest struct {
@@ -114,13 +436,257 @@ type UpdateRequest struct {
 }
 
 type UpdateResponse struct {
-	Err error `json:"error,omitempty"`
+	Version int64 `json:"version,omitempty"`
+	Err     error `json:"error,omitempty"`
 }
 
+func (r UpdateResponse) error() error { return r.Err }
+
+func (r UpdateResponse) Headers() http.Header { return versionHeaders(r.Version) }
+
+type PatchRequest struct {
+	ID      string          `json:"-"`
+	Version int64           `json:"-"`
+	Patch   json.RawMessage `json:"patch"`
+}
+
+type PatchResponse struct {
+	Version int64 `json:"version,omitempty"`
+	Err     error `json:"error,omitempty"`
+}
+
+func (r PatchResponse) error() error { return r.Err }
+
+func (r PatchResponse) Headers() http.Header { return versionHeaders(r.Version) }
+
 type DeleteRequest struct {
-	ID string `json:"id"`
+	ID      string `json:"id"`
+	Version int64  `json:"-"`
 }
 
 type DeleteResponse struct {
 	Err error `json:"error,omitempty"`
 }
+
+func (r DeleteResponse) error() error { return r.Err }
+
+type TrashRequest struct {
+	Filter model.AccountFilter `json:"filter"`
+}
+
+type TrashResponse struct {
+	Accounts   []model.Account `json:"accounts"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+	Err        error           `json:"error,omitempty"`
+}
+
+func (r TrashResponse) error() error { return r.Err }
+
+func (r TrashResponse) Masked() interface{} {
+	accounts := make([]model.Account, 0, len(r.Accounts))
+	for _, account := range r.Accounts {
+		accounts = append(accounts, account.Masked())
+	}
+	r.Accounts = accounts
+	return r
+}
+
+type RestoreRequest struct {
+	ID string `json:"id"`
+}
+
+type RestoreResponse struct {
+	Version int64 `json:"version,omitempty"`
+	Err     error `json:"error,omitempty"`
+}
+
+func (r RestoreResponse) error() error { return r.Err }
+
+func (r RestoreResponse) Headers() http.Header { return versionHeaders(r.Version) }
+
+type BatchCreateRequest struct {
+	Mode     model.BatchMode       `json:"mode,omitempty" enums:"atomic,best_effort"`
+	Accounts []model.AccountCreate `json:"accounts"`
+}
+
+type BatchUpdateRequest struct {
+	Mode     model.BatchMode            `json:"mode,omitempty" enums:"atomic,best_effort"`
+	Accounts []model.AccountBatchUpdate `json:"accounts"`
+}
+
+type BatchDeleteRequest struct {
+	Mode     model.BatchMode        `json:"mode,omitempty" enums:"atomic,best_effort"`
+	Accounts []model.AccountVersion `json:"accounts"`
+}
+
+type BatchItemResult struct {
+	Index   int               `json:"index"`
+	ID      string            `json:"id,omitempty"`
+	Version int64             `json:"version,omitempty"`
+	Error   *httperror.Detail `json:"error,omitempty"`
+}
+
+type BatchResponse struct {
+	Results []BatchItemResult `json:"results"`
+	Err     error             `json:"error,omitempty"`
+}
+
+func (r BatchResponse) error() error { return r.Err }
+
+type RevealRequest struct {
+	ID string `json:"id"`
+}
+
+type RevealResponse struct {
+	Account model.Account `json:"account"`
+	Err     error         `json:"error,omitempty"`
+}
+
+func (r RevealResponse) error() error { return r.Err }
+
+type GetCookiesRequest struct {
+	ID     string
+	Format model.CookieFormat
+}
+
+type GetCookiesResponse struct {
+	Jar     model.CookieJar
+	Format  model.CookieFormat
+	Version int64
+	Err     error
+}
+
+func (r GetCookiesResponse) error() error { return r.Err }
+
+func (r GetCookiesResponse) Headers() http.Header { return versionHeaders(r.Version) }
+
+type SetCookiesRequest struct {
+	ID      string
+	Version int64
+	Jar     model.CookieJar
+}
+
+type CookiesResponse struct {
+	Version int64 `json:"version,omitempty"`
+	Err     error `json:"error,omitempty"`
+}
+
+func (r CookiesResponse) error() error { return r.Err }
+
+func (r CookiesResponse) Headers() http.Header { return versionHeaders(r.Version) }
+
+type StatusHistoryRequest struct {
+	ID string `json:"id"`
+}
+
+type StatusHistoryResponse struct {
+	StatusTransitions []model.StatusTransition `json:"status_transitions"`
+	Err               error                    `json:"error,omitempty"`
+}
+
+func (r StatusHistoryResponse) error() error { return r.Err }
+
+type CredentialHistoryRequest struct {
+	ID string `json:"id"`
+}
+
+type CredentialHistoryResponse struct {
+	CredentialVersions []model.CredentialVersion `json:"credential_versions"`
+	Err                error                     `json:"error,omitempty"`
+}
+
+func (r CredentialHistoryResponse) error() error { return r.Err }
+
+func (r CredentialHistoryResponse) Masked() interface{} {
+	credentialVersions := make([]model.CredentialVersion, 0, len(r.CredentialVersions))
+	for _, credentialVersion := range r.CredentialVersions {
+		credentialVersions = append(credentialVersions, credentialVersion.Masked())
+	}
+	r.CredentialVersions = credentialVersions
+	return r
+}
+
+type RollbackRequest struct {
+	ID      string `json:"id"`
+	Version int64  `json:"-"`
+	Target  int64  `json:"target"`
+}
+
+type RollbackResponse struct {
+	Version int64 `json:"version,omitempty"`
+	Err     error `json:"error,omitempty"`
+}
+
+func (r RollbackResponse) error() error { return r.Err }
+
+func (r RollbackResponse) Headers() http.Header { return versionHeaders(r.Version) }
+
+type TagRequest struct {
+	model.AccountTags
+}
+
+type TagResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r TagResponse) error() error { return r.Err }
+
+type GetTagsRequest struct {
+}
+
+type GetTagsResponse struct {
+	Tags []model.TagCount `json:"tags"`
+	Err  error            `json:"error,omitempty"`
+}
+
+func (r GetTagsResponse) error() error { return r.Err }
+
+type LeaseRequest struct {
+	Lease model.LeaseCreate `json:"lease"`
+}
+
+type LeaseResponse struct {
+	Lease   model.Lease   `json:"lease"`
+	Account model.Account `json:"account"`
+	Err     error         `json:"error,omitempty"`
+}
+
+func (r LeaseResponse) error() error { return r.Err }
+
+func (r LeaseResponse) Masked() interface{} {
+	r.Account = r.Account.Masked()
+	return r
+}
+
+type RenewLeaseRequest struct {
+	ID    string           `json:"-"`
+	Lease model.LeaseRenew `json:"lease"`
+}
+
+type RenewLeaseResponse struct {
+	Lease model.Lease `json:"lease"`
+	Err   error       `json:"error,omitempty"`
+}
+
+func (r RenewLeaseResponse) error() error { return r.Err }
+
+type ReleaseLeaseRequest struct {
+	ID      string `json:"-"`
+	LeaseID string `json:"lease_id"`
+}
+
+type ReleaseLeaseResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r ReleaseLeaseResponse) error() error { return r.Err }
+
+type RewrapKeysRequest struct {
+}
+
+type RewrapKeysResponse struct {
+	Rewrapped int   `json:"rewrapped"`
+	Err       error `json:"error,omitempty"`
+}
+
+func (r RewrapKeysResponse) error() error { return r.Err }
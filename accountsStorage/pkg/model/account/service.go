@@ -2,9 +2,15 @@ package account
 
 import (
 	"account_storage/internal/app/store"
+	"account_storage/pkg/auth"
 	"account_storage/pkg/model"
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"sort"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
@@ -12,21 +18,62 @@ import (
 type Service interface {
 	Create(ctx context.Context, account model.AccountCreate) (string, error)
 	GetByID(ctx context.Context, id string) (model.Account, error)
-	Update(ctx context.Context, account model.Account) error
-	Delete(ctx context.Context, id string) error
-	GetAll(ctx context.Context) ([]model.Account, error)
+	Update(ctx context.Context, account model.Account) (int64, error)
+	Patch(ctx context.Context, id string, version int64, patch []byte) (int64, error)
+	Delete(ctx context.Context, id string, version int64) error
+	Trash(ctx context.Context, filter model.AccountFilter) (model.AccountPage, error)
+	Restore(ctx context.Context, id string) (int64, error)
+	PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int, error)
+	BatchCreate(ctx context.Context, mode model.BatchMode, accountCreates []model.AccountCreate) ([]model.BatchResult, error)
+	BatchUpdate(ctx context.Context, mode model.BatchMode, accounts []model.Account) ([]model.BatchResult, error)
+	BatchDelete(ctx context.Context, mode model.BatchMode, accountVersions []model.AccountVersion) ([]model.BatchResult, error)
+	GetAll(ctx context.Context, filter model.AccountFilter) (model.AccountPage, error)
+	Import(ctx context.Context, options model.ImportOptions, body io.Reader) (model.ImportReport, error)
+	Export(ctx context.Context, filter model.AccountFilter, columns []string, fn func(model.Account) error) error
+	Reveal(ctx context.Context, id string) (model.Account, error)
+	GetCookies(ctx context.Context, id string) (model.CookieJar, int64, error)
+	ReplaceCookies(ctx context.Context, id string, version int64, jar model.CookieJar) (int64, error)
+	MergeCookies(ctx context.Context, id string, version int64, jar model.CookieJar) (int64, error)
+	Lease(ctx context.Context, lease model.LeaseCreate) (model.Lease, model.Account, error)
+	RenewLease(ctx context.Context, accountID string, leaseRenew model.LeaseRenew) (model.Lease, error)
+	ReleaseLease(ctx context.Context, accountID, leaseID string) error
+	RewrapKeys(ctx context.Context) (int, error)
+	ExpireSessions(ctx context.Context) (int, error)
+	StatusHistory(ctx context.Context, id string) ([]model.StatusTransition, error)
+	CredentialHistory(ctx context.Context, id string) ([]model.CredentialVersion, error)
+	Rollback(ctx context.Context, id string, version, target int64) (int64, error)
+	Tag(ctx context.Context, accountTags model.AccountTags) error
+	Untag(ctx context.Context, accountTags model.AccountTags) error
+	GetTags(ctx context.Context) ([]model.TagCount, error)
 	Nginx(ctx context.Context) (string, error)
 }
 
 type service struct {
-	repository store.AccountRepository
-	logger     *logrus.Logger
+	repository                  store.AccountRepository
+	auditRepository             store.AuditRepository
+	statusHistoryRepository     store.StatusHistoryRepository
+	accountTypeRepository       store.AccountTypeRepository
+	credentialHistoryRepository store.CredentialHistoryRepository
+	statusMachine               model.StatusMachine
+	logger                      *logrus.Logger
 }
 
-func NewService(repository store.AccountRepository, logger *logrus.Logger) Service {
+func NewService(
+	repository store.AccountRepository,
+	auditRepository store.AuditRepository,
+	statusHistoryRepository store.StatusHistoryRepository,
+	accountTypeRepository store.AccountTypeRepository,
+	credentialHistoryRepository store.CredentialHistoryRepository,
+	statusMachine model.StatusMachine,
+	logger *logrus.Logger) Service {
 	return &service{
-		repository: repository,
-		logger:     logger,
+		repository:                  repository,
+		auditRepository:             auditRepository,
+		statusHistoryRepository:     statusHistoryRepository,
+		accountTypeRepository:       accountTypeRepository,
+		credentialHistoryRepository: credentialHistoryRepository,
+		statusMachine:               statusMachine,
+		logger:                      logger,
 	}
 }
 
@@ -37,56 +84,86 @@ func NewService(repository store.AccountRepository, logger *logrus.Logger) Servi
 // @Produce json
 // @Param account body CreateRequest true "Account to create"
 // @Success 200 {object} CreateResponse
-// @Failure 400 {string} string "Bad Request"
-// @Failure 500 {string} string "Internal Server Error"
+// @Failure 400 {object} httperror.Response "Bad Request"
+// @Failure 500 {object} httperror.Response "Internal Server Error"
 // @Router /accounts [post]
 func (s *service) Create(ctx context.Context, account model.AccountCreate) (string, error) {
+	status, err := s.statusMachine.InitialStatus(account.Status)
+	if err != nil {
+		return "", err
+	}
+	account.Status = status
+
+	if err := s.accountChecker(ctx)(account.Account(), ""); err != nil {
+		return "", err
+	}
+
 	id, err := s.repository.Create(ctx, account)
 	if err != nil {
 		s.logger.WithFields(logrus.Fields{
 			"package":  "account",
 			"function": "Create",
 			"error":    err,
-			"account":  account,
+			"account":  account.Redacted(),
+			"caller":   callerIdentity(ctx),
 		}).Error("creating account failed")
 
 		return id, err
 	}
+
+	s.audit(ctx, model.AuditActionCreate, id, account.SetFields())
+	s.recordTransition(ctx, model.StatusTransitionCreate{AccountID: id, To: account.Status})
+
 	return id, nil
 }
 
 // @Summary Get all accounts
-// @Description Retrieve a list of all accounts
+// @Description Retrieve a page of accounts with secret fields masked
 // @Tags accounts
 // @Accept json
 // @Produce json
-// @Success 200 {object} GetAllResponse "List of accounts"
-// @Failure 500 {string} string "Internal Server Error"
+// @Param account_type query string false "Account type"
+// @Param status query string false "Status"
+// @Param email query string false "Email, case insensitive"
+// @Param created_after query string false "Created at or after, RFC 3339"
+// @Param created_before query string false "Created before, RFC 3339"
+// @Param cookies_expiring_before query string false "Earliest cookie expiry before, RFC 3339"
+// @Param attribute query []string false "Non-secret attribute value as name:value, all must match" collectionFormat(multi)
+// @Param tag query []string false "Tag" collectionFormat(multi)
+// @Param tag_match query string false "Whether accounts must have all tags or any of them, all by default"
+// @Param sort query string false "Sort field, created_at or name, prefixed with - for descending order"
+// @Param limit query int false "Page size"
+// @Param cursor query string false "Cursor from the previous page"
+// @Success 200 {object} GetAllResponse "Page of accounts"
+// @Failure 400 {object} httperror.Response "Bad Request"
+// @Failure 500 {object} httperror.Response "Internal Server Error"
 // @Router /accounts [get]
-func (s *service) GetAll(ctx context.Context) ([]model.Account, error) {
-	accounts, err := s.repository.GetAll(ctx)
+func (s *service) GetAll(ctx context.Context, filter model.AccountFilter) (model.AccountPage, error) {
+	page, err := s.repository.GetAll(ctx, filter)
 	if err != nil {
 		s.logger.WithFields(logrus.Fields{
 			"package":  "account",
 			"function": "GetAll",
 			"error":    err,
+			"filter":   filter,
 		}).Error("getting all accounts failed")
 
-		return nil, err
+		return model.AccountPage{}, err
 	}
-	return accounts, nil
+	return page, nil
 }
 
 // @Summary Get account by ID
-// @Description Retrieve an account by its unique identifier
+// @Description Retrieve an account by its unique identifier with secret fields masked
 // @Tags accounts
 // @Accept json
 // @Produce json
 // @Param id path string true "Account ID"
 // @Success 200 {object} GetByIDResponse "Account data"
-// @Failure 400 {string} string "Bad Request"
-// @Failure 404 {string} string "Not Found"
-// @Failure 500 {string} string "Internal Server Error"
+// @Header 200 {string} ETag "Account version"
+// @Failure 400 {object} httperror.Response "Bad Request"
+// @Failure 404 {object} httperror.Response "Not Found"
+// @Failure 500 {object} httperror.Response "Internal Server Error"
 // @Router /accounts/{id} [get]
 func (s *service) GetByID(ctx context.Context, id string) (model.Account, error) {
 	account, err := s.repository.GetByID(ctx, id)
@@ -103,58 +180,443 @@ func (s *service) GetByID(ctx context.Context, id string) (model.Account, error)
 	return account, nil
 }
 
-// @Summary Update an account
-// @Description Update an account with specified details
+// @Summary Replace an account
+// @Description Replace every field of an account, omitted fields are cleared
 // @Tags accounts
 // @Accept json
 // @Produce json
 // @Param id path string true "Account ID"
+// @Param If-Match header string true "ETag of the account"
+// @Param X-Status-Reason header string false "Reason of a status change"
 // @Param account body UpdateRequest true "Account to update"
 // @Success 200 {object} UpdateResponse "Updated account data"
-// @Failure 400 {string} string "Bad Request"
-// @Failure 404 {string} string "Not Found"
-// @Failure 500 {string} string "Internal Server Error"
+// @Header 200 {string} ETag "Account version"
+// @Failure 400 {object} httperror.Response "Bad Request"
+// @Failure 404 {object} httperror.Response "Not Found"
+// @Failure 409 {object} httperror.Response "Status change not allowed"
+// @Failure 412 {object} httperror.Response "Account has changed"
+// @Failure 428 {object} httperror.Response "If-Match is missing"
+// @Failure 500 {object} httperror.Response "Internal Server Error"
 // @Router /accounts/{id} [put]
-func (s *service) Update(ctx context.Context, account model.Account) error {
-	err := s.repository.Update(ctx, account)
+func (s *service) Update(ctx context.Context, account model.Account) (int64, error) {
+	id := account.ID.String()
+
+	before, err := s.repository.GetByID(ctx, id)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "account",
+			"function": "Update",
+			"error":    err,
+			"id":       id,
+			"caller":   callerIdentity(ctx),
+		}).Error("getting account by id failed")
+
+		return 0, err
+	}
+
+	if err := checkVersion(before, account.Version); err != nil {
+		return 0, err
+	}
+
+	if err := s.statusMachine.CheckTransition(before.Status, account.Status); err != nil {
+		return 0, err
+	}
+	if err := s.accountChecker(ctx)(account, before.AccountType); err != nil {
+		return 0, err
+	}
+	if err := s.recordCredentials(ctx, "Update", before, account); err != nil {
+		return 0, err
+	}
+
+	err = s.repository.Update(ctx, account)
 	if err != nil {
 		s.logger.WithFields(logrus.Fields{
 			"package":  "account",
 			"function": "Update",
 			"error":    err,
-			"account":  account,
+			"account":  account.Redacted(),
+			"caller":   callerIdentity(ctx),
 		}).Error("updating account failed")
 
-		return err
+		return 0, err
 	}
-	return nil
+
+	after, err := s.repository.GetByID(ctx, id)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "account",
+			"function": "Update",
+			"error":    err,
+			"id":       id,
+			"caller":   callerIdentity(ctx),
+		}).Error("getting updated account failed")
+
+		return 0, err
+	}
+
+	s.audit(ctx, model.AuditActionUpdate, id, model.ChangedFields(before, after))
+	s.recordTransition(ctx, model.StatusTransitionCreate{AccountID: id, From: before.Status, To: after.Status})
+
+	return after.Version, nil
+}
+
+// @Summary Patch an account
+// @Description Apply a JSON merge patch (RFC 7396) to an account, null clears a field
+// @Tags accounts
+// @Accept json
+// @Produce json
+// @Param id path string true "Account ID"
+// @Param If-Match header string true "ETag of the account"
+// @Param X-Status-Reason header string false "Reason of a status change"
+// @Param patch body UpdateRequest true "Merge patch of the account"
+// @Success 200 {object} PatchResponse
+// @Header 200 {string} ETag "Account version"
+// @Failure 400 {object} httperror.Response "Bad Request"
+// @Failure 404 {object} httperror.Response "Not Found"
+// @Failure 409 {object} httperror.Response "Status change not allowed"
+// @Failure 412 {object} httperror.Response "Account has changed"
+// @Failure 428 {object} httperror.Response "If-Match is missing"
+// @Failure 500 {object} httperror.Response "Internal Server Error"
+// @Router /accounts/{id} [patch]
+func (s *service) Patch(ctx context.Context, id string, version int64, patch []byte) (int64, error) {
+	before, err := s.repository.GetByID(ctx, id)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "account",
+			"function": "Patch",
+			"error":    err,
+			"id":       id,
+			"caller":   callerIdentity(ctx),
+		}).Error("getting account by id failed")
+
+		return 0, err
+	}
+
+	if err := checkVersion(before, version); err != nil {
+		return 0, err
+	}
+
+	after, err := before.ApplyMergePatch(patch)
+	if err != nil {
+		return 0, err
+	}
+	after.Version = version
+
+	if err := s.statusMachine.CheckTransition(before.Status, after.Status); err != nil {
+		return 0, err
+	}
+	if err := s.accountChecker(ctx)(after, before.AccountType); err != nil {
+		return 0, err
+	}
+	if err := s.recordCredentials(ctx, "Patch", before, after); err != nil {
+		return 0, err
+	}
+
+	err = s.repository.Update(ctx, after)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "account",
+			"function": "Patch",
+			"error":    err,
+			"account":  after.Redacted(),
+			"caller":   callerIdentity(ctx),
+		}).Error("updating account failed")
+
+		return 0, err
+	}
+
+	s.audit(ctx, model.AuditActionUpdate, id, model.ChangedFields(before, after))
+	s.recordTransition(ctx, model.StatusTransitionCreate{AccountID: id, From: before.Status, To: after.Status})
+
+	return version + 1, nil
 }
 
 // @Summary Delete an account
-// @Description Delete an account
+// @Description Move an account to the trash, it can be restored until it is purged
 // @Tags accounts
 // @Accept json
 // @Produce json
 // @Param id path string true "Account ID to delete"
+// @Param If-Match header string true "ETag of the account"
 // @Success 200 {object} DeleteResponse
-// @Failure 400 {string} string "Bad Request"
-// @Failure 500 {string} string "Internal Server Error"
+// @Failure 400 {object} httperror.Response "Bad Request"
+// @Failure 404 {object} httperror.Response "Not Found"
+// @Failure 412 {object} httperror.Response "Account has changed"
+// @Failure 428 {object} httperror.Response "If-Match is missing"
+// @Failure 500 {object} httperror.Response "Internal Server Error"
 // @Router /accounts/{id} [delete]
-func (s *service) Delete(ctx context.Context, id string) error {
-	err := s.repository.Delete(ctx, id)
+func (s *service) Delete(ctx context.Context, id string, version int64) error {
+	err := s.repository.Delete(ctx, id, version)
 	if err != nil {
 		s.logger.WithFields(logrus.Fields{
 			"package":  "account",
 			"function": "Delete",
 			"error":    err,
 			"id":       id,
+			"caller":   callerIdentity(ctx),
 		}).Error("deleting account failed")
 
 		return err
 	}
+
+	s.audit(ctx, model.AuditActionDelete, id, nil)
+
+	return nil
+}
+
+// @Summary List deleted accounts
+// @Description Retrieve a page of the accounts in the trash with secret fields masked
+// @Tags accounts
+// @Accept json
+// @Produce json
+// @Param account_type query string false "Account type"
+// @Param status query string false "Status"
+// @Param email query string false "Email, case insensitive"
+// @Param created_after query string false "Created at or after, RFC 3339"
+// @Param created_before query string false "Created before, RFC 3339"
+// @Param attribute query []string false "Non-secret attribute value as name:value, all must match" collectionFormat(multi)
+// @Param tag query []string false "Tag" collectionFormat(multi)
+// @Param tag_match query string false "Whether accounts must have all tags or any of them, all by default"
+// @Param sort query string false "Sort field, created_at or name, prefixed with - for descending order"
+// @Param limit query int false "Page size"
+// @Param cursor query string false "Cursor from the previous page"
+// @Success 200 {object} TrashResponse "Page of deleted accounts"
+// @Failure 400 {object} httperror.Response "Bad Request"
+// @Failure 500 {object} httperror.Response "Internal Server Error"
+// @Router /accounts/trash [get]
+func (s *service) Trash(ctx context.Context, filter model.AccountFilter) (model.AccountPage, error) {
+	filter.Deleted = true
+
+	page, err := s.repository.GetAll(ctx, filter)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "account",
+			"function": "Trash",
+			"error":    err,
+			"filter":   filter,
+		}).Error("getting deleted accounts failed")
+
+		return model.AccountPage{}, err
+	}
+	return page, nil
+}
+
+// @Summary Restore a deleted account
+// @Description Take an account out of the trash
+// @Tags accounts
+// @Accept json
+// @Produce json
+// @Param id path string true "Account ID"
+// @Success 200 {object} RestoreResponse
+// @Header 200 {string} ETag "New account version"
+// @Failure 400 {object} httperror.Response "Bad Request"
+// @Failure 404 {object} httperror.Response "Not Found"
+// @Failure 500 {object} httperror.Response "Internal Server Error"
+// @Router /accounts/{id}/restore [post]
+func (s *service) Restore(ctx context.Context, id string) (int64, error) {
+	version, err := s.repository.Restore(ctx, id)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "account",
+			"function": "Restore",
+			"error":    err,
+			"id":       id,
+			"caller":   callerIdentity(ctx),
+		}).Error("restoring account failed")
+
+		return 0, err
+	}
+
+	s.audit(ctx, model.AuditActionRestore, id, nil)
+
+	return version, nil
+}
+
+// PurgeDeleted removes the accounts deleted before deletedBefore for good
+// and returns how many were removed.
+func (s *service) PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int, error) {
+	ids, err := s.repository.Purge(ctx, deletedBefore)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "account",
+			"function": "PurgeDeleted",
+			"error":    err,
+		}).Error("purging deleted accounts failed")
+
+		return 0, err
+	}
+
+	for _, id := range ids {
+		s.audit(ctx, model.AuditActionPurge, id, nil)
+	}
+
+	return len(ids), nil
+}
+
+// @Summary Reveal account secrets
+// @Description Retrieve an account with its secret fields in plaintext. Every reveal is audited
+// @Tags accounts
+// @Accept json
+// @Produce json
+// @Param id path string true "Account ID"
+// @Success 200 {object} RevealResponse "Account data with secrets"
+// @Failure 400 {object} httperror.Response "Bad Request"
+// @Failure 404 {object} httperror.Response "Not Found"
+// @Failure 500 {object} httperror.Response "Internal Server Error"
+// @Router /accounts/{id}/reveal [post]
+func (s *service) Reveal(ctx context.Context, id string) (model.Account, error) {
+	account, err := s.repository.GetByID(ctx, id)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "account",
+			"function": "Reveal",
+			"error":    err,
+			"id":       id,
+			"caller":   callerIdentity(ctx),
+		}).Error("getting account by id failed")
+
+		return model.Account{}, err
+	}
+
+	_, err = s.auditRepository.Create(ctx, newAuditRecord(ctx, model.AuditActionReveal, id, account.PresentSecrets()))
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "account",
+			"function": "Reveal",
+			"error":    err,
+			"id":       id,
+			"caller":   callerIdentity(ctx),
+		}).Error("recording reveal failed")
+
+		return model.Account{}, err
+	}
+
+	return account, nil
+}
+
+// @Summary Lease an account
+// @Description Atomically reserve a free account, optionally of a given type and status, for the caller until the lease expires
+// @Tags leases
+// @Accept json
+// @Produce json
+// @Param lease body LeaseRequest true "Lease filter and ttl in seconds"
+// @Success 200 {object} LeaseResponse "Lease and leased account"
+// @Failure 400 {object} httperror.Response "Bad Request"
+// @Failure 404 {object} httperror.Response "No account available"
+// @Failure 500 {object} httperror.Response "Internal Server Error"
+// @Router /accounts/lease [post]
+func (s *service) Lease(ctx context.Context, lease model.LeaseCreate) (model.Lease, model.Account, error) {
+	lease.Holder = callerActor(ctx)
+
+	leased, account, err := s.repository.Lease(ctx, lease)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "account",
+			"function": "Lease",
+			"error":    err,
+			"lease":    lease,
+			"caller":   callerIdentity(ctx),
+		}).Error("leasing account failed")
+
+		return model.Lease{}, model.Account{}, err
+	}
+	return leased, account, nil
+}
+
+// @Summary Renew a lease
+// @Description Extend a lease that has not expired yet
+// @Tags leases
+// @Accept json
+// @Produce json
+// @Param id path string true "Account ID"
+// @Param lease body RenewLeaseRequest true "Lease ID and new ttl in seconds"
+// @Success 200 {object} RenewLeaseResponse "Renewed lease"
+// @Failure 400 {object} httperror.Response "Bad Request"
+// @Failure 409 {object} httperror.Response "Lease is not held"
+// @Failure 500 {object} httperror.Response "Internal Server Error"
+// @Router /accounts/{id}/lease/renew [post]
+func (s *service) RenewLease(ctx context.Context, accountID string, leaseRenew model.LeaseRenew) (model.Lease, error) {
+	lease, err := s.repository.RenewLease(ctx, accountID, leaseRenew)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "account",
+			"function": "RenewLease",
+			"error":    err,
+			"id":       accountID,
+			"leaseID":  leaseRenew.LeaseID,
+			"caller":   callerIdentity(ctx),
+		}).Error("renewing lease failed")
+
+		return model.Lease{}, err
+	}
+	return lease, nil
+}
+
+// @Summary Release a lease
+// @Description Release a lease so the account can be leased again
+// @Tags leases
+// @Accept json
+// @Produce json
+// @Param id path string true "Account ID"
+// @Param lease body ReleaseLeaseRequest true "Lease ID"
+// @Success 200 {object} ReleaseLeaseResponse
+// @Failure 400 {object} httperror.Response "Bad Request"
+// @Failure 409 {object} httperror.Response "Lease is not held"
+// @Failure 500 {object} httperror.Response "Internal Server Error"
+// @Router /accounts/{id}/lease/release [post]
+func (s *service) ReleaseLease(ctx context.Context, accountID, leaseID string) error {
+	err := s.repository.ReleaseLease(ctx, accountID, leaseID)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "account",
+			"function": "ReleaseLease",
+			"error":    err,
+			"id":       accountID,
+			"leaseID":  leaseID,
+			"caller":   callerIdentity(ctx),
+		}).Error("releasing lease failed")
+
+		return err
+	}
 	return nil
 }
 
+// @Summary Re-wrap data keys
+// @Description Re-wrap the data keys of all accounts with the current master key
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Success 200 {object} RewrapKeysResponse
+// @Failure 500 {object} httperror.Response "Internal Server Error"
+// @Router /admin/keys/rewrap [post]
+func (s *service) RewrapKeys(ctx context.Context) (int, error) {
+	rewrapped, err := s.repository.Rewrap(ctx)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":   "account",
+			"function":  "RewrapKeys",
+			"error":     err,
+			"rewrapped": rewrapped,
+		}).Error("rewrapping data keys failed")
+
+		return rewrapped, err
+	}
+
+	rewrappedHistory, err := s.credentialHistoryRepository.Rewrap(ctx)
+	rewrapped += rewrappedHistory
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":   "account",
+			"function":  "RewrapKeys",
+			"error":     err,
+			"rewrapped": rewrapped,
+		}).Error("rewrapping credential history data keys failed")
+
+		return rewrapped, err
+	}
+	return rewrapped, nil
+}
+
 // @Summary Get data from Nginx
 // @Description Makes an HTTP GET request to Nginx and returns the response body as a string.
 // @Tags nginx
@@ -179,3 +641,941 @@ func (s *service) Nginx(ctx context.Context) (string, error) {
 
 	return res, nil
 }
+
+// @Summary Create accounts in a batch
+// @Description Create up to 5000 accounts in one transaction. In atomic mode a failing item fails the whole batch, in best_effort mode failures are reported per item
+// @Tags accounts
+// @Accept json
+// @Produce json
+// @Param accounts body BatchCreateRequest true "Accounts to create"
+// @Success 200 {object} BatchResponse "Result of every item"
+// @Failure 400 {object} httperror.Response "Bad Request"
+// @Failure 500 {object} httperror.Response "Internal Server Error"
+// @Router /accounts:batchCreate [post]
+func (s *service) BatchCreate(ctx context.Context, mode model.BatchMode, accountCreates []model.AccountCreate) ([]model.BatchResult, error) {
+	if err := model.ValidateBatchSize(len(accountCreates)); err != nil {
+		return nil, err
+	}
+
+	checkAccount := s.accountChecker(ctx)
+	rejected, err := checkBatch(mode, len(accountCreates), func(index int) error {
+		status, err := s.statusMachine.InitialStatus(accountCreates[index].Status)
+		if err != nil {
+			return err
+		}
+		accountCreates[index].Status = status
+		return checkAccount(accountCreates[index].Account(), "")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := runCheckedBatch(accountCreates, rejected, func(accountCreates []model.AccountCreate) ([]model.BatchResult, error) {
+		return s.repository.CreateBatch(ctx, accountCreates, mode)
+	})
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "account",
+			"function": "BatchCreate",
+			"error":    err,
+			"mode":     mode,
+			"size":     len(accountCreates),
+			"caller":   callerIdentity(ctx),
+		}).Error("creating accounts in batch failed")
+
+		return nil, err
+	}
+
+	for i, result := range results {
+		if result.Err == nil {
+			s.audit(ctx, model.AuditActionCreate, result.ID, accountCreates[i].SetFields())
+			s.recordTransition(ctx, model.StatusTransitionCreate{AccountID: result.ID, To: accountCreates[i].Status})
+		}
+	}
+
+	return results, nil
+}
+
+// @Summary Replace accounts in a batch
+// @Description Replace up to 5000 accounts, each at its expected version, in one transaction. In atomic mode a failing item fails the whole batch, in best_effort mode failures are reported per item
+// @Tags accounts
+// @Accept json
+// @Produce json
+// @Param accounts body BatchUpdateRequest true "Accounts to replace"
+// @Success 200 {object} BatchResponse "Result of every item"
+// @Failure 400 {object} httperror.Response "Bad Request"
+// @Failure 404 {object} httperror.Response "Not Found"
+// @Failure 412 {object} httperror.Response "Account has changed"
+// @Failure 500 {object} httperror.Response "Internal Server Error"
+// @Router /accounts:batchUpdate [post]
+func (s *service) BatchUpdate(ctx context.Context, mode model.BatchMode, accounts []model.Account) ([]model.BatchResult, error) {
+	if err := model.ValidateBatchSize(len(accounts)); err != nil {
+		return nil, err
+	}
+
+	// An item whose account cannot be read fails before the batch is
+	// stored, the audit needs the account before the change.
+	befores := make([]model.Account, len(accounts))
+	checkAccount := s.accountChecker(ctx)
+	rejected, err := checkBatch(mode, len(accounts), func(index int) error {
+		var err error
+		befores[index], err = s.repository.GetByID(ctx, accounts[index].ID.String())
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(befores[index], accounts[index].Version); err != nil {
+			return err
+		}
+		if err := s.statusMachine.CheckTransition(befores[index].Status, accounts[index].Status); err != nil {
+			return err
+		}
+		return checkAccount(accounts[index], befores[index].AccountType)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range accounts {
+		if _, ok := rejected[i]; ok {
+			continue
+		}
+		if err := s.recordCredentials(ctx, "BatchUpdate", befores[i], accounts[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	results, err := runCheckedBatch(accounts, rejected, func(accounts []model.Account) ([]model.BatchResult, error) {
+		return s.repository.UpdateBatch(ctx, accounts, mode)
+	})
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "account",
+			"function": "BatchUpdate",
+			"error":    err,
+			"mode":     mode,
+			"size":     len(accounts),
+			"caller":   callerIdentity(ctx),
+		}).Error("updating accounts in batch failed")
+
+		return nil, err
+	}
+
+	for i, result := range results {
+		if result.Err == nil {
+			s.audit(ctx, model.AuditActionUpdate, result.ID, model.ChangedFields(befores[i], accounts[i]))
+			s.recordTransition(ctx, model.StatusTransitionCreate{AccountID: result.ID, From: befores[i].Status, To: accounts[i].Status})
+		}
+	}
+
+	return results, nil
+}
+
+// @Summary Delete accounts in a batch
+// @Description Delete up to 5000 accounts, each at its expected version, in one transaction. In atomic mode a failing item fails the whole batch, in best_effort mode failures are reported per item
+// @Tags accounts
+// @Accept json
+// @Produce json
+// @Param accounts body BatchDeleteRequest true "Accounts to delete"
+// @Success 200 {object} BatchResponse "Result of every item"
+// @Failure 400 {object} httperror.Response "Bad Request"
+// @Failure 404 {object} httperror.Response "Not Found"
+// @Failure 412 {object} httperror.Response "Account has changed"
+// @Failure 500 {object} httperror.Response "Internal Server Error"
+// @Router /accounts:batchDelete [post]
+func (s *service) BatchDelete(ctx context.Context, mode model.BatchMode, accountVersions []model.AccountVersion) ([]model.BatchResult, error) {
+	if err := model.ValidateBatchSize(len(accountVersions)); err != nil {
+		return nil, err
+	}
+
+	results, err := s.repository.DeleteBatch(ctx, accountVersions, mode)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "account",
+			"function": "BatchDelete",
+			"error":    err,
+			"mode":     mode,
+			"size":     len(accountVersions),
+			"caller":   callerIdentity(ctx),
+		}).Error("deleting accounts in batch failed")
+
+		return nil, err
+	}
+
+	for _, result := range results {
+		if result.Err == nil {
+			s.audit(ctx, model.AuditActionDelete, result.ID, nil)
+		}
+	}
+
+	return results, nil
+}
+
+// @Summary Import accounts from CSV
+// @Description Create accounts from CSV lines. Columns come from the header line or the columns parameter, e.g. login,password,email,email_password with delimiter ":". Lines failing validation are reported and skipped
+// @Tags accounts
+// @Accept plain
+// @Produce json
+// @Param csv body string true "CSV lines"
+// @Param delimiter query string false "Field delimiter, a comma by default, \t for tab"
+// @Param columns query string false "Comma separated columns of the lines, - skips a column"
+// @Param header query bool false "Skip the first line when columns are given"
+// @Param dry_run query bool false "Only validate the lines"
+// @Success 200 {object} ImportResponse "Result of every line"
+// @Failure 400 {object} httperror.Response "Bad Request"
+// @Failure 500 {object} httperror.Response "Internal Server Error"
+// @Router /accounts/import [post]
+func (s *service) Import(ctx context.Context, options model.ImportOptions, body io.Reader) (model.ImportReport, error) {
+	report := model.ImportReport{DryRun: options.DryRun}
+	reader := model.NewAccountCSVReader(body, options)
+	checkAccount := s.accountChecker(ctx)
+
+	lines := make([]int, 0, model.MaxBatchSize)
+	accountCreates := make([]model.AccountCreate, 0, model.MaxBatchSize)
+	createBatch := func() error {
+		if len(accountCreates) == 0 {
+			return nil
+		}
+
+		results := make([]model.BatchResult, len(accountCreates))
+		if !options.DryRun {
+			var err error
+			results, err = s.repository.CreateBatch(ctx, accountCreates, model.BatchModeBestEffort)
+			if err != nil {
+				return err
+			}
+		}
+
+		for i, result := range results {
+			report.Lines = append(report.Lines, model.ImportLineResult{Line: lines[i], ID: result.ID, Err: result.Err})
+			if result.Err == nil && !options.DryRun {
+				s.audit(ctx, model.AuditActionCreate, result.ID, accountCreates[i].SetFields())
+				s.recordTransition(ctx, model.StatusTransitionCreate{AccountID: result.ID, To: accountCreates[i].Status})
+			}
+		}
+
+		lines = lines[:0]
+		accountCreates = accountCreates[:0]
+		return nil
+	}
+
+	for {
+		line, accountCreate, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err == nil {
+			accountCreate.Status, err = s.statusMachine.InitialStatus(accountCreate.Status)
+		}
+		if err == nil {
+			err = checkAccount(accountCreate.Account(), "")
+		}
+		if err != nil && line > 0 && errors.Is(err, model.ErrInvalidArgument) {
+			report.Lines = append(report.Lines, model.ImportLineResult{Line: line, Err: err})
+			continue
+		}
+		if err == nil {
+			lines = append(lines, line)
+			accountCreates = append(accountCreates, accountCreate)
+			if len(accountCreates) == model.MaxBatchSize {
+				err = createBatch()
+			}
+		}
+		if err != nil {
+			s.logger.WithFields(logrus.Fields{
+				"package":  "account",
+				"function": "Import",
+				"error":    err,
+				"line":     line,
+				"caller":   callerIdentity(ctx),
+			}).Error("importing accounts failed")
+
+			return model.ImportReport{}, err
+		}
+	}
+
+	if err := createBatch(); err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "account",
+			"function": "Import",
+			"error":    err,
+			"caller":   callerIdentity(ctx),
+		}).Error("importing accounts failed")
+
+		return model.ImportReport{}, err
+	}
+
+	sort.Slice(report.Lines, func(i, j int) bool {
+		return report.Lines[i].Line < report.Lines[j].Line
+	})
+	for _, line := range report.Lines {
+		if line.Err != nil {
+			report.Failed++
+		} else {
+			report.Imported++
+		}
+	}
+
+	return report, nil
+}
+
+// @Summary Export accounts as CSV
+// @Description Stream the accounts matching the filter as CSV, with their secrets in plaintext. Every exported account with secrets is audited before it is written, the export stops when the audit fails
+// @Tags accounts
+// @Accept json
+// @Produce plain
+// @Param format query string false "Export format, only csv"
+// @Param columns query string false "Comma separated columns, all by default"
+// @Param delimiter query string false "Field delimiter, a comma by default"
+// @Param header query bool false "Write a header line, true by default"
+// @Param account_type query string false "Account type"
+// @Param status query string false "Status"
+// @Param email query string false "Email, case insensitive"
+// @Param created_after query string false "Created at or after, RFC 3339"
+// @Param created_before query string false "Created before, RFC 3339"
+// @Param cookies_expiring_before query string false "Earliest cookie expiry before, RFC 3339"
+// @Param attribute query []string false "Non-secret attribute value as name:value, all must match" collectionFormat(multi)
+// @Param tag query []string false "Tag" collectionFormat(multi)
+// @Param tag_match query string false "Whether accounts must have all tags or any of them, all by default"
+// @Success 200 {string} string "CSV of the accounts"
+// @Failure 400 {object} httperror.Response "Bad Request"
+// @Failure 500 {object} httperror.Response "Internal Server Error"
+// @Router /accounts/export [get]
+func (s *service) Export(ctx context.Context, filter model.AccountFilter, columns []string, fn func(model.Account) error) error {
+	err := s.repository.Export(ctx, filter, func(account model.Account) error {
+		// Like a reveal the export is recorded before the secrets are
+		// written, an export that cannot be audited stops here.
+		if secrets := account.SecretColumns(columns); len(secrets) > 0 {
+			_, err := s.auditRepository.Create(ctx, newAuditRecord(ctx, model.AuditActionExport, account.ID.String(), secrets))
+			if err != nil {
+				return fmt.Errorf("error recording export of account with id %s: %w", account.ID, err)
+			}
+		}
+
+		return fn(account)
+	})
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "account",
+			"function": "Export",
+			"error":    err,
+			"filter":   filter,
+			"caller":   callerIdentity(ctx),
+		}).Error("exporting accounts failed")
+
+		return err
+	}
+
+	return nil
+}
+
+// @Summary Get the cookie jar of an account
+// @Description Retrieve the cookies of an account as browser extension JSON or Netscape cookies.txt. Every read is audited as a reveal
+// @Tags cookies
+// @Produce json,plain
+// @Param id path string true "Account ID"
+// @Param format query string false "Cookie format, json or netscape"
+// @Success 200 {array} object "Cookie jar"
+// @Header 200 {string} ETag "Account version"
+// @Failure 400 {object} httperror.Response "Bad Request"
+// @Failure 404 {object} httperror.Response "Not Found"
+// @Failure 409 {object} httperror.Response "Stored cookie is not a cookie jar"
+// @Failure 500 {object} httperror.Response "Internal Server Error"
+// @Router /accounts/{id}/cookies [get]
+func (s *service) GetCookies(ctx context.Context, id string) (model.CookieJar, int64, error) {
+	account, err := s.repository.GetByID(ctx, id)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "account",
+			"function": "GetCookies",
+			"error":    err,
+			"id":       id,
+			"caller":   callerIdentity(ctx),
+		}).Error("getting account by id failed")
+
+		return nil, 0, err
+	}
+
+	jar, err := model.DecodeCookieJar(account.Cookie)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	_, err = s.auditRepository.Create(ctx, newAuditRecord(ctx, model.AuditActionReveal, id, []string{"cookie"}))
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "account",
+			"function": "GetCookies",
+			"error":    err,
+			"id":       id,
+			"caller":   callerIdentity(ctx),
+		}).Error("recording reveal failed")
+
+		return nil, 0, err
+	}
+
+	return jar, account.Version, nil
+}
+
+// @Summary Replace the cookie jar of an account
+// @Description Replace the cookies of an account with a jar in browser extension JSON or Netscape cookies.txt
+// @Tags cookies
+// @Accept json,plain
+// @Produce json
+// @Param id path string true "Account ID"
+// @Param If-Match header string true "ETag of the account"
+// @Param format query string false "Cookie format, json or netscape"
+// @Param cookies body string true "Cookie jar"
+// @Success 200 {object} CookiesResponse
+// @Header 200 {string} ETag "Account version"
+// @Failure 400 {object} httperror.Response "Bad Request"
+// @Failure 404 {object} httperror.Response "Not Found"
+// @Failure 412 {object} httperror.Response "Account has changed"
+// @Failure 428 {object} httperror.Response "If-Match is missing"
+// @Failure 500 {object} httperror.Response "Internal Server Error"
+// @Router /accounts/{id}/cookies [put]
+func (s *service) ReplaceCookies(ctx context.Context, id string, version int64, jar model.CookieJar) (int64, error) {
+	return s.setCookies(ctx, "ReplaceCookies", id, version, func(model.Account) (model.CookieJar, error) {
+		return jar, nil
+	})
+}
+
+// @Summary Merge cookies into the cookie jar of an account
+// @Description Add cookies to an account, replacing those with the same domain, path and name. Expired cookies remove their match
+// @Tags cookies
+// @Accept json,plain
+// @Produce json
+// @Param id path string true "Account ID"
+// @Param If-Match header string true "ETag of the account"
+// @Param format query string false "Cookie format, json or netscape"
+// @Param cookies body string true "Cookies to merge"
+// @Success 200 {object} CookiesResponse
+// @Header 200 {string} ETag "Account version"
+// @Failure 400 {object} httperror.Response "Bad Request"
+// @Failure 404 {object} httperror.Response "Not Found"
+// @Failure 409 {object} httperror.Response "Stored cookie is not a cookie jar"
+// @Failure 412 {object} httperror.Response "Account has changed"
+// @Failure 428 {object} httperror.Response "If-Match is missing"
+// @Failure 500 {object} httperror.Response "Internal Server Error"
+// @Router /accounts/{id}/cookies [patch]
+func (s *service) MergeCookies(ctx context.Context, id string, version int64, jar model.CookieJar) (int64, error) {
+	return s.setCookies(ctx, "MergeCookies", id, version, func(before model.Account) (model.CookieJar, error) {
+		existing, err := model.DecodeCookieJar(before.Cookie)
+		if err != nil {
+			return nil, err
+		}
+		return existing.Merge(jar, time.Now()), nil
+	})
+}
+
+// setCookies stores the jar that jar returns for the account, if the
+// account is still at version.
+func (s *service) setCookies(ctx context.Context, function, id string, version int64, jar func(before model.Account) (model.CookieJar, error)) (int64, error) {
+	before, err := s.repository.GetByID(ctx, id)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "account",
+			"function": function,
+			"error":    err,
+			"id":       id,
+			"caller":   callerIdentity(ctx),
+		}).Error("getting account by id failed")
+
+		return 0, err
+	}
+
+	if err := checkVersion(before, version); err != nil {
+		return 0, err
+	}
+
+	cookies, err := jar(before)
+	if err != nil {
+		return 0, err
+	}
+
+	after := before
+	after.Cookie = cookies.String()
+	after.Version = version
+
+	if err := s.recordCredentials(ctx, function, before, after); err != nil {
+		return 0, err
+	}
+
+	err = s.repository.Update(ctx, after)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "account",
+			"function": function,
+			"error":    err,
+			"account":  after.Redacted(),
+			"caller":   callerIdentity(ctx),
+		}).Error("updating account failed")
+
+		return 0, err
+	}
+
+	s.audit(ctx, model.AuditActionUpdate, id, model.ChangedFields(before, after))
+
+	return version + 1, nil
+}
+
+// ExpireSessions marks the accounts whose cookies have expired as session
+// expired, as far as the status machine allows, and returns how many were
+// marked.
+func (s *service) ExpireSessions(ctx context.Context) (int, error) {
+	statusTransitions, err := s.repository.ExpireSessions(ctx, time.Now(), s.statusMachine.Blocking(model.AccountStatusSessionExpired))
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "account",
+			"function": "ExpireSessions",
+			"error":    err,
+		}).Error("expiring sessions failed")
+
+		return 0, err
+	}
+
+	for _, statusTransition := range statusTransitions {
+		s.audit(ctx, model.AuditActionUpdate, statusTransition.AccountID, []string{"status"})
+
+		statusTransition.Reason = "cookies expired"
+		s.recordTransition(ctx, statusTransition)
+	}
+
+	return len(statusTransitions), nil
+}
+
+// @Summary Get the status history of an account
+// @Description Retrieve every status change of an account with its time, reason and actor, oldest first
+// @Tags accounts
+// @Accept json
+// @Produce json
+// @Param id path string true "Account ID"
+// @Success 200 {object} StatusHistoryResponse
+// @Failure 400 {object} httperror.Response "Bad Request"
+// @Failure 404 {object} httperror.Response "Not Found"
+// @Failure 500 {object} httperror.Response "Internal Server Error"
+// @Router /accounts/{id}/status-history [get]
+func (s *service) StatusHistory(ctx context.Context, id string) ([]model.StatusTransition, error) {
+	if _, err := s.repository.GetByID(ctx, id); err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "account",
+			"function": "StatusHistory",
+			"error":    err,
+			"id":       id,
+		}).Error("getting account by id failed")
+
+		return nil, err
+	}
+
+	statusTransitions, err := s.statusHistoryRepository.GetByAccountID(ctx, id)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "account",
+			"function": "StatusHistory",
+			"error":    err,
+			"id":       id,
+		}).Error("getting status history failed")
+
+		return nil, err
+	}
+
+	return statusTransitions, nil
+}
+
+// @Summary Get the credential history of an account
+// @Description Retrieve the replaced versions of the secret fields of an account with secrets masked, oldest first
+// @Tags accounts
+// @Accept json
+// @Produce json
+// @Param id path string true "Account ID"
+// @Success 200 {object} CredentialHistoryResponse
+// @Failure 400 {object} httperror.Response "Bad Request"
+// @Failure 404 {object} httperror.Response "Not Found"
+// @Failure 500 {object} httperror.Response "Internal Server Error"
+// @Router /accounts/{id}/history [get]
+func (s *service) CredentialHistory(ctx context.Context, id string) ([]model.CredentialVersion, error) {
+	if _, err := s.repository.GetByID(ctx, id); err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "account",
+			"function": "CredentialHistory",
+			"error":    err,
+			"id":       id,
+		}).Error("getting account by id failed")
+
+		return nil, err
+	}
+
+	credentialVersions, err := s.credentialHistoryRepository.GetByAccountID(ctx, id)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "account",
+			"function": "CredentialHistory",
+			"error":    err,
+			"id":       id,
+		}).Error("getting credential history failed")
+
+		return nil, err
+	}
+
+	return credentialVersions, nil
+}
+
+// @Summary Roll back the credentials of an account
+// @Description Restore the secret fields of an account as they were at a version from its credential history. The credentials that are replaced are kept in the history too
+// @Tags accounts
+// @Accept json
+// @Produce json
+// @Param id path string true "Account ID"
+// @Param version path int true "Version to roll back to"
+// @Param If-Match header string true "ETag of the account"
+// @Success 200 {object} RollbackResponse
+// @Header 200 {string} ETag "New account version"
+// @Failure 400 {object} httperror.Response "Bad Request"
+// @Failure 404 {object} httperror.Response "Not Found"
+// @Failure 412 {object} httperror.Response "Account has changed"
+// @Failure 428 {object} httperror.Response "If-Match is missing"
+// @Failure 500 {object} httperror.Response "Internal Server Error"
+// @Router /accounts/{id}/rollback/{version} [post]
+func (s *service) Rollback(ctx context.Context, id string, version, target int64) (int64, error) {
+	before, err := s.repository.GetByID(ctx, id)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "account",
+			"function": "Rollback",
+			"error":    err,
+			"id":       id,
+			"caller":   callerIdentity(ctx),
+		}).Error("getting account by id failed")
+
+		return 0, err
+	}
+
+	if err := checkVersion(before, version); err != nil {
+		return 0, err
+	}
+
+	credentialVersion, err := s.credentialHistoryRepository.GetVersion(ctx, id, target)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "account",
+			"function": "Rollback",
+			"error":    err,
+			"id":       id,
+			"version":  target,
+			"caller":   callerIdentity(ctx),
+		}).Error("getting credential version failed")
+
+		return 0, err
+	}
+
+	after := credentialVersion.Apply(before)
+	after.Version = version
+	if err := s.accountChecker(ctx)(after, before.AccountType); err != nil {
+		return 0, err
+	}
+	if err := s.recordCredentials(ctx, "Rollback", before, after); err != nil {
+		return 0, err
+	}
+
+	err = s.repository.Update(ctx, after)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "account",
+			"function": "Rollback",
+			"error":    err,
+			"account":  after.Redacted(),
+			"caller":   callerIdentity(ctx),
+		}).Error("updating account failed")
+
+		return 0, err
+	}
+
+	s.audit(ctx, model.AuditActionRollback, id, model.ChangedFields(before, after))
+
+	return version + 1, nil
+}
+
+// @Summary Tag accounts
+// @Description Add tags to up to 5000 accounts. Nothing changes when one of the accounts does not exist
+// @Tags accounts
+// @Accept json
+// @Produce json
+// @Param tags body TagRequest true "Accounts and tags"
+// @Success 200 {object} TagResponse
+// @Failure 400 {object} httperror.Response "Bad Request"
+// @Failure 404 {object} httperror.Response "Not Found"
+// @Failure 500 {object} httperror.Response "Internal Server Error"
+// @Router /accounts:tag [post]
+func (s *service) Tag(ctx context.Context, accountTags model.AccountTags) error {
+	return s.changeTags(ctx, "Tag", accountTags, s.repository.Tag)
+}
+
+// @Summary Untag accounts
+// @Description Remove tags from up to 5000 accounts. Nothing changes when one of the accounts does not exist
+// @Tags accounts
+// @Accept json
+// @Produce json
+// @Param tags body TagRequest true "Accounts and tags"
+// @Success 200 {object} TagResponse
+// @Failure 400 {object} httperror.Response "Bad Request"
+// @Failure 404 {object} httperror.Response "Not Found"
+// @Failure 500 {object} httperror.Response "Internal Server Error"
+// @Router /accounts:untag [post]
+func (s *service) Untag(ctx context.Context, accountTags model.AccountTags) error {
+	return s.changeTags(ctx, "Untag", accountTags, s.repository.Untag)
+}
+
+func (s *service) changeTags(
+	ctx context.Context, function string, accountTags model.AccountTags, change func(context.Context, model.AccountTags) error) error {
+	if err := model.ValidateBatchSize(len(accountTags.AccountIDs)); err != nil {
+		return err
+	}
+	if err := model.ValidateTags(accountTags.Tags); err != nil {
+		return err
+	}
+
+	err := change(ctx, accountTags)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "account",
+			"function": function,
+			"error":    err,
+			"size":     len(accountTags.AccountIDs),
+			"tags":     accountTags.Tags,
+			"caller":   callerIdentity(ctx),
+		}).Error("changing account tags failed")
+
+		return err
+	}
+
+	for _, id := range accountTags.AccountIDs {
+		s.audit(ctx, model.AuditActionUpdate, id.String(), []string{"tags"})
+	}
+
+	return nil
+}
+
+// @Summary Get all tags
+// @Description Retrieve every tag with the number of accounts that have it
+// @Tags accounts
+// @Accept json
+// @Produce json
+// @Success 200 {object} GetTagsResponse
+// @Failure 500 {object} httperror.Response "Internal Server Error"
+// @Router /tags [get]
+func (s *service) GetTags(ctx context.Context) ([]model.TagCount, error) {
+	tagCounts, err := s.repository.Tags(ctx)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "account",
+			"function": "GetTags",
+			"error":    err,
+		}).Error("getting tags failed")
+
+		return nil, err
+	}
+	return tagCounts, nil
+}
+
+// recordTransition records a status change that has already happened, so
+// like audit it only logs a failure. Unchanged statuses are not recorded.
+// The reason and actor default to those of the request.
+func (s *service) recordTransition(ctx context.Context, statusTransition model.StatusTransitionCreate) {
+	if statusTransition.From == statusTransition.To {
+		return
+	}
+	if statusTransition.Reason == "" {
+		statusTransition.Reason = statusReasonFromContext(ctx)
+	}
+	if statusTransition.Actor == "" {
+		statusTransition.Actor = callerActor(ctx)
+	}
+
+	_, err := s.statusHistoryRepository.Create(ctx, statusTransition)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "account",
+			"function": "recordTransition",
+			"error":    err,
+			"id":       statusTransition.AccountID,
+			"caller":   callerIdentity(ctx),
+		}).Error("recording status transition failed")
+	}
+}
+
+// recordCredentials keeps the secret fields of before in the credential
+// history when the update to after changes them. It runs before the update,
+// which must not happen when the replaced secrets could not be kept. Should
+// the update fail after all, the version holds what the account had then
+// and recording it again is a no-op.
+func (s *service) recordCredentials(ctx context.Context, function string, before, after model.Account) error {
+	if !model.CredentialsChanged(before, after) {
+		return nil
+	}
+
+	credentialVersion := model.NewCredentialVersion(before)
+	credentialVersion.Actor = callerActor(ctx)
+
+	err := s.credentialHistoryRepository.Create(ctx, credentialVersion)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "account",
+			"function": function,
+			"error":    err,
+			"id":       credentialVersion.AccountID,
+			"caller":   callerIdentity(ctx),
+		}).Error("recording credential version failed")
+
+		return err
+	}
+
+	return nil
+}
+
+// accountChecker returns a check of the attributes of accounts and of the
+// accounts against the definition of their type, which is looked up once
+// per check. A type that is not registered is accepted only when the
+// account already had it, so that accounts stored before the registry can
+// still be changed.
+func (s *service) accountChecker(ctx context.Context) func(account model.Account, previousType string) error {
+	accountTypes := make(map[string]model.AccountType)
+
+	return func(account model.Account, previousType string) error {
+		if err := account.Attributes.Validate(); err != nil {
+			return err
+		}
+		if account.AccountType == "" {
+			return nil
+		}
+
+		accountType, ok := accountTypes[account.AccountType]
+		if !ok {
+			var err error
+			accountType, err = s.accountTypeRepository.GetByName(ctx, account.AccountType)
+			if errors.Is(err, model.ErrNotFound) {
+				if account.AccountType == previousType {
+					return nil
+				}
+				return model.Errorf(model.ErrInvalidArgument, "unknown account type %s", account.AccountType)
+			}
+			if err != nil {
+				s.logger.WithFields(logrus.Fields{
+					"package":      "account",
+					"function":     "accountChecker",
+					"error":        err,
+					"account_type": account.AccountType,
+					"caller":       callerIdentity(ctx),
+				}).Error("getting account type failed")
+
+				return err
+			}
+			accountTypes[account.AccountType] = accountType
+		}
+
+		return accountType.ValidateAccount(account)
+	}
+}
+
+// audit records a completed mutation. The mutation has already happened, so
+// a failure is only logged instead of being reported to the caller.
+func (s *service) audit(ctx context.Context, action, accountID string, fields []string) {
+	_, err := s.auditRepository.Create(ctx, newAuditRecord(ctx, action, accountID, fields))
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "account",
+			"function": "audit",
+			"error":    err,
+			"action":   action,
+			"id":       accountID,
+			"caller":   callerIdentity(ctx),
+		}).Error("recording audit record failed")
+	}
+}
+
+func newAuditRecord(ctx context.Context, action, accountID string, fields []string) model.AuditRecordCreate {
+	auditRecord := model.AuditRecordCreate{
+		Action:    action,
+		AccountID: accountID,
+		Fields:    fields,
+	}
+
+	auditRecord.Actor = callerActor(ctx)
+	auditRecord.Caller = callerIdentity(ctx)
+
+	return auditRecord
+}
+
+func callerActor(ctx context.Context) string {
+	caller, ok := auth.CallerFromContext(ctx)
+	if !ok {
+		return ""
+	}
+	return caller.Actor()
+}
+
+func callerIdentity(ctx context.Context) string {
+	caller, ok := auth.CallerFromContext(ctx)
+	if !ok {
+		return ""
+	}
+	return caller.Identity()
+}
+
+// checkVersion fails with a precondition error when the account is no
+// longer at the version the caller expects, before the change is validated
+// against an account the caller has not seen.
+func checkVersion(before model.Account, version int64) error {
+	if before.Version != version {
+		return model.Errorf(model.ErrPreconditionFailed, "account with id %s is at version %d, not %d", before.ID, before.Version, version)
+	}
+	return nil
+}
+
+// checkBatch runs check on every item of a batch before it is stored. In
+// atomic mode the first failing item fails the batch, in best effort mode
+// the failures are returned by index.
+func checkBatch(mode model.BatchMode, size int, check func(index int) error) (map[int]error, error) {
+	rejected := make(map[int]error)
+	for index := 0; index < size; index++ {
+		if err := check(index); err != nil {
+			if mode.Atomic() {
+				return nil, model.BatchItemError(index, err)
+			}
+			rejected[index] = err
+		}
+	}
+	return rejected, nil
+}
+
+// runCheckedBatch runs the batch on the items that were not rejected and
+// returns the results of all items, in order.
+func runCheckedBatch[T any](items []T, rejected map[int]error, run func([]T) ([]model.BatchResult, error)) ([]model.BatchResult, error) {
+	if len(rejected) == 0 {
+		return run(items)
+	}
+
+	accepted := make([]T, 0, len(items)-len(rejected))
+	for index, item := range items {
+		if _, ok := rejected[index]; !ok {
+			accepted = append(accepted, item)
+		}
+	}
+
+	var acceptedResults []model.BatchResult
+	if len(accepted) > 0 {
+		var err error
+		acceptedResults, err = run(accepted)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	results := make([]model.BatchResult, len(items))
+	for index := range results {
+		if err, ok := rejected[index]; ok {
+			results[index] = model.BatchResult{Err: err}
+			continue
+		}
+		results[index], acceptedResults = acceptedResults[0], acceptedResults[1:]
+	}
+	return results, nil
+}
@@ -0,0 +1,2027 @@
+package account_test
+
+import (
+	"account_storage/internal/app/encryption/encryptiontest"
+	"account_storage/internal/app/store"
+	"account_storage/internal/app/store/localstore"
+	"account_storage/internal/app/store/migrate"
+	"account_storage/internal/app/store/sqlitestore"
+	"account_storage/internal/app/store/sqlstore"
+	"account_storage/migrations"
+	"account_storage/pkg/httperror"
+	"account_storage/pkg/model"
+	"account_storage/pkg/model/account"
+	"account_storage/pkg/model/accounttype"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+// testDatabaseURLEnv names a Postgres database the sql store tests run
+// against, its public schema is dropped first. They are skipped without it.
+const testDatabaseURLEnv = "ACCOUNTS_STORAGE_TEST_DATABASE_URL"
+
+type testStore struct {
+	name string
+	open func(t *testing.T, logger *logrus.Logger) store.Store
+}
+
+var testStores = []testStore{
+	{
+		name: "local",
+		open: func(t *testing.T, logger *logrus.Logger) store.Store {
+			localStore, err := localstore.New(logger, encryptiontest.NewEnvelope(t), localstore.Options{})
+			if err != nil {
+				t.Fatalf("opening local store: %v", err)
+			}
+			return localStore
+		},
+	},
+	{
+		// wal journals every change, so the tests also run the local store
+		// code that writes the write-ahead log.
+		name: "wal",
+		open: func(t *testing.T, logger *logrus.Logger) store.Store {
+			localStore, err := localstore.New(logger, encryptiontest.NewEnvelope(t), localstore.Options{Path: t.TempDir(), Fsync: localstore.FsyncNever})
+			if err != nil {
+				t.Fatalf("opening local store: %v", err)
+			}
+			t.Cleanup(func() { localStore.Close() })
+			return localStore
+		},
+	},
+	{
+		name: "sqlite",
+		open: func(t *testing.T, logger *logrus.Logger) store.Store {
+			db, err := sqlitestore.Open(context.Background(), filepath.Join(t.TempDir(), "accounts.db"))
+			if err != nil {
+				t.Fatalf("opening sqlite store: %v", err)
+			}
+			t.Cleanup(func() { db.Close() })
+			migrateTestDB(t, db, migrations.DialectSQLite, logger)
+			return sqlitestore.New(db, logger, encryptiontest.NewEnvelope(t))
+		},
+	},
+	{
+		name: "sql",
+		open: func(t *testing.T, logger *logrus.Logger) store.Store {
+			databaseURL := os.Getenv(testDatabaseURLEnv)
+			if databaseURL == "" {
+				t.Skipf("%s is not set", testDatabaseURLEnv)
+			}
+			db, err := sql.Open("postgres", databaseURL)
+			if err != nil {
+				t.Fatalf("opening sql store: %v", err)
+			}
+			t.Cleanup(func() { db.Close() })
+			migrateTestDB(t, db, migrations.DialectPostgres, logger)
+			return sqlstore.New(db, logger, encryptiontest.NewEnvelope(t))
+		},
+	},
+}
+
+// migrateTestDB applies the migrations of dialect, on Postgres to a fresh
+// public schema.
+func migrateTestDB(t *testing.T, db *sql.DB, dialect string, logger *logrus.Logger) {
+	if dialect == migrations.DialectPostgres {
+		if _, err := db.Exec("DROP SCHEMA public CASCADE; CREATE SCHEMA public"); err != nil {
+			t.Fatalf("resetting schema: %v", err)
+		}
+	}
+
+	migrator, err := migrate.New(db, dialect, logger)
+	if err != nil {
+		t.Fatalf("loading migrations: %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("migrating: %v", err)
+	}
+}
+
+// testStatusMachine is the status machine of the example config.
+var testStatusMachine = model.StatusMachine{
+	Initial: "new",
+	Transitions: map[string][]string{
+		"new":                             {"warming", "active", "banned"},
+		"warming":                         {"active", "banned"},
+		"active":                          {"limited", "banned", model.AccountStatusSessionExpired},
+		"limited":                         {"active", "banned"},
+		model.AccountStatusSessionExpired: {"active", "banned"},
+		"banned":                          {},
+	},
+}
+
+// newTestService returns a service with free-form statuses.
+func newTestService(t *testing.T, testStore testStore) account.Service {
+	return newTestServiceWithStatusMachine(t, testStore, model.StatusMachine{})
+}
+
+func newTestServiceWithStatusMachine(t *testing.T, testStore testStore, statusMachine model.StatusMachine) account.Service {
+	_, service := openTestService(t, testStore, statusMachine)
+	return service
+}
+
+// openTestService returns the service with its store, for tests that set up
+// more than accounts.
+func openTestService(t *testing.T, testStore testStore, statusMachine model.StatusMachine) (store.Store, account.Service) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	store := testStore.open(t, logger)
+	return store, account.NewService(store.Account(), store.Audit(), store.StatusHistory(), store.AccountType(), store.CredentialHistory(), statusMachine, logger)
+}
+
+func testAccountCreate() model.AccountCreate {
+	return model.AccountCreate{
+		Name:          "account",
+		Login:         "login",
+		Password:      "password",
+		Email:         "account@example.com",
+		EmailPassword: "email password",
+		Status:        "active",
+	}
+}
+
+func TestLeaseOrder(t *testing.T) {
+	for _, testStore := range testStores {
+		t.Run(testStore.name, func(t *testing.T) {
+			service := newTestService(t, testStore)
+			ctx := context.Background()
+
+			var ids []string
+			for i := 0; i < 2; i++ {
+				id, err := service.Create(ctx, testAccountCreate())
+				if err != nil {
+					t.Fatalf("creating account: %v", err)
+				}
+				ids = append(ids, id)
+			}
+
+			lease := func(ttl int) (string, error) {
+				_, leased, err := service.Lease(ctx, model.LeaseCreate{TTL: ttl})
+				return leased.ID.String(), err
+			}
+
+			// The oldest account goes first, accounts that were never leased
+			// go before expired leases.
+			if got, err := lease(1); err != nil || got != ids[0] {
+				t.Fatalf("leased %s, %v, want %s", got, err, ids[0])
+			}
+			time.Sleep(1100 * time.Millisecond)
+			if got, err := lease(0); err != nil || got != ids[1] {
+				t.Fatalf("leased %s, %v, want %s", got, err, ids[1])
+			}
+			if got, err := lease(0); err != nil || got != ids[0] {
+				t.Fatalf("leased %s, %v, want expired %s", got, err, ids[0])
+			}
+			if _, err := lease(0); !errors.Is(err, model.ErrNoAccountAvailable) {
+				t.Errorf("error = %v, want %v", err, model.ErrNoAccountAvailable)
+			}
+		})
+	}
+}
+
+func TestConcurrentLease(t *testing.T) {
+	const accounts, lessees = 20, 50
+
+	for _, testStore := range testStores {
+		t.Run(testStore.name, func(t *testing.T) {
+			service := newTestService(t, testStore)
+			ctx := context.Background()
+
+			for i := 0; i < accounts; i++ {
+				if _, err := service.Create(ctx, testAccountCreate()); err != nil {
+					t.Fatalf("creating account: %v", err)
+				}
+			}
+
+			var mutex sync.Mutex
+			leasedBy := make(map[string]int)
+			var wait sync.WaitGroup
+			for i := 0; i < lessees; i++ {
+				wait.Add(1)
+				go func(lessee int) {
+					defer wait.Done()
+
+					_, leased, err := service.Lease(ctx, model.LeaseCreate{})
+					if errors.Is(err, model.ErrNoAccountAvailable) {
+						return
+					}
+					if err != nil {
+						t.Errorf("leasing account: %v", err)
+						return
+					}
+
+					mutex.Lock()
+					defer mutex.Unlock()
+					if other, ok := leasedBy[leased.ID.String()]; ok {
+						t.Errorf("account %s leased by %d and %d", leased.ID, other, lessee)
+					}
+					leasedBy[leased.ID.String()] = lessee
+				}(i)
+			}
+			wait.Wait()
+
+			if len(leasedBy) != accounts {
+				t.Errorf("leased %d accounts, want %d", len(leasedBy), accounts)
+			}
+		})
+	}
+}
+
+// allPages follows the cursors from filter and returns the names of every
+// account in order and how many pages there were.
+func allPages(t *testing.T, service account.Service, filter model.AccountFilter) ([]string, int) {
+	ctx := context.Background()
+
+	var names []string
+	for pages := 1; ; pages++ {
+		page, err := service.GetAll(ctx, filter)
+		if err != nil {
+			t.Fatalf("getting accounts: %v", err)
+		}
+		for _, account := range page.Accounts {
+			names = append(names, account.Name)
+		}
+		if page.NextCursor == "" {
+			return names, pages
+		}
+		if pages > 10 {
+			t.Fatalf("more than %d pages", pages)
+		}
+		filter.Cursor = page.NextCursor
+	}
+}
+
+func TestGetAll(t *testing.T) {
+	// Created in this order, a millisecond apart.
+	created := []model.AccountCreate{
+		{Name: "c", AccountType: "mail", Status: "active", Email: "c@example.com"},
+		{Name: "a", AccountType: "mail", Status: "banned", Email: "a@example.com"},
+		{Name: "e", AccountType: "social", Status: "active", Email: "e@example.com"},
+		{Name: "b", AccountType: "social", Status: "active", Email: "b@example.com"},
+		{Name: "d", AccountType: "mail", Status: "active", Email: "d@example.com"},
+	}
+
+	tests := []struct {
+		name      string
+		filter    model.AccountFilter
+		want      []string
+		wantPages int
+	}{
+		{
+			name:      "created at in one page",
+			filter:    model.AccountFilter{},
+			want:      []string{"c", "a", "e", "b", "d"},
+			wantPages: 1,
+		},
+		{
+			name:      "created at across pages",
+			filter:    model.AccountFilter{Limit: 2},
+			want:      []string{"c", "a", "e", "b", "d"},
+			wantPages: 3,
+		},
+		{
+			name:      "page size divides the accounts",
+			filter:    model.AccountFilter{Limit: 5},
+			want:      []string{"c", "a", "e", "b", "d"},
+			wantPages: 1,
+		},
+		{
+			name:      "created at descending",
+			filter:    model.AccountFilter{Sort: model.AccountSort{Field: model.SortByCreatedAt, Descending: true}, Limit: 2},
+			want:      []string{"d", "b", "e", "a", "c"},
+			wantPages: 3,
+		},
+		{
+			name:      "name across pages",
+			filter:    model.AccountFilter{Sort: model.AccountSort{Field: model.SortByName}, Limit: 2},
+			want:      []string{"a", "b", "c", "d", "e"},
+			wantPages: 3,
+		},
+		{
+			name:      "name descending",
+			filter:    model.AccountFilter{Sort: model.AccountSort{Field: model.SortByName, Descending: true}, Limit: 3},
+			want:      []string{"e", "d", "c", "b", "a"},
+			wantPages: 2,
+		},
+		{
+			name:      "status and account type",
+			filter:    model.AccountFilter{AccountType: "mail", Status: "active", Limit: 1},
+			want:      []string{"c", "d"},
+			wantPages: 2,
+		},
+		{
+			name:      "email ignores case",
+			filter:    model.AccountFilter{Email: "B@Example.com"},
+			want:      []string{"b"},
+			wantPages: 1,
+		},
+		{
+			name:      "nothing matches",
+			filter:    model.AccountFilter{Status: "limited"},
+			want:      nil,
+			wantPages: 1,
+		},
+	}
+
+	for _, testStore := range testStores {
+		t.Run(testStore.name, func(t *testing.T) {
+			store, service := openTestService(t, testStore, model.StatusMachine{})
+			ctx := context.Background()
+
+			for _, name := range []string{"mail", "social"} {
+				if err := store.AccountType().Create(ctx, model.AccountType{Name: name}); err != nil {
+					t.Fatalf("creating account type: %v", err)
+				}
+			}
+			for _, accountCreate := range created {
+				if _, err := service.Create(ctx, accountCreate); err != nil {
+					t.Fatalf("creating account: %v", err)
+				}
+				time.Sleep(time.Millisecond)
+			}
+
+			for _, test := range tests {
+				t.Run(test.name, func(t *testing.T) {
+					got, pages := allPages(t, service, test.filter)
+					if !reflect.DeepEqual(got, test.want) {
+						t.Errorf("accounts = %v, want %v", got, test.want)
+					}
+					if pages != test.wantPages {
+						t.Errorf("pages = %d, want %d", pages, test.wantPages)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestGetAllCursor(t *testing.T) {
+	byName := model.AccountSort{Field: model.SortByName}
+
+	for _, testStore := range testStores {
+		t.Run(testStore.name, func(t *testing.T) {
+			service := newTestService(t, testStore)
+			ctx := context.Background()
+
+			created := make([]time.Time, 0, 3)
+			for _, name := range []string{"a", "c", "e"} {
+				if _, err := service.Create(ctx, model.AccountCreate{Name: name}); err != nil {
+					t.Fatalf("creating account: %v", err)
+				}
+				created = append(created, time.Now())
+				time.Sleep(time.Millisecond)
+			}
+
+			first, err := service.GetAll(ctx, model.AccountFilter{Sort: byName, Limit: 2})
+			if err != nil {
+				t.Fatalf("getting accounts: %v", err)
+			}
+
+			// A page continues after the last account of the previous one,
+			// accounts created in between show up where they sort.
+			for _, name := range []string{"b", "d"} {
+				if _, err := service.Create(ctx, model.AccountCreate{Name: name}); err != nil {
+					t.Fatalf("creating account: %v", err)
+				}
+			}
+			rest, _ := allPages(t, service, model.AccountFilter{Sort: byName, Limit: 2, Cursor: first.NextCursor})
+			if want := []string{"d", "e"}; !reflect.DeepEqual(rest, want) {
+				t.Errorf("accounts after cursor = %v, want %v", rest, want)
+			}
+
+			ranged, _ := allPages(t, service, model.AccountFilter{CreatedAfter: created[0], CreatedBefore: created[2]})
+			if want := []string{"c", "e"}; !reflect.DeepEqual(ranged, want) {
+				t.Errorf("accounts created in range = %v, want %v", ranged, want)
+			}
+
+			_, err = service.GetAll(ctx, model.AccountFilter{Cursor: first.NextCursor})
+			if !errors.Is(err, model.ErrInvalidCursor) {
+				t.Errorf("cursor for another sort: error = %v, want %v", err, model.ErrInvalidCursor)
+			}
+			_, err = service.GetAll(ctx, model.AccountFilter{Sort: byName, Cursor: "not a cursor"})
+			if !errors.Is(err, model.ErrInvalidCursor) {
+				t.Errorf("malformed cursor: error = %v, want %v", err, model.ErrInvalidCursor)
+			}
+		})
+	}
+}
+
+func TestErrorStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	gin.DefaultWriter = io.Discard
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		ifMatch    string
+		wantStatus int
+		wantCode   string
+	}{
+		{"existing account", http.MethodGet, "/accounts/{id}", "", "", http.StatusOK, ""},
+		{"missing account", http.MethodGet, "/accounts/" + uuid.NewString(), "", "", http.StatusNotFound, model.CodeNotFound},
+		{"malformed id", http.MethodGet, "/accounts/not-a-uuid", "", "", http.StatusBadRequest, model.CodeInvalidArgument},
+		{"delete missing account", http.MethodDelete, "/accounts/" + uuid.NewString(), "", `"1"`, http.StatusNotFound, model.CodeNotFound},
+		{"malformed body", http.MethodPost, "/accounts", "{", "", http.StatusBadRequest, model.CodeInvalidArgument},
+		{"unknown sort", http.MethodGet, "/accounts?sort=password", "", "", http.StatusBadRequest, model.CodeInvalidArgument},
+		{"malformed cursor", http.MethodGet, "/accounts?cursor=garbage", "", "", http.StatusBadRequest, model.CodeInvalidArgument},
+		{"no account to lease", http.MethodPost, "/accounts/lease", `{"lease": {"account_type": "none"}}`, "", http.StatusNotFound, model.CodeNotFound},
+		{"lease not held", http.MethodPost, "/accounts/{id}/lease/renew", `{"lease": {"lease_id": "` + uuid.NewString() + `"}}`, "", http.StatusConflict, model.CodeConflict},
+		{"update without If-Match", http.MethodPut, "/accounts/{id}", `{"account": {"name": "x"}}`, "", http.StatusPreconditionRequired, model.CodePreconditionRequired},
+		{"patch at a stale version", http.MethodPatch, "/accounts/{id}", `{"account": {"name": "x"}}`, `"7"`, http.StatusPreconditionFailed, model.CodePreconditionFailed},
+		{"delete at a stale version", http.MethodDelete, "/accounts/{id}", "", `"7"`, http.StatusPreconditionFailed, model.CodePreconditionFailed},
+		{"malformed If-Match", http.MethodPatch, "/accounts/{id}", `{}`, "W/x", http.StatusPreconditionFailed, model.CodePreconditionFailed},
+		{"rollback without If-Match", http.MethodPost, "/accounts/{id}/rollback/1", "", "", http.StatusPreconditionRequired, model.CodePreconditionRequired},
+		{"rollback at a stale version", http.MethodPost, "/accounts/{id}/rollback/1", "", `"7"`, http.StatusPreconditionFailed, model.CodePreconditionFailed},
+	}
+
+	for _, testStore := range testStores {
+		t.Run(testStore.name, func(t *testing.T) {
+			service := newTestService(t, testStore)
+			id, err := service.Create(context.Background(), testAccountCreate())
+			if err != nil {
+				t.Fatalf("creating account: %v", err)
+			}
+			logger := logrus.New()
+			logger.SetOutput(io.Discard)
+			handler := account.NewGinService(account.MakeEndpoints(service), nil, logger, func(c *gin.Context) { c.Next() })
+
+			for _, test := range tests {
+				t.Run(test.name, func(t *testing.T) {
+					path := strings.ReplaceAll(test.path, "{id}", id)
+					request := httptest.NewRequest(test.method, path, strings.NewReader(test.body))
+					if test.ifMatch != "" {
+						request.Header.Set("If-Match", test.ifMatch)
+					}
+					recorder := httptest.NewRecorder()
+					handler.ServeHTTP(recorder, request)
+
+					if recorder.Code != test.wantStatus {
+						t.Errorf("status = %d, want %d: %s", recorder.Code, test.wantStatus, recorder.Body)
+					}
+					if test.wantCode == "" {
+						return
+					}
+					var response httperror.Response
+					if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+						t.Fatalf("decoding error response: %v", err)
+					}
+					if response.Error.Code != test.wantCode || response.Error.Message == "" {
+						t.Errorf("error = %+v, want code %s", response.Error, test.wantCode)
+					}
+				})
+			}
+		})
+	}
+}
+
+// comparable returns the fields of account that PATCH and PUT change.
+func comparable(account model.Account) model.AccountUpdate {
+	return model.AccountUpdate{
+		Name:                  account.Name,
+		AccountType:           account.AccountType,
+		Login:                 account.Login,
+		Password:              account.Password,
+		Email:                 account.Email,
+		EmailPassword:         account.EmailPassword,
+		RecoveryEmail:         account.RecoveryEmail,
+		RecoveryEmailPassword: account.RecoveryEmailPassword,
+		Cookie:                account.Cookie,
+		Status:                account.Status,
+		Attributes:            account.Attributes,
+	}
+}
+
+func TestPatchAndUpdate(t *testing.T) {
+	created := testAccountCreate()
+
+	tests := []struct {
+		name string
+		// patch is sent as a merge patch when set, update replaces the
+		// account otherwise.
+		patch   string
+		update  model.AccountUpdate
+		want    func(want *model.AccountUpdate)
+		wantErr error
+	}{
+		{
+			name:  "patch changes the given fields only",
+			patch: `{"account": {"name": "renamed", "password": "new password"}}`,
+			want: func(want *model.AccountUpdate) {
+				want.Name = "renamed"
+				want.Password = "new password"
+			},
+		},
+		{
+			name:  "patch keeps omitted fields",
+			patch: `{}`,
+			want:  func(want *model.AccountUpdate) {},
+		},
+		{
+			name:  "patch clears fields set to null",
+			patch: `{"account": {"email": null, "emailPassword": null}}`,
+			want: func(want *model.AccountUpdate) {
+				want.Email = ""
+				want.EmailPassword = ""
+			},
+		},
+		{
+			name:  "patch sets attributes",
+			patch: `{"account": {"attributes": {"pin": {"type": "number", "value": "12", "secret": true}}}}`,
+			want: func(want *model.AccountUpdate) {
+				want.Attributes = model.Attributes{"pin": {Type: model.AttributeTypeNumber, Value: "12", Secret: true}}
+			},
+		},
+		{
+			name:    "patch rejects invalid attributes",
+			patch:   `{"account": {"attributes": {"pin": {"type": "number", "value": "twelve"}}}}`,
+			wantErr: model.ErrInvalidArgument,
+		},
+		{
+			name:    "patch rejects unknown fields",
+			patch:   `{"account": {"created_at": "2024-01-01T00:00:00Z"}}`,
+			wantErr: model.ErrInvalidArgument,
+		},
+		{
+			name:    "patch rejects malformed json",
+			patch:   `{"account":`,
+			wantErr: model.ErrInvalidArgument,
+		},
+		{
+			name: "update replaces every field",
+			update: model.AccountUpdate{
+				Name:          "replaced",
+				Login:         "new login",
+				RecoveryEmail: "recovery@example.com",
+				Status:        "active",
+			},
+			want: func(want *model.AccountUpdate) {
+				*want = model.AccountUpdate{
+					Name:          "replaced",
+					Login:         "new login",
+					RecoveryEmail: "recovery@example.com",
+					Status:        "active",
+				}
+			},
+		},
+		{
+			name:   "update clears omitted fields",
+			update: model.AccountUpdate{Name: "account"},
+			want: func(want *model.AccountUpdate) {
+				*want = model.AccountUpdate{Name: "account"}
+			},
+		},
+	}
+
+	for _, testStore := range testStores {
+		t.Run(testStore.name, func(t *testing.T) {
+			service := newTestService(t, testStore)
+			ctx := context.Background()
+
+			for _, test := range tests {
+				t.Run(test.name, func(t *testing.T) {
+					id, err := service.Create(ctx, created)
+					if err != nil {
+						t.Fatalf("creating account: %v", err)
+					}
+					before, err := service.GetByID(ctx, id)
+					if err != nil {
+						t.Fatalf("getting account: %v", err)
+					}
+
+					var version int64
+					if test.patch != "" {
+						version, err = service.Patch(ctx, id, before.Version, []byte(test.patch))
+					} else {
+						version, err = service.Update(ctx, before.Replace(test.update))
+					}
+					if test.wantErr != nil {
+						if !errors.Is(err, test.wantErr) {
+							t.Errorf("error = %v, want %v", err, test.wantErr)
+						}
+						return
+					}
+					if err != nil {
+						t.Fatalf("changing account: %v", err)
+					}
+
+					after, err := service.GetByID(ctx, id)
+					if err != nil {
+						t.Fatalf("getting account: %v", err)
+					}
+
+					want := comparable(before)
+					test.want(&want)
+					if got := comparable(after); !reflect.DeepEqual(got, want) {
+						t.Errorf("account = %+v, want %+v", got, want)
+					}
+					if version != before.Version+1 || after.Version != version {
+						t.Errorf("version = %d, stored %d, want %d", version, after.Version, before.Version+1)
+					}
+					if !after.CreatedAt.Equal(before.CreatedAt) {
+						t.Errorf("created at = %v, want %v", after.CreatedAt, before.CreatedAt)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestStaleVersion(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(ctx context.Context, service account.Service, stale model.Account) error
+	}{
+		{
+			name: "update",
+			change: func(ctx context.Context, service account.Service, stale model.Account) error {
+				_, err := service.Update(ctx, stale.Replace(model.AccountUpdate{Name: "stale"}))
+				return err
+			},
+		},
+		{
+			name: "patch",
+			change: func(ctx context.Context, service account.Service, stale model.Account) error {
+				// The patch is invalid too, the version is checked first.
+				_, err := service.Patch(ctx, stale.ID.String(), stale.Version, []byte(`{"account": {"unknown": 1}}`))
+				return err
+			},
+		},
+		{
+			name: "merge cookies",
+			change: func(ctx context.Context, service account.Service, stale model.Account) error {
+				_, err := service.MergeCookies(ctx, stale.ID.String(), stale.Version, model.CookieJar{{Domain: "example.com", Path: "/", Name: "stale"}})
+				return err
+			},
+		},
+		{
+			name: "delete",
+			change: func(ctx context.Context, service account.Service, stale model.Account) error {
+				return service.Delete(ctx, stale.ID.String(), stale.Version)
+			},
+		},
+	}
+
+	for _, testStore := range testStores {
+		t.Run(testStore.name, func(t *testing.T) {
+			service := newTestService(t, testStore)
+			ctx := context.Background()
+
+			for _, test := range tests {
+				t.Run(test.name, func(t *testing.T) {
+					id, err := service.Create(ctx, testAccountCreate())
+					if err != nil {
+						t.Fatalf("creating account: %v", err)
+					}
+					stale, err := service.GetByID(ctx, id)
+					if err != nil {
+						t.Fatalf("getting account: %v", err)
+					}
+					if _, err := service.Patch(ctx, id, stale.Version, []byte(`{"account": {"name": "changed"}}`)); err != nil {
+						t.Fatalf("changing account: %v", err)
+					}
+
+					err = test.change(ctx, service, stale)
+					if !errors.Is(err, model.ErrPreconditionFailed) {
+						t.Errorf("error = %v, want %v", err, model.ErrPreconditionFailed)
+					}
+					current, err := service.GetByID(ctx, id)
+					if err != nil || current.Name != "changed" {
+						t.Errorf("account = %+v, %v, want the unchanged account", current, err)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestConcurrentPatch(t *testing.T) {
+	const writers = 10
+
+	for _, testStore := range testStores {
+		t.Run(testStore.name, func(t *testing.T) {
+			service := newTestService(t, testStore)
+			ctx := context.Background()
+
+			id, err := service.Create(ctx, testAccountCreate())
+			if err != nil {
+				t.Fatalf("creating account: %v", err)
+			}
+			before, err := service.GetByID(ctx, id)
+			if err != nil {
+				t.Fatalf("getting account: %v", err)
+			}
+
+			var mutex sync.Mutex
+			var succeeded int
+			var wait sync.WaitGroup
+			for i := 0; i < writers; i++ {
+				wait.Add(1)
+				go func(writer int) {
+					defer wait.Done()
+
+					patch := fmt.Sprintf(`{"account": {"name": "writer %d"}}`, writer)
+					_, err := service.Patch(ctx, id, before.Version, []byte(patch))
+					if err != nil && !errors.Is(err, model.ErrPreconditionFailed) {
+						t.Errorf("patching account: %v", err)
+						return
+					}
+
+					mutex.Lock()
+					defer mutex.Unlock()
+					if err == nil {
+						succeeded++
+					}
+				}(i)
+			}
+			wait.Wait()
+
+			if succeeded != 1 {
+				t.Errorf("%d writers changed the account at version %d, want 1", succeeded, before.Version)
+			}
+			after, err := service.GetByID(ctx, id)
+			if err != nil {
+				t.Fatalf("getting account: %v", err)
+			}
+			if after.Version != before.Version+1 {
+				t.Errorf("version = %d, want %d", after.Version, before.Version+1)
+			}
+		})
+	}
+}
+
+func TestBatch(t *testing.T) {
+	// Every batch is run against two accounts at version 1, the second item
+	// fails unless the test says otherwise.
+	replace := func(id string, version int64) model.Account {
+		return model.Account{ID: uuid.MustParse(id), Name: "replaced", Version: version}
+	}
+
+	tests := []struct {
+		name string
+		run  func(ctx context.Context, service account.Service, ids []string) ([]model.BatchResult, error)
+		// wantErr fails the whole batch, wantItemErr the second item only.
+		wantErr     error
+		wantItemErr error
+		wantChanged []bool
+	}{
+		{
+			name: "atomic update",
+			run: func(ctx context.Context, service account.Service, ids []string) ([]model.BatchResult, error) {
+				return service.BatchUpdate(ctx, model.BatchModeAtomic, []model.Account{replace(ids[0], 1), replace(ids[1], 1)})
+			},
+			wantChanged: []bool{true, true},
+		},
+		{
+			name: "atomic update with a stale item",
+			run: func(ctx context.Context, service account.Service, ids []string) ([]model.BatchResult, error) {
+				return service.BatchUpdate(ctx, model.BatchModeAtomic, []model.Account{replace(ids[0], 1), replace(ids[1], 2)})
+			},
+			wantErr:     model.ErrPreconditionFailed,
+			wantChanged: []bool{false, false},
+		},
+		{
+			name: "best effort update with a stale item",
+			run: func(ctx context.Context, service account.Service, ids []string) ([]model.BatchResult, error) {
+				return service.BatchUpdate(ctx, model.BatchModeBestEffort, []model.Account{replace(ids[0], 1), replace(ids[1], 2)})
+			},
+			wantItemErr: model.ErrPreconditionFailed,
+			wantChanged: []bool{true, false},
+		},
+		{
+			name: "atomic update with a missing item",
+			run: func(ctx context.Context, service account.Service, ids []string) ([]model.BatchResult, error) {
+				return service.BatchUpdate(ctx, model.BatchModeAtomic, []model.Account{replace(ids[0], 1), replace(uuid.NewString(), 1)})
+			},
+			wantErr:     model.ErrNotFound,
+			wantChanged: []bool{false, false},
+		},
+		{
+			name: "best effort update with a missing item",
+			run: func(ctx context.Context, service account.Service, ids []string) ([]model.BatchResult, error) {
+				return service.BatchUpdate(ctx, model.BatchModeBestEffort, []model.Account{replace(ids[0], 1), replace(uuid.NewString(), 1)})
+			},
+			wantItemErr: model.ErrNotFound,
+			wantChanged: []bool{true, false},
+		},
+		{
+			name: "atomic delete with a stale item",
+			run: func(ctx context.Context, service account.Service, ids []string) ([]model.BatchResult, error) {
+				return service.BatchDelete(ctx, model.BatchModeAtomic, []model.AccountVersion{{ID: ids[0], Version: 1}, {ID: ids[1], Version: 2}})
+			},
+			wantErr:     model.ErrPreconditionFailed,
+			wantChanged: []bool{false, false},
+		},
+		{
+			name: "best effort delete with a stale item",
+			run: func(ctx context.Context, service account.Service, ids []string) ([]model.BatchResult, error) {
+				return service.BatchDelete(ctx, model.BatchModeBestEffort, []model.AccountVersion{{ID: ids[0], Version: 1}, {ID: ids[1], Version: 2}})
+			},
+			wantItemErr: model.ErrPreconditionFailed,
+			wantChanged: []bool{true, false},
+		},
+		{
+			name: "empty batch",
+			run: func(ctx context.Context, service account.Service, ids []string) ([]model.BatchResult, error) {
+				return service.BatchDelete(ctx, model.BatchModeAtomic, nil)
+			},
+			wantErr:     model.ErrInvalidArgument,
+			wantChanged: []bool{false, false},
+		},
+	}
+
+	for _, testStore := range testStores {
+		t.Run(testStore.name, func(t *testing.T) {
+			service := newTestService(t, testStore)
+			ctx := context.Background()
+
+			for _, test := range tests {
+				t.Run(test.name, func(t *testing.T) {
+					created, err := service.BatchCreate(ctx, model.BatchModeAtomic, []model.AccountCreate{testAccountCreate(), testAccountCreate()})
+					if err != nil {
+						t.Fatalf("creating accounts: %v", err)
+					}
+					ids := []string{created[0].ID, created[1].ID}
+
+					results, err := test.run(ctx, service, ids)
+					if test.wantErr != nil {
+						if !errors.Is(err, test.wantErr) {
+							t.Errorf("error = %v, want %v", err, test.wantErr)
+						}
+					} else if err != nil {
+						t.Fatalf("running batch: %v", err)
+					} else {
+						if len(results) != 2 || results[0].Err != nil {
+							t.Fatalf("results = %+v, want two with the first one succeeded", results)
+						}
+						if !errors.Is(results[1].Err, test.wantItemErr) {
+							t.Errorf("second item error = %v, want %v", results[1].Err, test.wantItemErr)
+						}
+					}
+
+					for i, id := range ids {
+						account, err := service.GetByID(ctx, id)
+						changed := err != nil || account.Name != "account" || account.Version != 1
+						if changed != test.wantChanged[i] {
+							t.Errorf("account %d changed = %t, want %t", i, changed, test.wantChanged[i])
+						}
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestImport(t *testing.T) {
+	columns := []string{model.ColumnLogin, model.ColumnPassword, model.ColumnEmail, model.ColumnEmailPassword}
+
+	tests := []struct {
+		name    string
+		line    string
+		want    model.AccountCreate
+		wantErr error
+	}{
+		{
+			name: "plain line",
+			line: "user:secret:user@example.com:mail secret",
+			want: model.AccountCreate{Login: "user", Password: "secret", Email: "user@example.com", EmailPassword: "mail secret"},
+		},
+		{
+			name: "quoted field with the delimiter",
+			line: `user:"se:cret":user@example.com:mail`,
+			want: model.AccountCreate{Login: "user", Password: "se:cret", Email: "user@example.com", EmailPassword: "mail"},
+		},
+		{
+			name: "bare quote",
+			line: `user:se"cret:user@example.com:mail"`,
+			want: model.AccountCreate{Login: "user", Password: `se"cret`, Email: "user@example.com", EmailPassword: `mail"`},
+		},
+		{
+			name: "spaces around fields",
+			line: " user : secret : User@Example.com :  mail ",
+			want: model.AccountCreate{Login: "user", Password: " secret ", Email: "User@Example.com", EmailPassword: "  mail "},
+		},
+		{
+			name:    "invalid email",
+			line:    "user:secret: user.example.com :mail",
+			wantErr: model.ErrInvalidArgument,
+		},
+	}
+
+	for _, testStore := range testStores {
+		t.Run(testStore.name, func(t *testing.T) {
+			service := newTestService(t, testStore)
+			ctx := context.Background()
+
+			for _, test := range tests {
+				t.Run(test.name, func(t *testing.T) {
+					options := model.ImportOptions{Delimiter: ':', Columns: columns}
+					report, err := service.Import(ctx, options, strings.NewReader(test.line+"\n"))
+					if err != nil {
+						t.Fatalf("importing: %v", err)
+					}
+					if len(report.Lines) != 1 {
+						t.Fatalf("lines = %+v, want one", report.Lines)
+					}
+					line := report.Lines[0]
+					if test.wantErr != nil {
+						if !errors.Is(line.Err, test.wantErr) {
+							t.Errorf("error = %v, want %v", line.Err, test.wantErr)
+						}
+						return
+					}
+					if line.Err != nil {
+						t.Fatalf("importing line: %v", line.Err)
+					}
+
+					account, err := service.Reveal(ctx, line.ID)
+					if err != nil {
+						t.Fatalf("revealing account: %v", err)
+					}
+					got := model.AccountCreate{Login: account.Login, Password: account.Password, Email: account.Email, EmailPassword: account.EmailPassword}
+					if !reflect.DeepEqual(got, test.want) {
+						t.Errorf("imported %+v, want %+v", got, test.want)
+					}
+				})
+			}
+		})
+	}
+}
+
+var errAudit = errors.New("audit unavailable")
+
+// failingAuditRepository fails to record every audit record.
+type failingAuditRepository struct {
+	store.AuditRepository
+}
+
+func (failingAuditRepository) Create(context.Context, model.AuditRecordCreate) (string, error) {
+	return "", errAudit
+}
+
+func TestExportWithoutAudit(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	store := testStores[0].open(t, logger)
+	service := account.NewService(store.Account(), failingAuditRepository{store.Audit()}, store.StatusHistory(), store.AccountType(), store.CredentialHistory(), model.StatusMachine{}, logger)
+	ctx := context.Background()
+
+	if _, err := service.Create(ctx, testAccountCreate()); err != nil {
+		t.Fatalf("creating account: %v", err)
+	}
+
+	var exported []model.Account
+	export := func(account model.Account) error {
+		exported = append(exported, account)
+		return nil
+	}
+
+	err := service.Export(ctx, model.AccountFilter{}, []string{model.ColumnID, model.ColumnPassword}, export)
+	if !errors.Is(err, errAudit) {
+		t.Errorf("error = %v, want %v", err, errAudit)
+	}
+	if len(exported) != 0 {
+		t.Errorf("exported %d accounts with secrets without an audit", len(exported))
+	}
+
+	// Columns without secrets are not audited.
+	err = service.Export(ctx, model.AccountFilter{}, []string{model.ColumnID, model.ColumnName}, export)
+	if err != nil {
+		t.Errorf("error = %v, want none", err)
+	}
+	if len(exported) != 1 {
+		t.Errorf("exported %d accounts, want 1", len(exported))
+	}
+}
+
+func TestCookies(t *testing.T) {
+	const netscape = "# Netscape HTTP Cookie File\n\n" +
+		".example.com\tTRUE\t/\tTRUE\t4102444800\tsession\tabc\n" +
+		"#HttpOnly_example.com\tFALSE\t/app\tFALSE\t0\ttoken\tx=y\n"
+	netscapeJar := model.CookieJar{
+		{Domain: ".example.com", Path: "/", Name: "session", Value: "abc", Expires: time.Unix(4102444800, 0).UTC(), Secure: true},
+		{Domain: "example.com", HostOnly: true, Path: "/app", Name: "token", Value: "x=y", HTTPOnly: true},
+	}
+
+	tests := []struct {
+		name string
+		// stored is the cookie of the account before the change.
+		stored  string
+		format  model.CookieFormat
+		replace string
+		merge   string
+		want    model.CookieJar
+		wantErr error
+	}{
+		{
+			name:    "replace with cookies.txt",
+			format:  model.CookieFormatNetscape,
+			replace: netscape,
+			want:    netscapeJar,
+		},
+		{
+			name:    "replace with extension json",
+			format:  model.CookieFormatJSON,
+			replace: `[{"domain":"example.com","name":"a","value":"1","session":true,"sameSite":"lax"}]`,
+			want:    model.CookieJar{{Domain: "example.com", Path: "/", Name: "a", Value: "1"}},
+		},
+		{
+			name:    "cookies.txt with a missing field",
+			format:  model.CookieFormatNetscape,
+			replace: "example.com\tTRUE\t/\tFALSE\t0\tname\n",
+			wantErr: model.ErrInvalidArgument,
+		},
+		{
+			name:    "cookies.txt with a bad flag",
+			format:  model.CookieFormatNetscape,
+			replace: "example.com\tyes\t/\tFALSE\t0\tname\tvalue\n",
+			wantErr: model.ErrInvalidArgument,
+		},
+		{
+			name:    "cookie without a domain",
+			format:  model.CookieFormatJSON,
+			replace: `[{"name":"a","value":"1"}]`,
+			wantErr: model.ErrInvalidArgument,
+		},
+		{
+			name:   "merge replaces and removes",
+			stored: netscape,
+			format: model.CookieFormatJSON,
+			merge: `[{"domain":".example.com","path":"/","name":"session","value":"def","session":true},` +
+				`{"domain":"example.com","path":"/app","name":"token","expirationDate":1,"session":false},` +
+				`{"domain":"example.com","path":"/","name":"new","value":"1","session":true}]`,
+			want: model.CookieJar{
+				{Domain: ".example.com", Path: "/", Name: "session", Value: "def"},
+				{Domain: "example.com", Path: "/", Name: "new", Value: "1"},
+			},
+		},
+		{
+			name:    "merge into an opaque cookie",
+			stored:  "opaque",
+			format:  model.CookieFormatJSON,
+			merge:   `[{"domain":"example.com","name":"a","value":"1"}]`,
+			wantErr: model.ErrConflict,
+		},
+	}
+
+	for _, testStore := range testStores {
+		t.Run(testStore.name, func(t *testing.T) {
+			service := newTestService(t, testStore)
+			ctx := context.Background()
+
+			for _, test := range tests {
+				t.Run(test.name, func(t *testing.T) {
+					accountCreate := testAccountCreate()
+					accountCreate.Cookie = test.stored
+					id, err := service.Create(ctx, accountCreate)
+					if err != nil {
+						t.Fatalf("creating account: %v", err)
+					}
+
+					set := service.ReplaceCookies
+					body := test.replace
+					if test.merge != "" {
+						set = service.MergeCookies
+						body = test.merge
+					}
+					jar, err := model.ParseCookieJar([]byte(body), test.format)
+					if err == nil {
+						_, err = set(ctx, id, 1, jar)
+					}
+					if test.wantErr != nil {
+						if !errors.Is(err, test.wantErr) {
+							t.Errorf("error = %v, want %v", err, test.wantErr)
+						}
+						return
+					}
+					if err != nil {
+						t.Fatalf("setting cookies: %v", err)
+					}
+
+					got, version, err := service.GetCookies(ctx, id)
+					if err != nil {
+						t.Fatalf("getting cookies: %v", err)
+					}
+					if version != 2 {
+						t.Errorf("version = %d, want 2", version)
+					}
+					if !reflect.DeepEqual(got, test.want) {
+						t.Errorf("cookies = %+v, want %+v", got, test.want)
+					}
+
+					// The jar survives a round trip through either format.
+					for _, format := range []model.CookieFormat{model.CookieFormatJSON, model.CookieFormatNetscape} {
+						data, err := got.Format(format)
+						if err != nil {
+							t.Fatalf("formatting cookies as %s: %v", format, err)
+						}
+						parsed, err := model.ParseCookieJar(data, format)
+						if err != nil {
+							t.Fatalf("parsing cookies as %s: %v", format, err)
+						}
+						if !reflect.DeepEqual(parsed, got) {
+							t.Errorf("%s round trip = %+v, want %+v", format, parsed, got)
+						}
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestExpireSessions(t *testing.T) {
+	const (
+		expired = `[{"domain":"example.com","name":"a","expirationDate":1},{"domain":"example.com","name":"b","expirationDate":4102444800}]`
+		valid   = `[{"domain":"example.com","name":"a","expirationDate":4102444800}]`
+		session = `[{"domain":"example.com","name":"a","session":true}]`
+	)
+
+	tests := []struct {
+		name   string
+		cookie string
+		status string
+		// wantExpireAt is the Unix time of the earliest expiry, 0 for none.
+		wantExpireAt int64
+		wantStatus   string
+	}{
+		{name: "expired cookie", cookie: expired, status: "active", wantExpireAt: 1, wantStatus: model.AccountStatusSessionExpired},
+		{name: "valid cookie", cookie: valid, status: "active", wantExpireAt: 4102444800, wantStatus: "active"},
+		{name: "session cookie", cookie: session, status: "active", wantStatus: "active"},
+		{name: "opaque cookie", cookie: "opaque", status: "active", wantStatus: "active"},
+		{name: "already expired", cookie: expired, status: model.AccountStatusSessionExpired, wantExpireAt: 1, wantStatus: model.AccountStatusSessionExpired},
+		{name: "banned", cookie: expired, status: "banned", wantExpireAt: 1, wantStatus: "banned"},
+	}
+
+	for _, testStore := range testStores {
+		t.Run(testStore.name, func(t *testing.T) {
+			service := newTestServiceWithStatusMachine(t, testStore, testStatusMachine)
+			ctx := context.Background()
+
+			ids := make([]string, len(tests))
+			for i, test := range tests {
+				accountCreate := testAccountCreate()
+				accountCreate.Name = test.name
+				accountCreate.Cookie = test.cookie
+				accountCreate.Status = test.status
+				id, err := service.Create(ctx, accountCreate)
+				if err != nil {
+					t.Fatalf("creating account: %v", err)
+				}
+				ids[i] = id
+			}
+
+			expiring, _ := allPages(t, service, model.AccountFilter{CookiesExpiringBefore: time.Now()})
+			sort.Strings(expiring)
+			if want := []string{"already expired", "banned", "expired cookie"}; !reflect.DeepEqual(expiring, want) {
+				t.Errorf("expiring accounts = %v, want %v", expiring, want)
+			}
+
+			expiredCount, err := service.ExpireSessions(ctx)
+			if err != nil {
+				t.Fatalf("expiring sessions: %v", err)
+			}
+			if expiredCount != 1 {
+				t.Errorf("expired %d sessions, want 1", expiredCount)
+			}
+			// Accounts are only marked once.
+			if expiredCount, err := service.ExpireSessions(ctx); err != nil || expiredCount != 0 {
+				t.Errorf("expiring sessions again = %d, %v, want none", expiredCount, err)
+			}
+
+			for i, test := range tests {
+				t.Run(test.name, func(t *testing.T) {
+					account, err := service.GetByID(ctx, ids[i])
+					if err != nil {
+						t.Fatalf("getting account: %v", err)
+					}
+					if account.Status != test.wantStatus {
+						t.Errorf("status = %q, want %q", account.Status, test.wantStatus)
+					}
+					var expireAt int64
+					if account.CookiesExpireAt != nil {
+						expireAt = account.CookiesExpireAt.Unix()
+					}
+					if expireAt != test.wantExpireAt {
+						t.Errorf("cookies expire at %d, want %d", expireAt, test.wantExpireAt)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestStatusTransitions(t *testing.T) {
+	// Every change is made to an account created with status from at
+	// version 1.
+	tests := []struct {
+		name    string
+		from    string
+		change  func(ctx context.Context, service account.Service, before model.Account, to string) error
+		to      string
+		wantErr error
+	}{
+		{name: "allowed update", from: "new", change: updateStatus, to: "warming"},
+		{name: "allowed patch", from: "active", change: patchStatus, to: model.AccountStatusSessionExpired},
+		{name: "allowed atomic batch", from: "warming", change: batchUpdateStatus(model.BatchModeAtomic), to: "active"},
+		{name: "unchanged", from: "banned", change: updateStatus, to: "banned"},
+		{name: "rejected update", from: "new", change: updateStatus, to: "limited", wantErr: model.ErrConflict},
+		{name: "rejected patch", from: "banned", change: patchStatus, to: "active", wantErr: model.ErrConflict},
+		{name: "rejected atomic batch", from: "new", change: batchUpdateStatus(model.BatchModeAtomic), to: "limited", wantErr: model.ErrConflict},
+		{name: "rejected best effort batch", from: "new", change: batchUpdateStatus(model.BatchModeBestEffort), to: "limited", wantErr: model.ErrConflict},
+		{name: "unknown status", from: "new", change: updateStatus, to: "frozen", wantErr: model.ErrInvalidArgument},
+		{
+			name: "stale and rejected",
+			from: "new",
+			change: func(ctx context.Context, service account.Service, before model.Account, to string) error {
+				before.Version++
+				return updateStatus(ctx, service, before, to)
+			},
+			to:      "limited",
+			wantErr: model.ErrPreconditionFailed,
+		},
+	}
+
+	for _, testStore := range testStores {
+		t.Run(testStore.name, func(t *testing.T) {
+			service := newTestServiceWithStatusMachine(t, testStore, testStatusMachine)
+			ctx := context.Background()
+
+			for _, test := range tests {
+				t.Run(test.name, func(t *testing.T) {
+					accountCreate := testAccountCreate()
+					accountCreate.Status = test.from
+					id, err := service.Create(ctx, accountCreate)
+					if err != nil {
+						t.Fatalf("creating account: %v", err)
+					}
+					before, err := service.GetByID(ctx, id)
+					if err != nil {
+						t.Fatalf("getting account: %v", err)
+					}
+
+					err = test.change(ctx, service, before, test.to)
+					if !errors.Is(err, test.wantErr) {
+						t.Fatalf("error = %v, want %v", err, test.wantErr)
+					}
+
+					wantStatus := test.to
+					wantHistory := []string{"->" + test.from}
+					if test.wantErr != nil {
+						wantStatus = test.from
+					} else if test.to != test.from {
+						wantHistory = append(wantHistory, test.from+"->"+test.to)
+					}
+
+					after, err := service.GetByID(ctx, id)
+					if err != nil {
+						t.Fatalf("getting account: %v", err)
+					}
+					if after.Status != wantStatus {
+						t.Errorf("status = %q, want %q", after.Status, wantStatus)
+					}
+
+					statusTransitions, err := service.StatusHistory(ctx, id)
+					if err != nil {
+						t.Fatalf("getting status history: %v", err)
+					}
+					var history []string
+					for _, statusTransition := range statusTransitions {
+						history = append(history, statusTransition.From+"->"+statusTransition.To)
+					}
+					if !reflect.DeepEqual(history, wantHistory) {
+						t.Errorf("status history = %v, want %v", history, wantHistory)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestInitialStatus(t *testing.T) {
+	for _, testStore := range testStores {
+		t.Run(testStore.name, func(t *testing.T) {
+			service := newTestServiceWithStatusMachine(t, testStore, testStatusMachine)
+			ctx := context.Background()
+
+			accountCreate := testAccountCreate()
+			accountCreate.Status = ""
+			id, err := service.Create(ctx, accountCreate)
+			if err != nil {
+				t.Fatalf("creating account: %v", err)
+			}
+			if account, err := service.GetByID(ctx, id); err != nil || account.Status != "new" {
+				t.Errorf("account = %+v, %v, want status new", account, err)
+			}
+
+			accountCreate.Status = "frozen"
+			if _, err := service.Create(ctx, accountCreate); !errors.Is(err, model.ErrInvalidArgument) {
+				t.Errorf("creating with an unknown status: error = %v, want %v", err, model.ErrInvalidArgument)
+			}
+		})
+	}
+}
+
+func updateStatus(ctx context.Context, service account.Service, before model.Account, to string) error {
+	before.Status = to
+	_, err := service.Update(ctx, before)
+	return err
+}
+
+func patchStatus(ctx context.Context, service account.Service, before model.Account, to string) error {
+	_, err := service.Patch(ctx, before.ID.String(), before.Version, []byte(fmt.Sprintf(`{"account": {"status": %q}}`, to)))
+	return err
+}
+
+// batchUpdateStatus returns the error of the batch, or of its only item in
+// best effort mode.
+func batchUpdateStatus(mode model.BatchMode) func(ctx context.Context, service account.Service, before model.Account, to string) error {
+	return func(ctx context.Context, service account.Service, before model.Account, to string) error {
+		before.Status = to
+		results, err := service.BatchUpdate(ctx, mode, []model.Account{before})
+		if err != nil {
+			return err
+		}
+		return results[0].Err
+	}
+}
+
+func TestAccountTypes(t *testing.T) {
+	mail := model.AccountType{
+		Name:            "mail",
+		RequiredFields:  []string{"email", "emailPassword"},
+		AllowedStatuses: []string{"new", "active"},
+	}
+
+	withType := func(accountType string, change func(*model.AccountCreate)) model.AccountCreate {
+		accountCreate := testAccountCreate()
+		accountCreate.AccountType = accountType
+		if change != nil {
+			change(&accountCreate)
+		}
+		return accountCreate
+	}
+
+	tests := []struct {
+		name string
+		// stored is created through the repository, bypassing validation.
+		stored  model.AccountCreate
+		change  func(ctx context.Context, service account.Service, stored model.Account) error
+		wantErr error
+	}{
+		{
+			name: "create with the required fields",
+			change: func(ctx context.Context, service account.Service, _ model.Account) error {
+				_, err := service.Create(ctx, withType("mail", nil))
+				return err
+			},
+		},
+		{
+			name: "create without a required field",
+			change: func(ctx context.Context, service account.Service, _ model.Account) error {
+				_, err := service.Create(ctx, withType("mail", func(a *model.AccountCreate) { a.EmailPassword = "" }))
+				return err
+			},
+			wantErr: model.ErrInvalidArgument,
+		},
+		{
+			name: "create with a status the type does not allow",
+			change: func(ctx context.Context, service account.Service, _ model.Account) error {
+				_, err := service.Create(ctx, withType("mail", func(a *model.AccountCreate) { a.Status = "banned" }))
+				return err
+			},
+			wantErr: model.ErrInvalidArgument,
+		},
+		{
+			name: "create with an unknown type",
+			change: func(ctx context.Context, service account.Service, _ model.Account) error {
+				_, err := service.Create(ctx, withType("unknown", nil))
+				return err
+			},
+			wantErr: model.ErrInvalidArgument,
+		},
+		{
+			name:   "update keeping an unregistered type",
+			stored: withType("legacy", nil),
+			change: func(ctx context.Context, service account.Service, stored model.Account) error {
+				stored.Name = "renamed"
+				_, err := service.Update(ctx, stored)
+				return err
+			},
+		},
+		{
+			name:   "patch clearing a required field",
+			stored: withType("mail", nil),
+			change: func(ctx context.Context, service account.Service, stored model.Account) error {
+				_, err := service.Patch(ctx, stored.ID.String(), stored.Version, []byte(`{"account": {"email": null}}`))
+				return err
+			},
+			wantErr: model.ErrInvalidArgument,
+		},
+		{
+			name:   "best effort batch update clearing a required field",
+			stored: withType("mail", nil),
+			change: func(ctx context.Context, service account.Service, stored model.Account) error {
+				stored.EmailPassword = ""
+				results, err := service.BatchUpdate(ctx, model.BatchModeBestEffort, []model.Account{stored})
+				if err != nil {
+					return err
+				}
+				return results[0].Err
+			},
+			wantErr: model.ErrInvalidArgument,
+		},
+		{
+			name: "import with a missing required field",
+			change: func(ctx context.Context, service account.Service, _ model.Account) error {
+				options := model.ImportOptions{Delimiter: ',', Columns: []string{model.ColumnAccountType, model.ColumnLogin, model.ColumnEmail}}
+				report, err := service.Import(ctx, options, strings.NewReader("mail,login,login@example.com\n"))
+				if err != nil {
+					return err
+				}
+				return report.Lines[0].Err
+			},
+			wantErr: model.ErrInvalidArgument,
+		},
+	}
+
+	for _, testStore := range testStores {
+		t.Run(testStore.name, func(t *testing.T) {
+			store, service := openTestService(t, testStore, model.StatusMachine{})
+			ctx := context.Background()
+			if err := store.AccountType().Create(ctx, mail); err != nil {
+				t.Fatalf("creating account type: %v", err)
+			}
+
+			for _, test := range tests {
+				t.Run(test.name, func(t *testing.T) {
+					var stored model.Account
+					if !reflect.DeepEqual(test.stored, model.AccountCreate{}) {
+						id, err := store.Account().Create(ctx, test.stored)
+						if err != nil {
+							t.Fatalf("creating account: %v", err)
+						}
+						if stored, err = service.GetByID(ctx, id); err != nil {
+							t.Fatalf("getting account: %v", err)
+						}
+					}
+
+					err := test.change(ctx, service, stored)
+					if !errors.Is(err, test.wantErr) {
+						t.Errorf("error = %v, want %v", err, test.wantErr)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestDeleteAccountTypeInUse(t *testing.T) {
+	for _, testStore := range testStores {
+		t.Run(testStore.name, func(t *testing.T) {
+			store, service := openTestService(t, testStore, model.StatusMachine{})
+			logger := logrus.New()
+			logger.SetOutput(io.Discard)
+			accountTypeService := accounttype.NewService(store.AccountType(), store.Account(), logger)
+			ctx := context.Background()
+
+			for _, name := range []string{"used", "unused"} {
+				if err := accountTypeService.Create(ctx, model.AccountType{Name: name}); err != nil {
+					t.Fatalf("creating account type: %v", err)
+				}
+			}
+			accountCreate := testAccountCreate()
+			accountCreate.AccountType = "used"
+			if _, err := service.Create(ctx, accountCreate); err != nil {
+				t.Fatalf("creating account: %v", err)
+			}
+
+			if err := accountTypeService.Delete(ctx, "used"); !errors.Is(err, model.ErrConflict) {
+				t.Errorf("deleting a used type: error = %v, want %v", err, model.ErrConflict)
+			}
+			if err := accountTypeService.Delete(ctx, "unused"); err != nil {
+				t.Errorf("deleting an unused type: %v", err)
+			}
+			if _, err := accountTypeService.GetByName(ctx, "unused"); !errors.Is(err, model.ErrNotFound) {
+				t.Errorf("getting a deleted type: error = %v, want %v", err, model.ErrNotFound)
+			}
+		})
+	}
+}
+
+func TestAttributes(t *testing.T) {
+	phone := model.AccountType{
+		Name: "phone",
+		Attributes: map[string]model.AttributeDefinition{
+			"phone": {Type: model.AttributeTypeString, Required: true},
+			"pin":   {Type: model.AttributeTypeNumber, Secret: true},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		accountType string
+		attributes  model.Attributes
+		wantErr     error
+	}{
+		{
+			name: "typed attributes",
+			attributes: model.Attributes{
+				"born":     {Type: model.AttributeTypeDate, Value: "1990-02-03"},
+				"verified": {Type: model.AttributeTypeBoolean, Value: "true"},
+				"backup":   {Value: "codes", Secret: true},
+			},
+		},
+		{name: "invalid name", attributes: model.Attributes{"a b": {Value: "1"}}, wantErr: model.ErrInvalidArgument},
+		{name: "invalid type", attributes: model.Attributes{"a": {Type: "list", Value: "1"}}, wantErr: model.ErrInvalidArgument},
+		{name: "value not of its type", attributes: model.Attributes{"a": {Type: model.AttributeTypeNumber, Value: "one"}}, wantErr: model.ErrInvalidArgument},
+		{name: "missing value", attributes: model.Attributes{"a": nil}, wantErr: model.ErrInvalidArgument},
+		{
+			name:        "attributes of the type",
+			accountType: "phone",
+			attributes: model.Attributes{
+				"phone": {Value: "+100"},
+				"pin":   {Type: model.AttributeTypeNumber, Value: "1234", Secret: true},
+			},
+		},
+		{name: "missing required attribute", accountType: "phone", attributes: model.Attributes{}, wantErr: model.ErrInvalidArgument},
+		{
+			name:        "attribute the type does not define",
+			accountType: "phone",
+			attributes:  model.Attributes{"phone": {Value: "+100"}, "other": {Value: "1"}},
+			wantErr:     model.ErrInvalidArgument,
+		},
+		{
+			name:        "secret attribute not flagged secret",
+			accountType: "phone",
+			attributes:  model.Attributes{"phone": {Value: "+100"}, "pin": {Type: model.AttributeTypeNumber, Value: "1234"}},
+			wantErr:     model.ErrInvalidArgument,
+		},
+	}
+
+	for _, testStore := range testStores {
+		t.Run(testStore.name, func(t *testing.T) {
+			store, service := openTestService(t, testStore, model.StatusMachine{})
+			ctx := context.Background()
+			if err := store.AccountType().Create(ctx, phone); err != nil {
+				t.Fatalf("creating account type: %v", err)
+			}
+
+			for _, test := range tests {
+				t.Run(test.name, func(t *testing.T) {
+					accountCreate := testAccountCreate()
+					accountCreate.AccountType = test.accountType
+					accountCreate.Attributes = test.attributes
+					id, err := service.Create(ctx, accountCreate)
+					if !errors.Is(err, test.wantErr) {
+						t.Fatalf("error = %v, want %v", err, test.wantErr)
+					}
+					if err != nil {
+						return
+					}
+
+					account, err := service.GetByID(ctx, id)
+					if err != nil {
+						t.Fatalf("getting account: %v", err)
+					}
+					if !reflect.DeepEqual(account.Attributes, test.attributes) {
+						t.Errorf("attributes = %v, want %v", account.Attributes, test.attributes)
+					}
+
+					masked := account.Masked()
+					for name, attribute := range test.attributes {
+						if got := masked.Attributes[name].Value; (got == attribute.Value) == attribute.Secret {
+							t.Errorf("masked attribute %s = %q, secret %t", name, got, attribute.Secret)
+						}
+					}
+					if !reflect.DeepEqual(account.Attributes, test.attributes) {
+						t.Errorf("masking changed the attributes of the account")
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestAttributeFilter(t *testing.T) {
+	for _, testStore := range testStores {
+		t.Run(testStore.name, func(t *testing.T) {
+			service := newTestService(t, testStore)
+			ctx := context.Background()
+
+			for name, attributes := range map[string]model.Attributes{
+				"red":        {"color": {Value: "red"}, "size": {Value: "1", Type: model.AttributeTypeNumber}},
+				"blue":       {"color": {Value: "blue"}, "size": {Value: "1", Type: model.AttributeTypeNumber}},
+				"secret red": {"color": {Value: "red", Secret: true}},
+				"none":       nil,
+			} {
+				accountCreate := testAccountCreate()
+				accountCreate.Name = name
+				accountCreate.Attributes = attributes
+				if _, err := service.Create(ctx, accountCreate); err != nil {
+					t.Fatalf("creating account: %v", err)
+				}
+			}
+
+			tests := []struct {
+				name       string
+				attributes map[string]string
+				want       []string
+			}{
+				{name: "one attribute", attributes: map[string]string{"color": "red"}, want: []string{"red"}},
+				{name: "all attributes", attributes: map[string]string{"color": "blue", "size": "1"}, want: []string{"blue"}},
+				{name: "shared attribute", attributes: map[string]string{"size": "1"}, want: []string{"blue", "red"}},
+				{name: "no match", attributes: map[string]string{"color": "green"}, want: nil},
+			}
+
+			for _, test := range tests {
+				t.Run(test.name, func(t *testing.T) {
+					got, _ := allPages(t, service, model.AccountFilter{Attributes: test.attributes})
+					sort.Strings(got)
+					if !reflect.DeepEqual(got, test.want) {
+						t.Errorf("accounts = %v, want %v", got, test.want)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestTags(t *testing.T) {
+	for _, testStore := range testStores {
+		t.Run(testStore.name, func(t *testing.T) {
+			service := newTestService(t, testStore)
+			ctx := context.Background()
+
+			ids := make(map[string]uuid.UUID)
+			for _, name := range []string{"a", "b", "c"} {
+				accountCreate := testAccountCreate()
+				accountCreate.Name = name
+				id, err := service.Create(ctx, accountCreate)
+				if err != nil {
+					t.Fatalf("creating account: %v", err)
+				}
+				ids[name] = uuid.MustParse(id)
+			}
+
+			if err := service.Tag(ctx, model.AccountTags{AccountIDs: []uuid.UUID{ids["a"], ids["b"]}, Tags: []string{"team:x", "campaign"}}); err != nil {
+				t.Fatalf("tagging accounts: %v", err)
+			}
+			if err := service.Tag(ctx, model.AccountTags{AccountIDs: []uuid.UUID{ids["b"], ids["c"]}, Tags: []string{"team:y"}}); err != nil {
+				t.Fatalf("tagging accounts: %v", err)
+			}
+			if err := service.Untag(ctx, model.AccountTags{AccountIDs: []uuid.UUID{ids["b"]}, Tags: []string{"campaign", "unknown"}}); err != nil {
+				t.Fatalf("untagging accounts: %v", err)
+			}
+
+			errorTests := []struct {
+				name        string
+				accountTags model.AccountTags
+				wantErr     error
+			}{
+				{name: "missing account", accountTags: model.AccountTags{AccountIDs: []uuid.UUID{ids["c"], uuid.New()}, Tags: []string{"campaign"}}, wantErr: model.ErrNotFound},
+				{name: "invalid tag", accountTags: model.AccountTags{AccountIDs: []uuid.UUID{ids["c"]}, Tags: []string{"-bad"}}, wantErr: model.ErrInvalidArgument},
+				{name: "no tags", accountTags: model.AccountTags{AccountIDs: []uuid.UUID{ids["c"]}}, wantErr: model.ErrInvalidArgument},
+			}
+			for _, test := range errorTests {
+				t.Run(test.name, func(t *testing.T) {
+					if err := service.Tag(ctx, test.accountTags); !errors.Is(err, test.wantErr) {
+						t.Errorf("error = %v, want %v", err, test.wantErr)
+					}
+				})
+			}
+
+			filterTests := []struct {
+				name   string
+				filter model.AccountFilter
+				want   []string
+			}{
+				{name: "one tag", filter: model.AccountFilter{Tags: []string{"team:x"}}, want: []string{"a", "b"}},
+				{name: "all tags", filter: model.AccountFilter{Tags: []string{"team:x", "team:y"}}, want: []string{"b"}},
+				{name: "any tag", filter: model.AccountFilter{Tags: []string{"team:x", "team:y"}, TagMatch: model.TagMatchAny}, want: []string{"a", "b", "c"}},
+				{name: "untagged", filter: model.AccountFilter{Tags: []string{"campaign"}}, want: []string{"a"}},
+				{name: "with other filters", filter: model.AccountFilter{Tags: []string{"team:y"}, Limit: 1, Sort: model.AccountSort{Field: model.SortByName}}, want: []string{"b", "c"}},
+			}
+			for _, test := range filterTests {
+				t.Run(test.name, func(t *testing.T) {
+					got, _ := allPages(t, service, test.filter)
+					sort.Strings(got)
+					if !reflect.DeepEqual(got, test.want) {
+						t.Errorf("accounts = %v, want %v", got, test.want)
+					}
+				})
+			}
+
+			tagCounts, err := service.GetTags(ctx)
+			if err != nil {
+				t.Fatalf("getting tags: %v", err)
+			}
+			want := []model.TagCount{{Tag: "campaign", Count: 1}, {Tag: "team:x", Count: 2}, {Tag: "team:y", Count: 2}}
+			if !reflect.DeepEqual(tagCounts, want) {
+				t.Errorf("tags = %+v, want %+v", tagCounts, want)
+			}
+
+			// Tags do not change the version and survive a replace.
+			account, err := service.GetByID(ctx, ids["b"].String())
+			if err != nil {
+				t.Fatalf("getting account: %v", err)
+			}
+			if account.Version != 1 || !reflect.DeepEqual(account.Tags, []string{"team:x", "team:y"}) {
+				t.Errorf("account version %d, tags %v, want version 1 and tags [team:x team:y]", account.Version, account.Tags)
+			}
+			if _, err := service.Update(ctx, account.Replace(model.AccountUpdate{Name: "b"})); err != nil {
+				t.Fatalf("updating account: %v", err)
+			}
+			if account, err := service.GetByID(ctx, ids["b"].String()); err != nil || !reflect.DeepEqual(account.Tags, []string{"team:x", "team:y"}) {
+				t.Errorf("tags after update = %v, %v, want [team:x team:y]", account.Tags, err)
+			}
+		})
+	}
+}
+
+func TestTrash(t *testing.T) {
+	// Every change is made to an account in the trash.
+	tests := []struct {
+		name    string
+		change  func(ctx context.Context, service account.Service, id string) error
+		wantErr error
+	}{
+		{
+			name: "get",
+			change: func(ctx context.Context, service account.Service, id string) error {
+				_, err := service.GetByID(ctx, id)
+				return err
+			},
+			wantErr: model.ErrNotFound,
+		},
+		{
+			name: "patch",
+			change: func(ctx context.Context, service account.Service, id string) error {
+				_, err := service.Patch(ctx, id, 2, []byte(`{"account": {"name": "changed"}}`))
+				return err
+			},
+			wantErr: model.ErrNotFound,
+		},
+		{
+			name: "delete again",
+			change: func(ctx context.Context, service account.Service, id string) error {
+				return service.Delete(ctx, id, 2)
+			},
+			wantErr: model.ErrNotFound,
+		},
+		{
+			name: "tag",
+			change: func(ctx context.Context, service account.Service, id string) error {
+				return service.Tag(ctx, model.AccountTags{AccountIDs: []uuid.UUID{uuid.MustParse(id)}, Tags: []string{"tag"}})
+			},
+			wantErr: model.ErrNotFound,
+		},
+		{
+			name: "restore",
+			change: func(ctx context.Context, service account.Service, id string) error {
+				version, err := service.Restore(ctx, id)
+				if err == nil && version != 3 {
+					return fmt.Errorf("restored version %d, want 3", version)
+				}
+				return err
+			},
+		},
+	}
+
+	for _, testStore := range testStores {
+		t.Run(testStore.name, func(t *testing.T) {
+			service := newTestService(t, testStore)
+			ctx := context.Background()
+
+			for _, test := range tests {
+				t.Run(test.name, func(t *testing.T) {
+					accountCreate := testAccountCreate()
+					accountCreate.Name = test.name
+					id, err := service.Create(ctx, accountCreate)
+					if err != nil {
+						t.Fatalf("creating account: %v", err)
+					}
+					if err := service.Delete(ctx, id, 1); err != nil {
+						t.Fatalf("deleting account: %v", err)
+					}
+
+					if err := test.change(ctx, service, id); !errors.Is(err, test.wantErr) {
+						t.Errorf("error = %v, want %v", err, test.wantErr)
+					}
+				})
+			}
+
+			listed, _ := allPages(t, service, model.AccountFilter{})
+			if want := []string{"restore"}; !reflect.DeepEqual(listed, want) {
+				t.Errorf("accounts = %v, want %v", listed, want)
+			}
+			page, err := service.Trash(ctx, model.AccountFilter{Sort: model.AccountSort{Field: model.SortByName}})
+			if err != nil {
+				t.Fatalf("getting trash: %v", err)
+			}
+			var trashed []string
+			for _, account := range page.Accounts {
+				trashed = append(trashed, account.Name)
+				if account.DeletedAt == nil {
+					t.Errorf("account %s in the trash has no deleted at", account.Name)
+				}
+			}
+			if want := []string{"delete again", "get", "patch", "tag"}; !reflect.DeepEqual(trashed, want) {
+				t.Errorf("trash = %v, want %v", trashed, want)
+			}
+		})
+	}
+}
+
+func TestPurgeDeleted(t *testing.T) {
+	for _, testStore := range testStores {
+		t.Run(testStore.name, func(t *testing.T) {
+			store, service := openTestService(t, testStore, testStatusMachine)
+			ctx := context.Background()
+
+			ids := make([]string, 3)
+			for i := range ids {
+				id, err := service.Create(ctx, testAccountCreate())
+				if err != nil {
+					t.Fatalf("creating account: %v", err)
+				}
+				ids[i] = id
+				if _, err := service.Patch(ctx, id, 1, []byte(`{"account": {"password": "changed"}}`)); err != nil {
+					t.Fatalf("changing account: %v", err)
+				}
+			}
+			// The first account is deleted before the purge, the second one
+			// after it and the last one is kept.
+			if err := service.Delete(ctx, ids[0], 2); err != nil {
+				t.Fatalf("deleting account: %v", err)
+			}
+			time.Sleep(10 * time.Millisecond)
+			deletedBefore := time.Now()
+			if err := service.Delete(ctx, ids[1], 2); err != nil {
+				t.Fatalf("deleting account: %v", err)
+			}
+
+			purged, err := service.PurgeDeleted(ctx, deletedBefore)
+			if err != nil {
+				t.Fatalf("purging accounts: %v", err)
+			}
+			if purged != 1 {
+				t.Errorf("purged %d accounts, want 1", purged)
+			}
+
+			if _, err := service.Restore(ctx, ids[0]); !errors.Is(err, model.ErrNotFound) {
+				t.Errorf("restoring a purged account: error = %v, want %v", err, model.ErrNotFound)
+			}
+			for i, wantHistory := range []int{0, 1, 1} {
+				statusTransitions, err := store.StatusHistory().GetByAccountID(ctx, ids[i])
+				if err != nil {
+					t.Fatalf("getting status history: %v", err)
+				}
+				if len(statusTransitions) != wantHistory {
+					t.Errorf("account %d has %d status transitions, want %d", i, len(statusTransitions), wantHistory)
+				}
+				credentialVersions, err := store.CredentialHistory().GetByAccountID(ctx, ids[i])
+				if err != nil {
+					t.Fatalf("getting credential history: %v", err)
+				}
+				if len(credentialVersions) != wantHistory {
+					t.Errorf("account %d has %d credential versions, want %d", i, len(credentialVersions), wantHistory)
+				}
+			}
+			if _, err := service.Restore(ctx, ids[1]); err != nil {
+				t.Errorf("restoring an account deleted after the purge: %v", err)
+			}
+		})
+	}
+}
+
+var errHistory = errors.New("credential history unavailable")
+
+// failingCredentialHistoryRepository fails to keep every credential version.
+type failingCredentialHistoryRepository struct {
+	store.CredentialHistoryRepository
+}
+
+func (failingCredentialHistoryRepository) Create(context.Context, model.CredentialVersion) error {
+	return errHistory
+}
+
+func TestCredentialHistory(t *testing.T) {
+	for _, testStore := range testStores {
+		t.Run(testStore.name, func(t *testing.T) {
+			store, service := openTestService(t, testStore, model.StatusMachine{})
+			logger := logrus.New()
+			logger.SetOutput(io.Discard)
+			withoutHistory := account.NewService(store.Account(), store.Audit(), store.StatusHistory(), store.AccountType(),
+				failingCredentialHistoryRepository{store.CredentialHistory()}, model.StatusMachine{}, logger)
+			ctx := context.Background()
+
+			id, err := service.Create(ctx, testAccountCreate())
+			if err != nil {
+				t.Fatalf("creating account: %v", err)
+			}
+
+			_, err = withoutHistory.Patch(ctx, id, 1, []byte(`{"account": {"password": "lost"}}`))
+			if !errors.Is(err, errHistory) {
+				t.Errorf("error = %v, want %v", err, errHistory)
+			}
+			unchanged, err := service.Reveal(ctx, id)
+			if err != nil {
+				t.Fatalf("getting account: %v", err)
+			}
+			if unchanged.Password != "password" || unchanged.Version != 1 {
+				t.Errorf("account changed without history to password %q at version %d", unchanged.Password, unchanged.Version)
+			}
+
+			if _, err := service.Patch(ctx, id, 1, []byte(`{"account": {"password": "kept"}}`)); err != nil {
+				t.Fatalf("changing account: %v", err)
+			}
+			// Changes that leave the secrets alone keep no version.
+			if _, err := service.Patch(ctx, id, 2, []byte(`{"account": {"name": "renamed"}}`)); err != nil {
+				t.Fatalf("changing account: %v", err)
+			}
+			versions, err := service.CredentialHistory(ctx, id)
+			if err != nil {
+				t.Fatalf("getting credential history: %v", err)
+			}
+			if len(versions) != 1 || versions[0].Version != 1 || versions[0].Password != "password" {
+				t.Fatalf("credential history = %+v, want version 1", versions)
+			}
+		})
+	}
+}
+
+func TestRollback(t *testing.T) {
+	tests := []struct {
+		name         string
+		version      int64
+		target       int64
+		wantErr      error
+		wantPassword string
+	}{
+		{"stale version", 2, 1, model.ErrPreconditionFailed, "changed"},
+		{"missing credential version", 3, 7, model.ErrNotFound, "changed"},
+		{"credential version", 3, 1, nil, "password"},
+	}
+
+	for _, testStore := range testStores {
+		t.Run(testStore.name, func(t *testing.T) {
+			for _, test := range tests {
+				t.Run(test.name, func(t *testing.T) {
+					_, service := openTestService(t, testStore, model.StatusMachine{})
+					ctx := context.Background()
+
+					id, err := service.Create(ctx, testAccountCreate())
+					if err != nil {
+						t.Fatalf("creating account: %v", err)
+					}
+					if _, err := service.Patch(ctx, id, 1, []byte(`{"account": {"password": "changed"}}`)); err != nil {
+						t.Fatalf("changing account: %v", err)
+					}
+					if _, err := service.Patch(ctx, id, 2, []byte(`{"account": {"name": "renamed"}}`)); err != nil {
+						t.Fatalf("changing account: %v", err)
+					}
+
+					version, err := service.Rollback(ctx, id, test.version, test.target)
+					if !errors.Is(err, test.wantErr) {
+						t.Fatalf("error = %v, want %v", err, test.wantErr)
+					}
+
+					after, err := service.Reveal(ctx, id)
+					if err != nil {
+						t.Fatalf("getting account: %v", err)
+					}
+					if after.Password != test.wantPassword || after.Name != "renamed" {
+						t.Errorf("account = %q with password %q, want %q with %q", after.Name, after.Password, "renamed", test.wantPassword)
+					}
+					if test.wantErr != nil {
+						return
+					}
+					if version != 4 || after.Version != 4 {
+						t.Errorf("version = %d, account at %d, want 4", version, after.Version)
+					}
+				})
+			}
+		})
+	}
+}
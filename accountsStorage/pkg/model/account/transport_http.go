@@ -6,6 +6,10 @@ import (
 	"errors"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	kithttp "github.com/go-kit/kit/transport/http"
@@ -13,6 +17,7 @@ import (
 	"github.com/sirupsen/logrus"
 
 	_ "account_storage/docs"
+	"account_storage/pkg/httperror"
 	"account_storage/pkg/logadapter"
 	"account_storage/pkg/model"
 
@@ -24,6 +29,24 @@ var (
 	ErrBadRouting = errors.New("bad routing")
 )
 
+// StatusReasonHeader carries the reason for the status changes a request
+// makes, which is recorded in the status history.
+const StatusReasonHeader = "X-Status-Reason"
+
+type statusReasonContextKey struct{}
+
+func statusReasonToContext(ctx context.Context, r *http.Request) context.Context {
+	if reason := r.Header.Get(StatusReasonHeader); reason != "" {
+		return context.WithValue(ctx, statusReasonContextKey{}, reason)
+	}
+	return ctx
+}
+
+func statusReasonFromContext(ctx context.Context) string {
+	reason, _ := ctx.Value(statusReasonContextKey{}).(string)
+	return reason
+}
+
 type GinContextKey struct{}
 
 func GinContextToContextMiddleware() gin.HandlerFunc {
@@ -35,19 +58,21 @@ func GinContextToContextMiddleware() gin.HandlerFunc {
 }
 
 func NewGinService(
-	svcEndpoints Endpoints, options []kithttp.ServerOption, logger *logrus.Logger) *gin.Engine {
+	svcEndpoints Endpoints, options []kithttp.ServerOption, logger *logrus.Logger, authMiddleware gin.HandlerFunc) *gin.Engine {
 	router := gin.Default()
 	router.Use(GinContextToContextMiddleware())
 	logrusAdapter := logadapter.NewLogrusAdapter(logger)
 	errorLogger := kithttp.ServerErrorLogger(logrusAdapter)
-	errorEncoder := kithttp.ServerErrorEncoder(encodeErrorResponse)
-	options = append(options, errorLogger, errorEncoder)
+	errorEncoder := kithttp.ServerErrorEncoder(httperror.EncodeError)
+	options = append(options, errorLogger, errorEncoder, kithttp.ServerBefore(statusReasonToContext))
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	router.GET("/nginx", nginxHandler(logger))
 
-	router.POST("/accounts", gin.WrapF(func(w http.ResponseWriter, r *http.Request) {
+	accounts := router.Group("/accounts", authMiddleware)
+
+	accounts.POST("", gin.WrapF(func(w http.ResponseWriter, r *http.Request) {
 		kithttp.NewServer(
 			svcEndpoints.Create,
 			decodeCreateRequest(logger),
@@ -56,7 +81,7 @@ func NewGinService(
 		).ServeHTTP(w, r)
 	}))
 
-	router.GET("/accounts", gin.WrapF(func(w http.ResponseWriter, r *http.Request) {
+	accounts.GET("", gin.WrapF(func(w http.ResponseWriter, r *http.Request) {
 		kithttp.NewServer(
 			svcEndpoints.GetAll,
 			decodeGetAllRequest,
@@ -65,7 +90,34 @@ func NewGinService(
 		).ServeHTTP(w, r)
 	}))
 
-	router.GET("/accounts/:id", gin.WrapF(func(w http.ResponseWriter, r *http.Request) {
+	accounts.POST("/import", gin.WrapF(func(w http.ResponseWriter, r *http.Request) {
+		kithttp.NewServer(
+			svcEndpoints.Import,
+			decodeImportRequest,
+			encodeResponse(logger),
+			options...,
+		).ServeHTTP(w, r)
+	}))
+
+	accounts.GET("/export", gin.WrapF(func(w http.ResponseWriter, r *http.Request) {
+		kithttp.NewServer(
+			svcEndpoints.Export,
+			decodeExportRequest,
+			encodeExportResponse(logger),
+			options...,
+		).ServeHTTP(w, r)
+	}))
+
+	accounts.GET("/trash", gin.WrapF(func(w http.ResponseWriter, r *http.Request) {
+		kithttp.NewServer(
+			svcEndpoints.Trash,
+			decodeTrashRequest,
+			encodeResponse(logger),
+			options...,
+		).ServeHTTP(w, r)
+	}))
+
+	accounts.GET("/:id", gin.WrapF(func(w http.ResponseWriter, r *http.Request) {
 		kithttp.NewServer(
 			svcEndpoints.GetByID,
 			decodeGetByIDRequest,
@@ -74,7 +126,7 @@ func NewGinService(
 		).ServeHTTP(w, r)
 	}))
 
-	router.PUT("/accounts/:id", gin.WrapF(func(w http.ResponseWriter, r *http.Request) {
+	accounts.PUT("/:id", gin.WrapF(func(w http.ResponseWriter, r *http.Request) {
 		kithttp.NewServer(
 			svcEndpoints.Update,
 			decodeUpdateRequest(logger),
@@ -83,7 +135,16 @@ func NewGinService(
 		).ServeHTTP(w, r)
 	}))
 
-	router.DELETE("/accounts/:id", gin.WrapF(func(w http.ResponseWriter, r *http.Request) {
+	accounts.PATCH("/:id", gin.WrapF(func(w http.ResponseWriter, r *http.Request) {
+		kithttp.NewServer(
+			svcEndpoints.Patch,
+			decodePatchRequest(logger),
+			encodeResponse(logger),
+			options...,
+		).ServeHTTP(w, r)
+	}))
+
+	accounts.DELETE("/:id", gin.WrapF(func(w http.ResponseWriter, r *http.Request) {
 		kithttp.NewServer(
 			svcEndpoints.Delete,
 			decodeDeleteRequest,
@@ -92,6 +153,144 @@ func NewGinService(
 		).ServeHTTP(w, r)
 	}))
 
+	// Batch operations are custom methods on the collection, so they are
+	// routed on a parameter that holds the colon and the method name.
+	batchServers := map[string]*kithttp.Server{
+		":batchCreate": kithttp.NewServer(svcEndpoints.BatchCreate, decodeBatchCreateRequest(logger), encodeResponse(logger), options...),
+		":batchUpdate": kithttp.NewServer(svcEndpoints.BatchUpdate, decodeBatchUpdateRequest(logger), encodeResponse(logger), options...),
+		":batchDelete": kithttp.NewServer(svcEndpoints.BatchDelete, decodeBatchDeleteRequest(logger), encodeResponse(logger), options...),
+		":tag":         kithttp.NewServer(svcEndpoints.Tag, decodeTagRequest(logger), encodeResponse(logger), options...),
+		":untag":       kithttp.NewServer(svcEndpoints.Untag, decodeTagRequest(logger), encodeResponse(logger), options...),
+	}
+
+	router.POST("/accounts:method", authMiddleware, func(c *gin.Context) {
+		server, ok := batchServers[c.Param("method")]
+		if !ok {
+			httperror.EncodeError(c.Request.Context(), model.Errorf(model.ErrNotFound, "no method %s on accounts", c.Param("method")), c.Writer)
+			return
+		}
+		server.ServeHTTP(c.Writer, c.Request)
+	})
+
+	accounts.POST("/:id/reveal", gin.WrapF(func(w http.ResponseWriter, r *http.Request) {
+		kithttp.NewServer(
+			svcEndpoints.Reveal,
+			decodeRevealRequest,
+			encodeResponse(logger),
+			options...,
+		).ServeHTTP(w, r)
+	}))
+
+	accounts.POST("/:id/restore", gin.WrapF(func(w http.ResponseWriter, r *http.Request) {
+		kithttp.NewServer(
+			svcEndpoints.Restore,
+			decodeRestoreRequest,
+			encodeResponse(logger),
+			options...,
+		).ServeHTTP(w, r)
+	}))
+
+	accounts.GET("/:id/history", gin.WrapF(func(w http.ResponseWriter, r *http.Request) {
+		kithttp.NewServer(
+			svcEndpoints.History,
+			decodeCredentialHistoryRequest,
+			encodeResponse(logger),
+			options...,
+		).ServeHTTP(w, r)
+	}))
+
+	accounts.POST("/:id/rollback/:version", gin.WrapF(func(w http.ResponseWriter, r *http.Request) {
+		kithttp.NewServer(
+			svcEndpoints.Rollback,
+			decodeRollbackRequest,
+			encodeResponse(logger),
+			options...,
+		).ServeHTTP(w, r)
+	}))
+
+	accounts.GET("/:id/status-history", gin.WrapF(func(w http.ResponseWriter, r *http.Request) {
+		kithttp.NewServer(
+			svcEndpoints.StatusHistory,
+			decodeStatusHistoryRequest,
+			encodeResponse(logger),
+			options...,
+		).ServeHTTP(w, r)
+	}))
+
+	accounts.GET("/:id/cookies", gin.WrapF(func(w http.ResponseWriter, r *http.Request) {
+		kithttp.NewServer(
+			svcEndpoints.GetCookies,
+			decodeGetCookiesRequest,
+			encodeCookiesResponse(logger),
+			options...,
+		).ServeHTTP(w, r)
+	}))
+
+	accounts.PUT("/:id/cookies", gin.WrapF(func(w http.ResponseWriter, r *http.Request) {
+		kithttp.NewServer(
+			svcEndpoints.ReplaceCookies,
+			decodeSetCookiesRequest(logger),
+			encodeResponse(logger),
+			options...,
+		).ServeHTTP(w, r)
+	}))
+
+	accounts.PATCH("/:id/cookies", gin.WrapF(func(w http.ResponseWriter, r *http.Request) {
+		kithttp.NewServer(
+			svcEndpoints.MergeCookies,
+			decodeSetCookiesRequest(logger),
+			encodeResponse(logger),
+			options...,
+		).ServeHTTP(w, r)
+	}))
+
+	accounts.POST("/lease", gin.WrapF(func(w http.ResponseWriter, r *http.Request) {
+		kithttp.NewServer(
+			svcEndpoints.Lease,
+			decodeLeaseRequest(logger),
+			encodeResponse(logger),
+			options...,
+		).ServeHTTP(w, r)
+	}))
+
+	accounts.POST("/:id/lease/renew", gin.WrapF(func(w http.ResponseWriter, r *http.Request) {
+		kithttp.NewServer(
+			svcEndpoints.RenewLease,
+			decodeRenewLeaseRequest(logger),
+			encodeResponse(logger),
+			options...,
+		).ServeHTTP(w, r)
+	}))
+
+	accounts.POST("/:id/lease/release", gin.WrapF(func(w http.ResponseWriter, r *http.Request) {
+		kithttp.NewServer(
+			svcEndpoints.ReleaseLease,
+			decodeReleaseLeaseRequest(logger),
+			encodeResponse(logger),
+			options...,
+		).ServeHTTP(w, r)
+	}))
+
+	router.GET("/tags", authMiddleware, gin.WrapF(func(w http.ResponseWriter, r *http.Request) {
+		kithttp.NewServer(
+			svcEndpoints.GetTags,
+			decodeGetTagsRequest,
+			encodeResponse(logger),
+			options...,
+		).ServeHTTP(w, r)
+	}))
+
+	admin := router.Group("/admin", authMiddleware)
+
+	admin.POST("/keys/rewrap", gin.WrapF(func(w http.ResponseWriter, r *http.Request) {
+		kithttp.NewServer(
+			svcEndpoints.RewrapKeys,
+			decodeRewrapKeysRequest,
+			encodeResponse(logger),
+			options...,
+		).ServeHTTP(w, r)
+	}))
+
 	return router
 }
 
@@ -126,7 +325,7 @@ func decodeCreateRequest(logger *logrus.Logger) kithttp.DecodeRequestFunc {
 				"error":    err,
 			}).Error("decoding from json failed")
 
-			return nil, err
+			return nil, model.Errorf(model.ErrInvalidArgument, "error decoding request: %w", err)
 		}
 
 		return req, nil
@@ -134,21 +333,195 @@ func decodeCreateRequest(logger *logrus.Logger) kithttp.DecodeRequestFunc {
 
 }
 
-func decodeGetByIDRequest(_ context.Context, r *http.Request) (interface{}, error) {
+// decodeIDParam returns the id path parameter, which must be a UUID.
+func decodeIDParam(r *http.Request) (string, error) {
 	ginCtx, ok := r.Context().Value(GinContextKey{}).(*gin.Context)
 	if !ok {
-		return nil, errors.New("could not retrieve gin.Context")
+		return "", errors.New("could not retrieve gin.Context")
 	}
 
 	id := ginCtx.Param("id")
 	if id == "" {
-		return nil, ErrBadRouting
+		return "", ErrBadRouting
+	}
+
+	if _, err := uuid.Parse(id); err != nil {
+		return "", model.Errorf(model.ErrInvalidArgument, "invalid account id %s: %w", id, err)
+	}
+	return id, nil
+}
+
+func decodeGetByIDRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	id, err := decodeIDParam(r)
+	if err != nil {
+		return nil, err
 	}
 	return GetByIDRequest{ID: id}, nil
 }
 
 func decodeGetAllRequest(_ context.Context, r *http.Request) (interface{}, error) {
-	return GetAllRequest{}, nil
+	filter, err := decodeAccountFilter(r.URL.Query())
+	if err != nil {
+		return nil, err
+	}
+
+	return GetAllRequest{Filter: filter}, nil
+}
+
+func decodeTrashRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	filter, err := decodeAccountFilter(r.URL.Query())
+	if err != nil {
+		return nil, err
+	}
+
+	return TrashRequest{Filter: filter}, nil
+}
+
+func decodeAccountFilter(query url.Values) (model.AccountFilter, error) {
+	filter := model.AccountFilter{
+		AccountType: query.Get("account_type"),
+		Status:      query.Get("status"),
+		Email:       query.Get("email"),
+		Cursor:      query.Get("cursor"),
+	}
+
+	var err error
+	filter.Sort, err = model.ParseAccountSort(query.Get("sort"))
+	if err != nil {
+		return model.AccountFilter{}, err
+	}
+
+	if createdAfter := query.Get("created_after"); createdAfter != "" {
+		filter.CreatedAfter, err = time.Parse(time.RFC3339, createdAfter)
+		if err != nil {
+			return model.AccountFilter{}, model.Errorf(model.ErrInvalidArgument, "error parsing created_after: %w", err)
+		}
+	}
+	if createdBefore := query.Get("created_before"); createdBefore != "" {
+		filter.CreatedBefore, err = time.Parse(time.RFC3339, createdBefore)
+		if err != nil {
+			return model.AccountFilter{}, model.Errorf(model.ErrInvalidArgument, "error parsing created_before: %w", err)
+		}
+	}
+	if cookiesExpiringBefore := query.Get("cookies_expiring_before"); cookiesExpiringBefore != "" {
+		filter.CookiesExpiringBefore, err = time.Parse(time.RFC3339, cookiesExpiringBefore)
+		if err != nil {
+			return model.AccountFilter{}, model.Errorf(model.ErrInvalidArgument, "error parsing cookies_expiring_before: %w", err)
+		}
+	}
+	if len(query["tag"]) > 0 {
+		filter.Tags = query["tag"]
+		filter.TagMatch, err = model.ParseTagMatch(query.Get("tag_match"))
+		if err != nil {
+			return model.AccountFilter{}, err
+		}
+	}
+	for _, attribute := range query["attribute"] {
+		name, value, err := model.ParseAttributeFilter(attribute)
+		if err != nil {
+			return model.AccountFilter{}, err
+		}
+		if filter.Attributes == nil {
+			filter.Attributes = make(map[string]string)
+		}
+		filter.Attributes[name] = value
+	}
+	if limit := query.Get("limit"); limit != "" {
+		filter.Limit, err = strconv.Atoi(limit)
+		if err != nil {
+			return model.AccountFilter{}, model.Errorf(model.ErrInvalidArgument, "error parsing limit: %w", err)
+		}
+	}
+
+	return filter, nil
+}
+
+func decodeTagRequest(logger *logrus.Logger) kithttp.DecodeRequestFunc {
+	return func(_ context.Context, r *http.Request) (interface{}, error) {
+		var req TagRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.WithFields(logrus.Fields{
+				"package":  "account",
+				"function": "decodeTagRequest",
+				"error":    err,
+			}).Error("decoding from json failed")
+
+			return nil, model.Errorf(model.ErrInvalidArgument, "error decoding request: %w", err)
+		}
+
+		return req, nil
+	}
+}
+
+func decodeGetTagsRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return GetTagsRequest{}, nil
+}
+
+func decodeImportRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	query := r.URL.Query()
+
+	var options model.ImportOptions
+	var err error
+	options.Delimiter, err = model.ParseDelimiter(query.Get("delimiter"))
+	if err != nil {
+		return nil, err
+	}
+	options.Columns, err = model.ParseImportColumns(query.Get("columns"))
+	if err != nil {
+		return nil, err
+	}
+	options.Header, err = parseBoolQuery(query, "header", false)
+	if err != nil {
+		return nil, err
+	}
+	options.DryRun, err = parseBoolQuery(query, "dry_run", false)
+	if err != nil {
+		return nil, err
+	}
+
+	return ImportRequest{Options: options, Body: r.Body}, nil
+}
+
+func decodeExportRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	query := r.URL.Query()
+
+	if format := query.Get("format"); format != "" && format != "csv" {
+		return nil, model.Errorf(model.ErrInvalidArgument, "unsupported export format %s", format)
+	}
+
+	filter, err := decodeAccountFilter(query)
+	if err != nil {
+		return nil, err
+	}
+
+	req := ExportRequest{Filter: filter}
+	req.Columns, err = model.ParseExportColumns(query.Get("columns"))
+	if err != nil {
+		return nil, err
+	}
+	req.Delimiter, err = model.ParseDelimiter(query.Get("delimiter"))
+	if err != nil {
+		return nil, err
+	}
+	req.Header, err = parseBoolQuery(query, "header", true)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+func parseBoolQuery(query url.Values, name string, defaultValue bool) (bool, error) {
+	value := query.Get(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, model.Errorf(model.ErrInvalidArgument, "error parsing %s: %w", name, err)
+	}
+	return parsed, nil
 }
 
 func decodeUpdateRequest(logger *logrus.Logger) kithttp.DecodeRequestFunc {
@@ -171,7 +544,7 @@ func decodeUpdateRequest(logger *logrus.Logger) kithttp.DecodeRequestFunc {
 
 		idUUID, err := uuid.Parse(idStr)
 		if err != nil {
-			return nil, err
+			return nil, model.Errorf(model.ErrInvalidArgument, "invalid account id %s: %w", idStr, err)
 		}
 
 		var req UpdateRequest
@@ -181,43 +554,323 @@ func decodeUpdateRequest(logger *logrus.Logger) kithttp.DecodeRequestFunc {
 				"function": "decodeUpdateRequest",
 				"error":    err,
 			}).Error("decoding from json failed")
-			return nil, err
+			return nil, model.Errorf(model.ErrInvalidArgument, "error decoding request: %w", err)
 		}
 
-		account := model.Account{
-			ID:                    idUUID,
-			Name:                  req.Account.Name,
-			AccountType:           req.Account.AccountType,
-			Login:                 req.Account.Login,
-			Password:              req.Account.Password,
-			Email:                 req.Account.Email,
-			EmailPassword:         req.Account.EmailPassword,
-			RecoveryEmail:         req.Account.RecoveryEmail,
-			RecoveryEmailPassword: req.Account.RecoveryEmailPassword,
-			Cookie:                req.Account.Cookie,
-			Status:                req.Account.Status,
+		version, err := decodeIfMatch(r)
+		if err != nil {
+			return nil, err
 		}
 
+		account := model.Account{ID: idUUID, Version: version}.Replace(req.Account)
+
 		return account, nil
 	}
 }
 
+func decodePatchRequest(logger *logrus.Logger) kithttp.DecodeRequestFunc {
+	return func(_ context.Context, r *http.Request) (interface{}, error) {
+		id, err := decodeIDParam(r)
+		if err != nil {
+			return nil, err
+		}
+
+		version, err := decodeIfMatch(r)
+		if err != nil {
+			return nil, err
+		}
+
+		patch, err := io.ReadAll(r.Body)
+		if err != nil {
+			logger.WithFields(logrus.Fields{
+				"package":  "account",
+				"function": "decodePatchRequest",
+				"error":    err,
+			}).Error("reading request body failed")
+
+			return nil, err
+		}
+
+		return PatchRequest{ID: id, Version: version, Patch: patch}, nil
+	}
+}
+
 func decodeDeleteRequest(_ context.Context, r *http.Request) (interface{}, error) {
-	var req DeleteRequest
+	id, err := decodeIDParam(r)
+	if err != nil {
+		return nil, err
+	}
+	version, err := decodeIfMatch(r)
+	if err != nil {
+		return nil, err
+	}
+	return DeleteRequest{ID: id, Version: version}, nil
+}
+
+// decodeIfMatch returns the account version from the If-Match header, which
+// requests changing an account must carry.
+func decodeIfMatch(r *http.Request) (int64, error) {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		return 0, model.NewError(model.ErrPreconditionRequired, "If-Match header is required")
+	}
+
+	version, err := strconv.ParseInt(strings.Trim(ifMatch, `"`), 10, 64)
+	if err != nil {
+		return 0, model.Errorf(model.ErrPreconditionFailed, "If-Match %s does not match any account version", ifMatch)
+	}
+	return version, nil
+}
+
+func versionHeaders(version int64) http.Header {
+	return http.Header{"ETag": []string{strconv.Quote(strconv.FormatInt(version, 10))}}
+}
+
+func decodeBatchCreateRequest(logger *logrus.Logger) kithttp.DecodeRequestFunc {
+	return func(_ context.Context, r *http.Request) (interface{}, error) {
+		var req BatchCreateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.WithFields(logrus.Fields{
+				"package":  "account",
+				"function": "decodeBatchCreateRequest",
+				"error":    err,
+			}).Error("decoding from json failed")
+
+			return nil, model.Errorf(model.ErrInvalidArgument, "error decoding request: %w", err)
+		}
+
+		var err error
+		req.Mode, err = model.ParseBatchMode(string(req.Mode))
+		if err != nil {
+			return nil, err
+		}
+
+		return req, nil
+	}
+}
+
+func decodeBatchUpdateRequest(logger *logrus.Logger) kithttp.DecodeRequestFunc {
+	return func(_ context.Context, r *http.Request) (interface{}, error) {
+		var req BatchUpdateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.WithFields(logrus.Fields{
+				"package":  "account",
+				"function": "decodeBatchUpdateRequest",
+				"error":    err,
+			}).Error("decoding from json failed")
+
+			return nil, model.Errorf(model.ErrInvalidArgument, "error decoding request: %w", err)
+		}
+
+		var err error
+		req.Mode, err = model.ParseBatchMode(string(req.Mode))
+		if err != nil {
+			return nil, err
+		}
+
+		return req, nil
+	}
+}
+
+func decodeBatchDeleteRequest(logger *logrus.Logger) kithttp.DecodeRequestFunc {
+	return func(_ context.Context, r *http.Request) (interface{}, error) {
+		var req BatchDeleteRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.WithFields(logrus.Fields{
+				"package":  "account",
+				"function": "decodeBatchDeleteRequest",
+				"error":    err,
+			}).Error("decoding from json failed")
+
+			return nil, model.Errorf(model.ErrInvalidArgument, "error decoding request: %w", err)
+		}
+
+		var err error
+		req.Mode, err = model.ParseBatchMode(string(req.Mode))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, accountVersion := range req.Accounts {
+			if _, err := uuid.Parse(accountVersion.ID); err != nil {
+				return nil, model.Errorf(model.ErrInvalidArgument, "invalid account id %s: %w", accountVersion.ID, err)
+			}
+		}
+
+		return req, nil
+	}
+}
+
+func decodeRevealRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	id, err := decodeIDParam(r)
+	if err != nil {
+		return nil, err
+	}
+	return RevealRequest{ID: id}, nil
+}
+
+func decodeRestoreRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	id, err := decodeIDParam(r)
+	if err != nil {
+		return nil, err
+	}
+	return RestoreRequest{ID: id}, nil
+}
+
+func decodeStatusHistoryRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	id, err := decodeIDParam(r)
+	if err != nil {
+		return nil, err
+	}
+	return StatusHistoryRequest{ID: id}, nil
+}
+
+func decodeCredentialHistoryRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	id, err := decodeIDParam(r)
+	if err != nil {
+		return nil, err
+	}
+	return CredentialHistoryRequest{ID: id}, nil
+}
+
+func decodeRollbackRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	id, err := decodeIDParam(r)
+	if err != nil {
+		return nil, err
+	}
 
 	ginCtx, ok := r.Context().Value(GinContextKey{}).(*gin.Context)
 	if !ok {
 		return nil, errors.New("could not retrieve gin.Context")
 	}
 
-	idStr := ginCtx.Param("id")
-	if idStr == "" {
-		return nil, ErrBadRouting
+	target, err := strconv.ParseInt(ginCtx.Param("version"), 10, 64)
+	if err != nil {
+		return nil, model.Errorf(model.ErrInvalidArgument, "invalid version %s", ginCtx.Param("version"))
 	}
 
-	req.ID = idStr
+	version, err := decodeIfMatch(r)
+	if err != nil {
+		return nil, err
+	}
 
-	return req, nil
+	return RollbackRequest{ID: id, Version: version, Target: target}, nil
+}
+
+func decodeGetCookiesRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	id, err := decodeIDParam(r)
+	if err != nil {
+		return nil, err
+	}
+	format, err := model.ParseCookieFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		return nil, err
+	}
+	return GetCookiesRequest{ID: id, Format: format}, nil
+}
+
+func decodeSetCookiesRequest(logger *logrus.Logger) kithttp.DecodeRequestFunc {
+	return func(_ context.Context, r *http.Request) (interface{}, error) {
+		id, err := decodeIDParam(r)
+		if err != nil {
+			return nil, err
+		}
+
+		version, err := decodeIfMatch(r)
+		if err != nil {
+			return nil, err
+		}
+
+		format, err := model.ParseCookieFormat(r.URL.Query().Get("format"))
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			logger.WithFields(logrus.Fields{
+				"package":  "account",
+				"function": "decodeSetCookiesRequest",
+				"error":    err,
+			}).Error("reading request body failed")
+
+			return nil, err
+		}
+
+		jar, err := model.ParseCookieJar(body, format)
+		if err != nil {
+			return nil, err
+		}
+
+		return SetCookiesRequest{ID: id, Version: version, Jar: jar}, nil
+	}
+}
+
+func decodeLeaseRequest(logger *logrus.Logger) kithttp.DecodeRequestFunc {
+	return func(_ context.Context, r *http.Request) (interface{}, error) {
+		var req LeaseRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.WithFields(logrus.Fields{
+				"package":  "account",
+				"function": "decodeLeaseRequest",
+				"error":    err,
+			}).Error("decoding from json failed")
+
+			return nil, model.Errorf(model.ErrInvalidArgument, "error decoding request: %w", err)
+		}
+
+		return req, nil
+	}
+}
+
+func decodeRenewLeaseRequest(logger *logrus.Logger) kithttp.DecodeRequestFunc {
+	return func(_ context.Context, r *http.Request) (interface{}, error) {
+		id, err := decodeIDParam(r)
+		if err != nil {
+			return nil, err
+		}
+
+		var req RenewLeaseRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.WithFields(logrus.Fields{
+				"package":  "account",
+				"function": "decodeRenewLeaseRequest",
+				"error":    err,
+			}).Error("decoding from json failed")
+
+			return nil, model.Errorf(model.ErrInvalidArgument, "error decoding request: %w", err)
+		}
+		req.ID = id
+
+		return req, nil
+	}
+}
+
+func decodeReleaseLeaseRequest(logger *logrus.Logger) kithttp.DecodeRequestFunc {
+	return func(_ context.Context, r *http.Request) (interface{}, error) {
+		id, err := decodeIDParam(r)
+		if err != nil {
+			return nil, err
+		}
+
+		var req ReleaseLeaseRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.WithFields(logrus.Fields{
+				"package":  "account",
+				"function": "decodeReleaseLeaseRequest",
+				"error":    err,
+			}).Error("decoding from json failed")
+
+			return nil, model.Errorf(model.ErrInvalidArgument, "error decoding request: %w", err)
+		}
+		req.ID = id
+
+		return req, nil
+	}
+}
+
+func decodeRewrapKeysRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return RewrapKeysRequest{}, nil
 }
 
 func encodeResponse(logger *logrus.Logger) kithttp.EncodeResponseFunc {
@@ -229,10 +882,18 @@ func encodeResponse(logger *logrus.Logger) kithttp.EncodeResponseFunc {
 
 		if e, ok := response.(errorer); ok && e.error() != nil {
 			logger.Errorf("Handling error: %v", e.error())
-			encodeErrorResponse(ctx, e.error(), w)
+			httperror.EncodeError(ctx, e.error(), w)
 			return nil
 		}
 
+		if headerer, ok := response.(kithttp.Headerer); ok {
+			for key, values := range headerer.Headers() {
+				for _, value := range values {
+					w.Header().Add(key, value)
+				}
+			}
+		}
+
 		w.Header().Set("Content-Type", "application/json; charset=utf-8")
 		if err := json.NewEncoder(w).Encode(response); err != nil {
 			logger.Errorf("Error encoding JSON response: %v", err)
@@ -245,24 +906,95 @@ func encodeResponse(logger *logrus.Logger) kithttp.EncodeResponseFunc {
 
 }
 
-type errorer interface {
-	error() error
-}
+// encodeCookiesResponse writes the cookie jar itself in the requested
+// format.
+func encodeCookiesResponse(logger *logrus.Logger) kithttp.EncodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		if e, ok := response.(errorer); ok && e.error() != nil {
+			logger.Errorf("Handling error: %v", e.error())
+			httperror.EncodeError(ctx, e.error(), w)
+			return nil
+		}
+
+		resp := response.(GetCookiesResponse)
+		body, err := resp.Jar.Format(resp.Format)
+		if err != nil {
+			logger.Errorf("Error encoding cookie jar: %v", err)
+			httperror.EncodeError(ctx, err, w)
+			return err
+		}
 
-func encodeErrorResponse(_ context.Context, err error, w http.ResponseWriter) {
-	if err == nil {
-		panic("encodeError with nil error")
+		for key, values := range resp.Headers() {
+			for _, value := range values {
+				w.Header().Add(key, value)
+			}
+		}
+		if resp.Format == model.CookieFormatNetscape {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		} else {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		}
+		_, err = w.Write(body)
+		return err
 	}
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	w.WriteHeader(codeFrom(err))
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"error": err.Error(),
-	})
 }
 
-func codeFrom(err error) int {
-	switch err {
-	default:
-		return http.StatusInternalServerError
+// encodeExportResponse streams the export as CSV. Errors are still sent as
+// JSON until the first bytes of the CSV are written, after that the
+// response is cut short.
+func encodeExportResponse(logger *logrus.Logger) kithttp.EncodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		if e, ok := response.(errorer); ok && e.error() != nil {
+			httperror.EncodeError(ctx, e.error(), w)
+			return nil
+		}
+
+		export := response.(ExportResponse)
+
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="accounts.csv"`)
+
+		writer := &startedWriter{writer: w}
+		csvWriter := model.NewAccountCSVWriter(writer, export.Columns, export.Delimiter)
+
+		err := func() error {
+			if export.Header {
+				if err := csvWriter.WriteHeader(); err != nil {
+					return err
+				}
+			}
+			if err := export.export(csvWriter.Write); err != nil {
+				return err
+			}
+			return csvWriter.Flush()
+		}()
+		if err != nil {
+			logger.WithFields(logrus.Fields{
+				"package":  "account",
+				"function": "encodeExportResponse",
+				"error":    err,
+			}).Error("exporting accounts failed")
+
+			if !writer.started {
+				w.Header().Del("Content-Disposition")
+				httperror.EncodeError(ctx, err, w)
+			}
+		}
+		return nil
 	}
 }
+
+// startedWriter records whether anything was written to the response.
+type startedWriter struct {
+	writer  io.Writer
+	started bool
+}
+
+func (startedWriter *startedWriter) Write(p []byte) (int, error) {
+	startedWriter.started = true
+	return startedWriter.writer.Write(p)
+}
+
+type errorer interface {
+	error() error
+}
@@ -0,0 +1,341 @@
+package model
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Account columns of CSV import and export. Id, created_at and version are
+// only exported.
+const (
+	ColumnID                    = "id"
+	ColumnName                  = "name"
+	ColumnAccountType           = "account_type"
+	ColumnLogin                 = "login"
+	ColumnPassword              = "password"
+	ColumnEmail                 = "email"
+	ColumnEmailPassword         = "email_password"
+	ColumnRecoveryEmail         = "recovery_email"
+	ColumnRecoveryEmailPassword = "recovery_email_password"
+	ColumnCookie                = "cookie"
+	ColumnStatus                = "status"
+	ColumnCreatedAt             = "created_at"
+	ColumnVersion               = "version"
+
+	// ColumnSkip ignores a column on import.
+	ColumnSkip = "-"
+)
+
+var (
+	importColumns = []string{ColumnSkip, ColumnName, ColumnAccountType, ColumnLogin, ColumnPassword, ColumnEmail,
+		ColumnEmailPassword, ColumnRecoveryEmail, ColumnRecoveryEmailPassword, ColumnCookie, ColumnStatus}
+	exportColumns = []string{ColumnID, ColumnName, ColumnAccountType, ColumnLogin, ColumnPassword, ColumnEmail,
+		ColumnEmailPassword, ColumnRecoveryEmail, ColumnRecoveryEmailPassword, ColumnCookie, ColumnStatus,
+		ColumnCreatedAt, ColumnVersion}
+)
+
+// secretColumns are the columns of the fields in SecretFieldNames.
+var secretColumns = map[string]bool{
+	ColumnPassword:              true,
+	ColumnEmailPassword:         true,
+	ColumnRecoveryEmailPassword: true,
+	ColumnCookie:                true,
+}
+
+// ImportOptions tell how to read an account CSV. Without Columns the first
+// line is a header naming them, otherwise Header skips the first line.
+type ImportOptions struct {
+	Delimiter rune
+	Columns   []string
+	Header    bool
+	DryRun    bool
+}
+
+// ImportLineResult is the outcome of one line of an import. ID is empty on
+// a dry run.
+type ImportLineResult struct {
+	Line int
+	ID   string
+	Err  error
+}
+
+type ImportReport struct {
+	DryRun   bool
+	Imported int
+	Failed   int
+	Lines    []ImportLineResult
+}
+
+// ParseImportColumns parses a comma separated list of the columns of an
+// import.
+func ParseImportColumns(columns string) ([]string, error) {
+	if columns == "" {
+		return nil, nil
+	}
+	return parseColumns(columns, importColumns)
+}
+
+// ParseExportColumns parses a comma separated list of the columns of an
+// export. All columns are exported by default.
+func ParseExportColumns(columns string) ([]string, error) {
+	if columns == "" {
+		return exportColumns, nil
+	}
+	return parseColumns(columns, exportColumns)
+}
+
+func parseColumns(columns string, allowed []string) ([]string, error) {
+	names := strings.Split(columns, ",")
+	for i, name := range names {
+		names[i] = strings.TrimSpace(name)
+		if err := checkColumn(names[i], allowed); err != nil {
+			return nil, err
+		}
+	}
+	return names, nil
+}
+
+func checkColumn(name string, allowed []string) error {
+	for _, column := range allowed {
+		if name == column {
+			return nil
+		}
+	}
+	return Errorf(ErrInvalidArgument, "unknown column %q", name)
+}
+
+// ParseDelimiter parses a CSV delimiter, which must be a single character.
+// The default is a comma.
+func ParseDelimiter(delimiter string) (rune, error) {
+	if delimiter == "" {
+		return ',', nil
+	}
+	if delimiter == `\t` {
+		return '\t', nil
+	}
+
+	r, size := utf8.DecodeRuneInString(delimiter)
+	if size != len(delimiter) || r == '"' || r == '\r' || r == '\n' || r == utf8.RuneError {
+		return 0, Errorf(ErrInvalidArgument, "invalid delimiter %q", delimiter)
+	}
+	return r, nil
+}
+
+// AccountCSVReader reads accounts to create from CSV, one line at a time.
+type AccountCSVReader struct {
+	reader  *csv.Reader
+	options ImportOptions
+	started bool
+}
+
+func NewAccountCSVReader(r io.Reader, options ImportOptions) *AccountCSVReader {
+	reader := csv.NewReader(r)
+	reader.Comma = options.Delimiter
+	reader.FieldsPerRecord = -1
+	// Supplier lists are not always proper CSV, a quote inside a field is
+	// kept as part of it.
+	reader.LazyQuotes = true
+	reader.ReuseRecord = true
+
+	return &AccountCSVReader{reader: reader, options: options}
+}
+
+// Read returns the next account and its line number. Errors matching
+// ErrInvalidArgument are about that line only and reading can go on, other
+// errors, including io.EOF at the end, stop the import.
+func (accountCSVReader *AccountCSVReader) Read() (int, AccountCreate, error) {
+	if !accountCSVReader.started {
+		accountCSVReader.started = true
+		if err := accountCSVReader.readHeader(); err != nil {
+			return 0, AccountCreate{}, err
+		}
+	}
+
+	record, err := accountCSVReader.reader.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return parseErr.StartLine, AccountCreate{}, Errorf(ErrInvalidArgument, "%w", parseErr.Err)
+		}
+		return 0, AccountCreate{}, err
+	}
+	line, _ := accountCSVReader.reader.FieldPos(0)
+
+	columns := accountCSVReader.options.Columns
+	if len(record) != len(columns) {
+		return line, AccountCreate{}, Errorf(ErrInvalidArgument, "expected %d fields, got %d", len(columns), len(record))
+	}
+
+	// Only fields that identify the account are trimmed, secrets are taken
+	// byte for byte since spaces may be part of them.
+	var accountCreate AccountCreate
+	for i, column := range columns {
+		field := accountCreate.column(column)
+		switch {
+		case field == nil:
+		case secretColumns[column]:
+			*field = record[i]
+		default:
+			*field = strings.TrimSpace(record[i])
+		}
+	}
+
+	return line, accountCreate, validateImported(accountCreate)
+}
+
+func (accountCSVReader *AccountCSVReader) readHeader() error {
+	if len(accountCSVReader.options.Columns) > 0 && !accountCSVReader.options.Header {
+		return nil
+	}
+
+	header, err := accountCSVReader.reader.Read()
+	if err == io.EOF {
+		return Errorf(ErrInvalidArgument, "csv is empty")
+	}
+	if err != nil {
+		return Errorf(ErrInvalidArgument, "error reading csv header: %w", err)
+	}
+	if len(accountCSVReader.options.Columns) > 0 {
+		return nil
+	}
+
+	columns := make([]string, len(header))
+	for i, name := range header {
+		columns[i] = strings.ToLower(strings.TrimSpace(name))
+		if err := checkColumn(columns[i], importColumns); err != nil {
+			return err
+		}
+	}
+	accountCSVReader.options.Columns = columns
+
+	return nil
+}
+
+func validateImported(accountCreate AccountCreate) error {
+	if accountCreate.Login == "" && accountCreate.Email == "" {
+		return NewError(ErrInvalidArgument, "login or email is required")
+	}
+	for _, email := range []string{accountCreate.Email, accountCreate.RecoveryEmail} {
+		if email != "" && !strings.Contains(email, "@") {
+			return Errorf(ErrInvalidArgument, "invalid email %q", email)
+		}
+	}
+	return nil
+}
+
+// AccountCSVWriter writes accounts as CSV with the given columns.
+type AccountCSVWriter struct {
+	writer  *csv.Writer
+	columns []string
+	record  []string
+}
+
+func NewAccountCSVWriter(w io.Writer, columns []string, delimiter rune) *AccountCSVWriter {
+	writer := csv.NewWriter(w)
+	writer.Comma = delimiter
+
+	return &AccountCSVWriter{writer: writer, columns: columns, record: make([]string, len(columns))}
+}
+
+func (accountCSVWriter *AccountCSVWriter) WriteHeader() error {
+	return accountCSVWriter.writer.Write(accountCSVWriter.columns)
+}
+
+func (accountCSVWriter *AccountCSVWriter) Write(account Account) error {
+	for i, column := range accountCSVWriter.columns {
+		accountCSVWriter.record[i] = account.columnValue(column)
+	}
+	return accountCSVWriter.writer.Write(accountCSVWriter.record)
+}
+
+func (accountCSVWriter *AccountCSVWriter) Flush() error {
+	accountCSVWriter.writer.Flush()
+	return accountCSVWriter.writer.Error()
+}
+
+// SecretColumns returns the secret columns among columns that are set on
+// the account.
+func (account Account) SecretColumns(columns []string) []string {
+	var secrets []string
+	for _, column := range columns {
+		if field := account.column(column); secretColumns[column] && field != nil && *field != "" {
+			secrets = append(secrets, column)
+		}
+	}
+	return secrets
+}
+
+func (accountCreate *AccountCreate) column(name string) *string {
+	switch name {
+	case ColumnName:
+		return &accountCreate.Name
+	case ColumnAccountType:
+		return &accountCreate.AccountType
+	case ColumnLogin:
+		return &accountCreate.Login
+	case ColumnPassword:
+		return &accountCreate.Password
+	case ColumnEmail:
+		return &accountCreate.Email
+	case ColumnEmailPassword:
+		return &accountCreate.EmailPassword
+	case ColumnRecoveryEmail:
+		return &accountCreate.RecoveryEmail
+	case ColumnRecoveryEmailPassword:
+		return &accountCreate.RecoveryEmailPassword
+	case ColumnCookie:
+		return &accountCreate.Cookie
+	case ColumnStatus:
+		return &accountCreate.Status
+	default:
+		return nil
+	}
+}
+
+func (account Account) columnValue(name string) string {
+	switch name {
+	case ColumnID:
+		return account.ID.String()
+	case ColumnCreatedAt:
+		return account.CreatedAt.Format(time.RFC3339)
+	case ColumnVersion:
+		return fmt.Sprint(account.Version)
+	}
+
+	if field := account.column(name); field != nil {
+		return *field
+	}
+	return ""
+}
+
+func (account *Account) column(name string) *string {
+	switch name {
+	case ColumnName:
+		return &account.Name
+	case ColumnAccountType:
+		return &account.AccountType
+	case ColumnLogin:
+		return &account.Login
+	case ColumnPassword:
+		return &account.Password
+	case ColumnEmail:
+		return &account.Email
+	case ColumnEmailPassword:
+		return &account.EmailPassword
+	case ColumnRecoveryEmail:
+		return &account.RecoveryEmail
+	case ColumnRecoveryEmailPassword:
+		return &account.RecoveryEmailPassword
+	case ColumnCookie:
+		return &account.Cookie
+	case ColumnStatus:
+		return &account.Status
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,169 @@
+package model
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	SortByCreatedAt = "created_at"
+	SortByName      = "name"
+
+	DefaultAccountPageSize = 50
+	MaxAccountPageSize     = 500
+)
+
+var (
+	ErrInvalidSort   = NewError(ErrInvalidArgument, "invalid sort")
+	ErrInvalidCursor = NewError(ErrInvalidArgument, "invalid cursor")
+)
+
+// AccountFilter selects a page of accounts. CreatedAfter is inclusive,
+// CreatedBefore and CookiesExpiringBefore are exclusive, zero values do not
+// filter. Attributes match the values of non-secret attributes by name,
+// Tags match as TagMatch says. Deleted selects the accounts in the trash
+// instead of the others.
+type AccountFilter struct {
+	AccountType           string            `json:"account_type,omitempty"`
+	Status                string            `json:"status,omitempty"`
+	Email                 string            `json:"email,omitempty"`
+	CreatedAfter          time.Time         `json:"created_after,omitempty"`
+	CreatedBefore         time.Time         `json:"created_before,omitempty"`
+	CookiesExpiringBefore time.Time         `json:"cookies_expiring_before,omitempty"`
+	Attributes            map[string]string `json:"attributes,omitempty"`
+	Tags                  []string          `json:"tags,omitempty"`
+	TagMatch              TagMatch          `json:"tag_match,omitempty"`
+	Deleted               bool              `json:"deleted,omitempty"`
+	Sort                  AccountSort       `json:"sort,omitempty"`
+	Limit                 int               `json:"limit,omitempty"`
+	Cursor                string            `json:"cursor,omitempty"`
+}
+
+// PageSize returns Limit clamped to (0, MaxAccountPageSize].
+func (filter AccountFilter) PageSize() int {
+	switch {
+	case filter.Limit <= 0:
+		return DefaultAccountPageSize
+	case filter.Limit > MaxAccountPageSize:
+		return MaxAccountPageSize
+	default:
+		return filter.Limit
+	}
+}
+
+type AccountPage struct {
+	Accounts   []Account `json:"accounts"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
+
+// AccountSort orders accounts by Field and then by ID, so the order is total
+// and a page can be continued from the last account of the previous one.
+type AccountSort struct {
+	Field      string `json:"field,omitempty"`
+	Descending bool   `json:"descending,omitempty"`
+}
+
+// ParseAccountSort parses "field" or "-field" for descending order. An empty
+// string sorts by creation time.
+func ParseAccountSort(sort string) (AccountSort, error) {
+	accountSort := AccountSort{Field: SortByCreatedAt}
+	if sort == "" {
+		return accountSort, nil
+	}
+
+	field, descending := strings.CutPrefix(sort, "-")
+	switch field {
+	case SortByCreatedAt, SortByName:
+	default:
+		return AccountSort{}, fmt.Errorf("%w: %s", ErrInvalidSort, sort)
+	}
+
+	accountSort.Field = field
+	accountSort.Descending = descending
+	return accountSort, nil
+}
+
+func (accountSort AccountSort) String() string {
+	field := accountSort.Field
+	if field == "" {
+		field = SortByCreatedAt
+	}
+	if accountSort.Descending {
+		return "-" + field
+	}
+	return field
+}
+
+// Compare returns a negative number when a goes before b.
+func (accountSort AccountSort) Compare(a, b Account) int {
+	var result int
+	switch accountSort.Field {
+	case SortByName:
+		result = strings.Compare(a.Name, b.Name)
+	default:
+		result = a.CreatedAt.Compare(b.CreatedAt)
+	}
+	if result == 0 {
+		result = strings.Compare(a.ID.String(), b.ID.String())
+	}
+
+	if accountSort.Descending {
+		return -result
+	}
+	return result
+}
+
+type accountCursor struct {
+	Sort      string    `json:"sort"`
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	Name      string    `json:"name,omitempty"`
+}
+
+// NewAccountCursor returns an opaque cursor pointing right after account.
+func NewAccountCursor(account Account, accountSort AccountSort) string {
+	cursor := accountCursor{
+		Sort: accountSort.String(),
+		ID:   account.ID,
+	}
+
+	switch accountSort.Field {
+	case SortByName:
+		cursor.Name = account.Name
+	default:
+		cursor.CreatedAt = account.CreatedAt
+	}
+
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// ParseAccountCursor returns the position a cursor points after as an
+// account holding only the sort key and the ID.
+func ParseAccountCursor(cursor string, accountSort AccountSort) (Account, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return Account{}, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+
+	var parsed accountCursor
+	err = json.Unmarshal(data, &parsed)
+	if err != nil {
+		return Account{}, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+
+	if parsed.Sort != accountSort.String() {
+		return Account{}, fmt.Errorf("%w: cursor is for sort %s", ErrInvalidCursor, parsed.Sort)
+	}
+
+	return Account{
+		ID:        parsed.ID,
+		CreatedAt: parsed.CreatedAt,
+		Name:      parsed.Name,
+	}, nil
+}
@@ -0,0 +1,99 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// ApplyMergePatch applies a JSON merge patch (RFC 7396) to the account's
+// representation {"account": {...}} and returns the patched account. A null
+// member clears the field. ID and creation time cannot be patched.
+func (account Account) ApplyMergePatch(patch []byte) (Account, error) {
+	var patchDocument interface{}
+	if err := json.Unmarshal(patch, &patchDocument); err != nil {
+		return Account{}, Errorf(ErrInvalidArgument, "error decoding merge patch: %w", err)
+	}
+
+	current, err := json.Marshal(accountDocument{Account: account.update()})
+	if err != nil {
+		return Account{}, err
+	}
+	var document interface{}
+	if err := json.Unmarshal(current, &document); err != nil {
+		return Account{}, err
+	}
+
+	patched, err := json.Marshal(mergePatch(document, patchDocument))
+	if err != nil {
+		return Account{}, err
+	}
+
+	var result accountDocument
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&result); err != nil {
+		return Account{}, Errorf(ErrInvalidArgument, "error applying merge patch: %w", err)
+	}
+
+	return account.Replace(result.Account), nil
+}
+
+// Replace returns the account with every mutable field taken from
+// accountUpdate, so empty fields of accountUpdate clear the account's.
+func (account Account) Replace(accountUpdate AccountUpdate) Account {
+	account.Name = accountUpdate.Name
+	account.AccountType = accountUpdate.AccountType
+	account.Login = accountUpdate.Login
+	account.Password = accountUpdate.Password
+	account.Email = accountUpdate.Email
+	account.EmailPassword = accountUpdate.EmailPassword
+	account.RecoveryEmail = accountUpdate.RecoveryEmail
+	account.RecoveryEmailPassword = accountUpdate.RecoveryEmailPassword
+	account.Cookie = accountUpdate.Cookie
+	account.Status = accountUpdate.Status
+	account.Attributes = accountUpdate.Attributes
+	return account
+}
+
+type accountDocument struct {
+	Account AccountUpdate `json:"account"`
+}
+
+func (account Account) update() AccountUpdate {
+	return AccountUpdate{
+		Name:                  account.Name,
+		AccountType:           account.AccountType,
+		Login:                 account.Login,
+		Password:              account.Password,
+		Email:                 account.Email,
+		EmailPassword:         account.EmailPassword,
+		RecoveryEmail:         account.RecoveryEmail,
+		RecoveryEmailPassword: account.RecoveryEmailPassword,
+		Cookie:                account.Cookie,
+		Status:                account.Status,
+		Attributes:            account.Attributes,
+	}
+}
+
+// mergePatch is the MergePatch function of RFC 7396 over decoded JSON.
+func mergePatch(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+			continue
+		}
+		targetObject[name] = mergePatch(targetObject[name], value)
+	}
+
+	return targetObject
+}
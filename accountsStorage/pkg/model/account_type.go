@@ -0,0 +1,96 @@
+package model
+
+import (
+	"regexp"
+	"slices"
+	"time"
+)
+
+var accountTypeNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,63}$`)
+
+// AccountType is the definition accounts of the type are validated against.
+// Empty AllowedStatuses allow any status, without Attributes accounts may
+// have any attributes.
+type AccountType struct {
+	Name            string                         `json:"name,omitempty"`
+	Description     string                         `json:"description,omitempty"`
+	RequiredFields  []string                       `json:"required_fields,omitempty"`
+	AllowedStatuses []string                       `json:"allowed_statuses,omitempty"`
+	Attributes      map[string]AttributeDefinition `json:"attributes,omitempty"`
+	CreatedAt       time.Time                      `json:"created_at,omitempty"`
+	UpdatedAt       time.Time                      `json:"updated_at,omitempty"`
+}
+
+// AttributeDefinition declares a custom attribute of the accounts of a type.
+// Secret attributes must be flagged secret on the accounts.
+type AttributeDefinition struct {
+	Type     string `json:"type"`
+	Required bool   `json:"required,omitempty"`
+	Secret   bool   `json:"secret,omitempty"`
+}
+
+// Validate checks the definition itself.
+func (accountType AccountType) Validate() error {
+	if !accountTypeNamePattern.MatchString(accountType.Name) {
+		return Errorf(ErrInvalidArgument, "invalid account type name %q", accountType.Name)
+	}
+
+	fields := make(map[string]bool)
+	for _, field := range (Account{}).namedValues() {
+		fields[field.name] = true
+	}
+	for _, field := range accountType.RequiredFields {
+		if !fields[field] {
+			return Errorf(ErrInvalidArgument, "unknown required field %s", field)
+		}
+	}
+
+	for name, attribute := range accountType.Attributes {
+		if !attributeNamePattern.MatchString(name) {
+			return Errorf(ErrInvalidArgument, "invalid attribute name %q", name)
+		}
+		if attribute.Type == "" || !validAttributeType(attribute.Type) {
+			return Errorf(ErrInvalidArgument, "attribute %s: invalid type %q", name, attribute.Type)
+		}
+	}
+	return nil
+}
+
+// ValidateAccount fails with ErrInvalidArgument when account does not match
+// the definition.
+func (accountType AccountType) ValidateAccount(account Account) error {
+	for _, field := range account.namedValues() {
+		if field.value == "" && slices.Contains(accountType.RequiredFields, field.name) {
+			return Errorf(ErrInvalidArgument, "%s is required for account type %s", field.name, accountType.Name)
+		}
+	}
+
+	if len(accountType.AllowedStatuses) > 0 && account.Status != "" && !slices.Contains(accountType.AllowedStatuses, account.Status) {
+		return Errorf(ErrInvalidArgument, "status %s is not allowed for account type %s", account.Status, accountType.Name)
+	}
+
+	if len(accountType.Attributes) == 0 {
+		return nil
+	}
+	for name, definition := range accountType.Attributes {
+		attribute, ok := account.Attributes[name]
+		if !ok {
+			if definition.Required {
+				return Errorf(ErrInvalidArgument, "attribute %s is required for account type %s", name, accountType.Name)
+			}
+			continue
+		}
+		if attribute.Type != definition.Type && !(attribute.Type == "" && definition.Type == AttributeTypeString) {
+			return Errorf(ErrInvalidArgument, "attribute %s must be a %s for account type %s", name, definition.Type, accountType.Name)
+		}
+		if definition.Secret && !attribute.Secret {
+			return Errorf(ErrInvalidArgument, "attribute %s must be secret for account type %s", name, accountType.Name)
+		}
+	}
+	for name := range account.Attributes {
+		if _, ok := accountType.Attributes[name]; !ok {
+			return Errorf(ErrInvalidArgument, "attribute %s is not defined for account type %s", name, accountType.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package accounttype
+
+import (
+	"account_storage/pkg/model"
+	"context"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+type Endpoints struct {
+	Create    endpoint.Endpoint
+	GetAll    endpoint.Endpoint
+	GetByName endpoint.Endpoint
+	Update    endpoint.Endpoint
+	Delete    endpoint.Endpoint
+}
+
+func MakeEndpoints(s Service) Endpoints {
+	return Endpoints{
+		Create:    makeCreateEndpoint(s),
+		GetAll:    makeGetAllEndpoint(s),
+		GetByName: makeGetByNameEndpoint(s),
+		Update:    makeUpdateEndpoint(s),
+		Delete:    makeDeleteEndpoint(s),
+	}
+}
+
+func makeCreateEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(CreateRequest)
+		err := s.Create(ctx, req.AccountType)
+		return CreateResponse{Name: req.AccountType.Name, Err: err}, nil
+	}
+}
+
+func makeGetAllEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		accountTypes, err := s.GetAll(ctx)
+		return GetAllResponse{AccountTypes: accountTypes, Err: err}, nil
+	}
+}
+
+func makeGetByNameEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(GetByNameRequest)
+		accountType, err := s.GetByName(ctx, req.Name)
+		return GetByNameResponse{AccountType: accountType, Err: err}, nil
+	}
+}
+
+func makeUpdateEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(UpdateRequest)
+		err := s.Update(ctx, req.AccountType)
+		return UpdateResponse{Err: err}, nil
+	}
+}
+
+func makeDeleteEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(DeleteRequest)
+		err := s.Delete(ctx, req.Name)
+		return DeleteResponse{Err: err}, nil
+	}
+}
+
+type CreateRequest struct {
+	AccountType model.AccountType `json:"account_type"`
+}
+
+type CreateResponse struct {
+	Name string `json:"name"`
+	Err  error  `json:"error,omitempty"`
+}
+
+func (r CreateResponse) error() error { return r.Err }
+
+type GetAllRequest struct {
+}
+
+type GetAllResponse struct {
+	AccountTypes []model.AccountType `json:"account_types"`
+	Err          error               `json:"error,omitempty"`
+}
+
+func (r GetAllResponse) error() error { return r.Err }
+
+type GetByNameRequest struct {
+	Name string `json:"name"`
+}
+
+type GetByNameResponse struct {
+	AccountType model.AccountType `json:"account_type"`
+	Err         error             `json:"error,omitempty"`
+}
+
+func (r GetByNameResponse) error() error { return r.Err }
+
+// UpdateRequest replaces the definition of the account type named in the
+// path, the name in the body is ignored.
+type UpdateRequest struct {
+	AccountType model.AccountType `json:"account_type"`
+}
+
+type UpdateResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r UpdateResponse) error() error { return r.Err }
+
+type DeleteRequest struct {
+	Name string `json:"name"`
+}
+
+type DeleteResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r DeleteResponse) error() error { return r.Err }
@@ -0,0 +1,180 @@
+package accounttype
+
+import (
+	"account_storage/internal/app/store"
+	"account_storage/pkg/model"
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+type Service interface {
+	Create(ctx context.Context, accountType model.AccountType) error
+	GetAll(ctx context.Context) ([]model.AccountType, error)
+	GetByName(ctx context.Context, name string) (model.AccountType, error)
+	Update(ctx context.Context, accountType model.AccountType) error
+	Delete(ctx context.Context, name string) error
+}
+
+type service struct {
+	repository        store.AccountTypeRepository
+	accountRepository store.AccountRepository
+	logger            *logrus.Logger
+}
+
+func NewService(repository store.AccountTypeRepository, accountRepository store.AccountRepository, logger *logrus.Logger) Service {
+	return &service{
+		repository:        repository,
+		accountRepository: accountRepository,
+		logger:            logger,
+	}
+}
+
+// @Summary Register an account type
+// @Description Register the definition accounts of a type are validated against
+// @Tags account-types
+// @Accept json
+// @Produce json
+// @Param accountType body CreateRequest true "Account type to register"
+// @Success 200 {object} CreateResponse
+// @Failure 400 {object} httperror.Response "Bad Request"
+// @Failure 409 {object} httperror.Response "Account type already exists"
+// @Failure 500 {object} httperror.Response "Internal Server Error"
+// @Router /account-types [post]
+func (s *service) Create(ctx context.Context, accountType model.AccountType) error {
+	if err := accountType.Validate(); err != nil {
+		return err
+	}
+
+	err := s.repository.Create(ctx, accountType)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "accounttype",
+			"function": "Create",
+			"error":    err,
+			"name":     accountType.Name,
+		}).Error("creating account type failed")
+
+		return err
+	}
+	return nil
+}
+
+// @Summary Get all account types
+// @Description Retrieve the registered account types
+// @Tags account-types
+// @Accept json
+// @Produce json
+// @Success 200 {object} GetAllResponse "List of account types"
+// @Failure 500 {object} httperror.Response "Internal Server Error"
+// @Router /account-types [get]
+func (s *service) GetAll(ctx context.Context) ([]model.AccountType, error) {
+	accountTypes, err := s.repository.GetAll(ctx)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "accounttype",
+			"function": "GetAll",
+			"error":    err,
+		}).Error("getting all account types failed")
+
+		return nil, err
+	}
+	return accountTypes, nil
+}
+
+// @Summary Get an account type
+// @Description Retrieve the definition of an account type
+// @Tags account-types
+// @Accept json
+// @Produce json
+// @Param name path string true "Account type name"
+// @Success 200 {object} GetByNameResponse
+// @Failure 404 {object} httperror.Response "Not Found"
+// @Failure 500 {object} httperror.Response "Internal Server Error"
+// @Router /account-types/{name} [get]
+func (s *service) GetByName(ctx context.Context, name string) (model.AccountType, error) {
+	accountType, err := s.repository.GetByName(ctx, name)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "accounttype",
+			"function": "GetByName",
+			"error":    err,
+			"name":     name,
+		}).Error("getting account type failed")
+
+		return model.AccountType{}, err
+	}
+	return accountType, nil
+}
+
+// @Summary Replace an account type
+// @Description Replace the definition of an account type. Stored accounts are validated against it when they are next changed
+// @Tags account-types
+// @Accept json
+// @Produce json
+// @Param name path string true "Account type name"
+// @Param accountType body UpdateRequest true "Account type definition"
+// @Success 200 {object} UpdateResponse
+// @Failure 400 {object} httperror.Response "Bad Request"
+// @Failure 404 {object} httperror.Response "Not Found"
+// @Failure 500 {object} httperror.Response "Internal Server Error"
+// @Router /account-types/{name} [put]
+func (s *service) Update(ctx context.Context, accountType model.AccountType) error {
+	if err := accountType.Validate(); err != nil {
+		return err
+	}
+
+	err := s.repository.Update(ctx, accountType)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "accounttype",
+			"function": "Update",
+			"error":    err,
+			"name":     accountType.Name,
+		}).Error("updating account type failed")
+
+		return err
+	}
+	return nil
+}
+
+// @Summary Delete an account type
+// @Description Delete an account type that no account has
+// @Tags account-types
+// @Accept json
+// @Produce json
+// @Param name path string true "Account type name"
+// @Success 200 {object} DeleteResponse
+// @Failure 404 {object} httperror.Response "Not Found"
+// @Failure 409 {object} httperror.Response "Account type is in use"
+// @Failure 500 {object} httperror.Response "Internal Server Error"
+// @Router /account-types/{name} [delete]
+func (s *service) Delete(ctx context.Context, name string) error {
+	page, err := s.accountRepository.GetAll(ctx, model.AccountFilter{AccountType: name, Limit: 1})
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "accounttype",
+			"function": "Delete",
+			"error":    err,
+			"name":     name,
+		}).Error("getting accounts of account type failed")
+
+		return err
+	}
+	if len(page.Accounts) > 0 {
+		return model.Errorf(model.ErrConflict, "account type %s is in use", name)
+	}
+
+	err = s.repository.Delete(ctx, name)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "accounttype",
+			"function": "Delete",
+			"error":    err,
+			"name":     name,
+		}).Error("deleting account type failed")
+
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package accounttype
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/sirupsen/logrus"
+
+	"account_storage/pkg/httperror"
+	"account_storage/pkg/logadapter"
+	"account_storage/pkg/model"
+	"account_storage/pkg/model/account"
+)
+
+// RegisterGinRoutes mounts the account type routes on router, which is
+// expected to be guarded by authentication middleware.
+func RegisterGinRoutes(
+	router gin.IRouter, svcEndpoints Endpoints, options []kithttp.ServerOption, logger *logrus.Logger) {
+	logrusAdapter := logadapter.NewLogrusAdapter(logger)
+	errorLogger := kithttp.ServerErrorLogger(logrusAdapter)
+	errorEncoder := kithttp.ServerErrorEncoder(httperror.EncodeError)
+	options = append(options, errorLogger, errorEncoder)
+
+	router.POST("/account-types", gin.WrapF(func(w http.ResponseWriter, r *http.Request) {
+		kithttp.NewServer(
+			svcEndpoints.Create,
+			decodeCreateRequest(logger),
+			encodeResponse(logger),
+			options...,
+		).ServeHTTP(w, r)
+	}))
+
+	router.GET("/account-types", gin.WrapF(func(w http.ResponseWriter, r *http.Request) {
+		kithttp.NewServer(
+			svcEndpoints.GetAll,
+			decodeGetAllRequest,
+			encodeResponse(logger),
+			options...,
+		).ServeHTTP(w, r)
+	}))
+
+	router.GET("/account-types/:name", gin.WrapF(func(w http.ResponseWriter, r *http.Request) {
+		kithttp.NewServer(
+			svcEndpoints.GetByName,
+			decodeGetByNameRequest,
+			encodeResponse(logger),
+			options...,
+		).ServeHTTP(w, r)
+	}))
+
+	router.PUT("/account-types/:name", gin.WrapF(func(w http.ResponseWriter, r *http.Request) {
+		kithttp.NewServer(
+			svcEndpoints.Update,
+			decodeUpdateRequest(logger),
+			encodeResponse(logger),
+			options...,
+		).ServeHTTP(w, r)
+	}))
+
+	router.DELETE("/account-types/:name", gin.WrapF(func(w http.ResponseWriter, r *http.Request) {
+		kithttp.NewServer(
+			svcEndpoints.Delete,
+			decodeDeleteRequest,
+			encodeResponse(logger),
+			options...,
+		).ServeHTTP(w, r)
+	}))
+}
+
+func decodeCreateRequest(logger *logrus.Logger) kithttp.DecodeRequestFunc {
+	return func(_ context.Context, r *http.Request) (interface{}, error) {
+		var req CreateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.WithFields(logrus.Fields{
+				"package":  "accounttype",
+				"function": "decodeCreateRequest",
+				"error":    err,
+			}).Error("decoding from json failed")
+
+			return nil, model.Errorf(model.ErrInvalidArgument, "error decoding request: %w", err)
+		}
+
+		return req, nil
+	}
+}
+
+func decodeGetAllRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return GetAllRequest{}, nil
+}
+
+func decodeGetByNameRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	name, err := decodeNameParam(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return GetByNameRequest{Name: name}, nil
+}
+
+func decodeUpdateRequest(logger *logrus.Logger) kithttp.DecodeRequestFunc {
+	return func(_ context.Context, r *http.Request) (interface{}, error) {
+		name, err := decodeNameParam(r)
+		if err != nil {
+			return nil, err
+		}
+
+		var req UpdateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.WithFields(logrus.Fields{
+				"package":  "accounttype",
+				"function": "decodeUpdateRequest",
+				"error":    err,
+			}).Error("decoding from json failed")
+
+			return nil, model.Errorf(model.ErrInvalidArgument, "error decoding request: %w", err)
+		}
+		req.AccountType.Name = name
+
+		return req, nil
+	}
+}
+
+func decodeDeleteRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	name, err := decodeNameParam(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return DeleteRequest{Name: name}, nil
+}
+
+func decodeNameParam(r *http.Request) (string, error) {
+	ginCtx, ok := r.Context().Value(account.GinContextKey{}).(*gin.Context)
+	if !ok {
+		return "", errors.New("could not retrieve gin.Context")
+	}
+
+	name := ginCtx.Param("name")
+	if name == "" {
+		return "", account.ErrBadRouting
+	}
+
+	return name, nil
+}
+
+func encodeResponse(logger *logrus.Logger) kithttp.EncodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		if e, ok := response.(errorer); ok && e.error() != nil {
+			logger.Errorf("Handling error: %v", e.error())
+			httperror.EncodeError(ctx, e.error(), w)
+			return nil
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			logger.Errorf("Error encoding JSON response: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		return nil
+	}
+}
+
+type errorer interface {
+	error() error
+}
@@ -0,0 +1,19 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type APIKey struct {
+	ID        uuid.UUID `json:"id,omitempty"`
+	Name      string    `json:"name,omitempty"`
+	KeyHash   string    `json:"-"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+}
+
+type APIKeyCreate struct {
+	Name    string `json:"name,omitempty"`
+	KeyHash string `json:"-"`
+}
@@ -0,0 +1,77 @@
+package apikey
+
+import (
+	"account_storage/pkg/model"
+	"context"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+type Endpoints struct {
+	Create endpoint.Endpoint
+	GetAll endpoint.Endpoint
+	Delete endpoint.Endpoint
+}
+
+func MakeEndpoints(s Service) Endpoints {
+	return Endpoints{
+		Create: makeCreateEndpoint(s),
+		GetAll: makeGetAllEndpoint(s),
+		Delete: makeDeleteEndpoint(s),
+	}
+}
+
+func makeCreateEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(CreateRequest)
+		id, key, err := s.Create(ctx, req.APIKey)
+		return CreateResponse{ID: id, Key: key, Err: err}, nil
+	}
+}
+
+func makeGetAllEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		apiKeys, err := s.GetAll(ctx)
+		return GetAllResponse{APIKeys: apiKeys, Err: err}, nil
+	}
+}
+
+func makeDeleteEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(DeleteRequest)
+		err := s.Delete(ctx, req.ID)
+		return DeleteResponse{Err: err}, nil
+	}
+}
+
+type CreateRequest struct {
+	APIKey model.APIKeyCreate `json:"api_key"`
+}
+
+type CreateResponse struct {
+	ID  string `json:"id"`
+	Key string `json:"key"`
+	Err error  `json:"error,omitempty"`
+}
+
+func (r CreateResponse) error() error { return r.Err }
+
+type GetAllRequest struct {
+}
+
+type GetAllResponse struct {
+	APIKeys []model.APIKey `json:"api_keys"`
+	Err     error          `json:"error,omitempty"`
+}
+
+func (r GetAllResponse) error() error { return r.Err }
+
+type DeleteRequest struct {
+	ID string `json:"id"`
+}
+
+type DeleteResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r DeleteResponse) error() error { return r.Err }
@@ -0,0 +1,169 @@
+package apikey
+
+import (
+	"account_storage/internal/app/store"
+	"account_storage/pkg/auth"
+	"account_storage/pkg/model"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+const adminCallerName = "admin"
+
+var (
+	ErrInvalidAPIKey = model.NewError(model.ErrUnauthorized, "invalid api key")
+)
+
+type Service interface {
+	Create(ctx context.Context, apiKey model.APIKeyCreate) (string, string, error)
+	GetAll(ctx context.Context) ([]model.APIKey, error)
+	Delete(ctx context.Context, id string) error
+	Authenticate(ctx context.Context, key string) (auth.Caller, error)
+}
+
+type service struct {
+	repository   store.APIKeyRepository
+	adminKeyHash string
+	logger       *logrus.Logger
+}
+
+// NewService returns the api key service. adminKey is the bootstrap key
+// that is accepted as the admin caller without being stored.
+func NewService(repository store.APIKeyRepository, adminKey string, logger *logrus.Logger) Service {
+	var adminKeyHash string
+	if adminKey != "" {
+		adminKeyHash = hashKey(adminKey)
+	}
+
+	return &service{
+		repository:   repository,
+		adminKeyHash: adminKeyHash,
+		logger:       logger,
+	}
+}
+
+// @Summary Create an api key
+// @Description Create a new api key. The key is returned only once, only its hash is stored
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param apiKey body CreateRequest true "API key to create"
+// @Success 200 {object} CreateResponse
+// @Failure 400 {object} httperror.Response "Bad Request"
+// @Failure 500 {object} httperror.Response "Internal Server Error"
+// @Router /admin/api-keys [post]
+func (s *service) Create(ctx context.Context, apiKey model.APIKeyCreate) (string, string, error) {
+	key, err := generateKey()
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "apikey",
+			"function": "Create",
+			"error":    err,
+		}).Error("generating api key failed")
+
+		return "", "", err
+	}
+
+	apiKey.KeyHash = hashKey(key)
+
+	id, err := s.repository.Create(ctx, apiKey)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "apikey",
+			"function": "Create",
+			"error":    err,
+			"name":     apiKey.Name,
+		}).Error("creating api key failed")
+
+		return "", "", err
+	}
+	return id, key, nil
+}
+
+// @Summary Get all api keys
+// @Description Retrieve a list of all api keys without their secrets
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Success 200 {object} GetAllResponse "List of api keys"
+// @Failure 500 {object} httperror.Response "Internal Server Error"
+// @Router /admin/api-keys [get]
+func (s *service) GetAll(ctx context.Context) ([]model.APIKey, error) {
+	apiKeys, err := s.repository.GetAll(ctx)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "apikey",
+			"function": "GetAll",
+			"error":    err,
+		}).Error("getting all api keys failed")
+
+		return nil, err
+	}
+	return apiKeys, nil
+}
+
+// @Summary Delete an api key
+// @Description Revoke an api key
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "API key ID to delete"
+// @Success 200 {object} DeleteResponse
+// @Failure 400 {object} httperror.Response "Bad Request"
+// @Failure 404 {object} httperror.Response "Not Found"
+// @Failure 500 {object} httperror.Response "Internal Server Error"
+// @Router /admin/api-keys/{id} [delete]
+func (s *service) Delete(ctx context.Context, id string) error {
+	err := s.repository.Delete(ctx, id)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "apikey",
+			"function": "Delete",
+			"error":    err,
+			"id":       id,
+		}).Error("deleting api key failed")
+
+		return err
+	}
+	return nil
+}
+
+func (s *service) Authenticate(ctx context.Context, key string) (auth.Caller, error) {
+	keyHash := hashKey(key)
+
+	if s.adminKeyHash != "" && subtle.ConstantTimeCompare([]byte(keyHash), []byte(s.adminKeyHash)) == 1 {
+		return auth.Caller{Name: adminCallerName, Source: auth.SourceBootstrap}, nil
+	}
+
+	apiKey, err := s.repository.GetByHash(ctx, keyHash)
+	if errors.Is(err, model.ErrNotFound) {
+		return auth.Caller{}, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return auth.Caller{}, fmt.Errorf("error authenticating api key: %w", err)
+	}
+
+	return auth.Caller{ID: apiKey.ID.String(), Name: apiKey.Name, Source: auth.SourceAPIKey}, nil
+}
+
+func generateKey() (string, error) {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	if err != nil {
+		return "", fmt.Errorf("error generating api key: %w", err)
+	}
+
+	return hex.EncodeToString(key), nil
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
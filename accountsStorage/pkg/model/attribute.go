@@ -0,0 +1,148 @@
+package model
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	AttributeTypeString  = "string"
+	AttributeTypeNumber  = "number"
+	AttributeTypeBoolean = "boolean"
+	AttributeTypeDate    = "date"
+
+	attributeDateLayout = "2006-01-02"
+)
+
+var attributeNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Attribute is a custom field of an account. Value is the text form of a
+// value of Type, which is a string when empty. Secret attributes are
+// encrypted and masked like the secret fields.
+type Attribute struct {
+	Type   string `json:"type,omitempty"`
+	Value  string `json:"value"`
+	Secret bool   `json:"secret,omitempty"`
+}
+
+// Attributes are the custom fields of an account by name.
+type Attributes map[string]*Attribute
+
+func validAttributeType(attributeType string) bool {
+	switch attributeType {
+	case "", AttributeTypeString, AttributeTypeNumber, AttributeTypeBoolean, AttributeTypeDate:
+		return true
+	default:
+		return false
+	}
+}
+
+// Validate checks the names of the attributes and that their values are of
+// their type. Secret values are checked before they are encrypted only.
+func (attributes Attributes) Validate() error {
+	for name, attribute := range attributes {
+		if !attributeNamePattern.MatchString(name) {
+			return Errorf(ErrInvalidArgument, "invalid attribute name %q", name)
+		}
+		if attribute == nil {
+			return Errorf(ErrInvalidArgument, "attribute %s: value is required", name)
+		}
+		if !validAttributeType(attribute.Type) {
+			return Errorf(ErrInvalidArgument, "attribute %s: invalid type %q", name, attribute.Type)
+		}
+
+		var err error
+		switch attribute.Type {
+		case AttributeTypeNumber:
+			_, err = strconv.ParseFloat(attribute.Value, 64)
+		case AttributeTypeBoolean:
+			_, err = strconv.ParseBool(attribute.Value)
+		case AttributeTypeDate:
+			_, err = time.Parse(attributeDateLayout, attribute.Value)
+		}
+		if err != nil {
+			return Errorf(ErrInvalidArgument, "attribute %s: %q is not a %s", name, attribute.Value, attribute.Type)
+		}
+	}
+	return nil
+}
+
+// clone copies the attributes, so their values can be changed without
+// changing the accounts sharing the map.
+func (attributes Attributes) clone() Attributes {
+	if attributes == nil {
+		return nil
+	}
+	cloned := make(Attributes, len(attributes))
+	for name, attribute := range attributes {
+		if attribute != nil {
+			copied := *attribute
+			attribute = &copied
+		}
+		cloned[name] = attribute
+	}
+	return cloned
+}
+
+// secretNames returns the names of the secret attributes, sorted.
+func (attributes Attributes) secretNames() []string {
+	var names []string
+	for name, attribute := range attributes {
+		if attribute != nil && attribute.Secret {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (attributes Attributes) secrets() []*string {
+	var fields []*string
+	for _, name := range attributes.secretNames() {
+		fields = append(fields, &attributes[name].Value)
+	}
+	return fields
+}
+
+// Redacted returns a copy that is safe to log.
+func (attributes Attributes) Redacted() interface{} {
+	attributes = attributes.clone()
+	redact(attributes.secrets()...)
+	return attributes
+}
+
+func attributeFieldName(name string) string {
+	return "attributes." + name
+}
+
+// changedAttributes returns the field names of the attributes that differ
+// between before and after.
+func changedAttributes(before, after Attributes) []string {
+	var names []string
+	for name, beforeAttribute := range before {
+		afterAttribute, ok := after[name]
+		if !ok || beforeAttribute == nil || afterAttribute == nil || *beforeAttribute != *afterAttribute {
+			names = append(names, attributeFieldName(name))
+		}
+	}
+	for name := range after {
+		if _, ok := before[name]; !ok {
+			names = append(names, attributeFieldName(name))
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// ParseAttributeFilter parses "name:value", which matches the accounts with
+// a non-secret attribute name of value.
+func ParseAttributeFilter(filter string) (string, string, error) {
+	name, value, ok := strings.Cut(filter, ":")
+	if !ok || !attributeNamePattern.MatchString(name) {
+		return "", "", Errorf(ErrInvalidArgument, "invalid attribute filter %q, expected name:value", filter)
+	}
+	return name, value, nil
+}
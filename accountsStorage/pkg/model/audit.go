@@ -0,0 +1,46 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	AuditActionCreate   = "create"
+	AuditActionUpdate   = "update"
+	AuditActionDelete   = "delete"
+	AuditActionReveal   = "reveal"
+	AuditActionExport   = "export"
+	AuditActionRestore  = "restore"
+	AuditActionPurge    = "purge"
+	AuditActionRollback = "rollback"
+)
+
+// AuditRecord describes one operation on an account. Caller is the
+// authenticated identity, Actor is who the caller acted for, which is the
+// X-Caller-Identity header when given and the caller otherwise.
+type AuditRecord struct {
+	ID        uuid.UUID `json:"id,omitempty"`
+	Actor     string    `json:"actor,omitempty"`
+	Caller    string    `json:"caller,omitempty"`
+	Action    string    `json:"action,omitempty"`
+	AccountID string    `json:"account_id,omitempty"`
+	Fields    []string  `json:"fields,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+}
+
+type AuditRecordCreate struct {
+	Actor     string   `json:"actor,omitempty"`
+	Caller    string   `json:"caller,omitempty"`
+	Action    string   `json:"action,omitempty"`
+	AccountID string   `json:"account_id,omitempty"`
+	Fields    []string `json:"fields,omitempty"`
+}
+
+type AuditFilter struct {
+	AccountID string    `json:"account_id,omitempty"`
+	Actor     string    `json:"actor,omitempty"`
+	From      time.Time `json:"from,omitempty"`
+	To        time.Time `json:"to,omitempty"`
+}
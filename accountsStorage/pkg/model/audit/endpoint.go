@@ -0,0 +1,37 @@
+package audit
+
+import (
+	"account_storage/pkg/model"
+	"context"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+type Endpoints struct {
+	GetAll endpoint.Endpoint
+}
+
+func MakeEndpoints(s Service) Endpoints {
+	return Endpoints{
+		GetAll: makeGetAllEndpoint(s),
+	}
+}
+
+func makeGetAllEndpoint(s Service) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(GetAllRequest)
+		auditRecords, err := s.GetAll(ctx, req.Filter)
+		return GetAllResponse{AuditRecords: auditRecords, Err: err}, nil
+	}
+}
+
+type GetAllRequest struct {
+	Filter model.AuditFilter `json:"filter"`
+}
+
+type GetAllResponse struct {
+	AuditRecords []model.AuditRecord `json:"audit_records"`
+	Err          error               `json:"error,omitempty"`
+}
+
+func (r GetAllResponse) error() error { return r.Err }
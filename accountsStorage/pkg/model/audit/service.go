@@ -0,0 +1,53 @@
+package audit
+
+import (
+	"account_storage/internal/app/store"
+	"account_storage/pkg/model"
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+type Service interface {
+	GetAll(ctx context.Context, filter model.AuditFilter) ([]model.AuditRecord, error)
+}
+
+type service struct {
+	repository store.AuditRepository
+	logger     *logrus.Logger
+}
+
+func NewService(repository store.AuditRepository, logger *logrus.Logger) Service {
+	return &service{
+		repository: repository,
+		logger:     logger,
+	}
+}
+
+// @Summary Get audit records
+// @Description Retrieve audit records of account operations, oldest first
+// @Tags audit
+// @Accept json
+// @Produce json
+// @Param account_id query string false "Account ID"
+// @Param actor query string false "Actor"
+// @Param from query string false "Start of the time range, RFC 3339"
+// @Param to query string false "End of the time range (exclusive), RFC 3339"
+// @Success 200 {object} GetAllResponse "List of audit records"
+// @Failure 400 {object} httperror.Response "Bad Request"
+// @Failure 500 {object} httperror.Response "Internal Server Error"
+// @Router /audit [get]
+func (s *service) GetAll(ctx context.Context, filter model.AuditFilter) ([]model.AuditRecord, error) {
+	auditRecords, err := s.repository.GetAll(ctx, filter)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"package":  "audit",
+			"function": "GetAll",
+			"error":    err,
+			"filter":   filter,
+		}).Error("getting audit records failed")
+
+		return nil, err
+	}
+	return auditRecords, nil
+}
@@ -0,0 +1,82 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/sirupsen/logrus"
+
+	"account_storage/pkg/httperror"
+	"account_storage/pkg/logadapter"
+	"account_storage/pkg/model"
+)
+
+// RegisterGinRoutes mounts the audit routes on router, which is expected
+// to be guarded by authentication middleware.
+func RegisterGinRoutes(
+	router gin.IRouter, svcEndpoints Endpoints, options []kithttp.ServerOption, logger *logrus.Logger) {
+	logrusAdapter := logadapter.NewLogrusAdapter(logger)
+	errorLogger := kithttp.ServerErrorLogger(logrusAdapter)
+	errorEncoder := kithttp.ServerErrorEncoder(httperror.EncodeError)
+	options = append(options, errorLogger, errorEncoder)
+
+	router.GET("/audit", gin.WrapF(func(w http.ResponseWriter, r *http.Request) {
+		kithttp.NewServer(
+			svcEndpoints.GetAll,
+			decodeGetAllRequest,
+			encodeResponse(logger),
+			options...,
+		).ServeHTTP(w, r)
+	}))
+}
+
+func decodeGetAllRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	query := r.URL.Query()
+
+	filter := model.AuditFilter{
+		AccountID: query.Get("account_id"),
+		Actor:     query.Get("actor"),
+	}
+
+	var err error
+	if from := query.Get("from"); from != "" {
+		filter.From, err = time.Parse(time.RFC3339, from)
+		if err != nil {
+			return nil, model.Errorf(model.ErrInvalidArgument, "error parsing from: %w", err)
+		}
+	}
+	if to := query.Get("to"); to != "" {
+		filter.To, err = time.Parse(time.RFC3339, to)
+		if err != nil {
+			return nil, model.Errorf(model.ErrInvalidArgument, "error parsing to: %w", err)
+		}
+	}
+
+	return GetAllRequest{Filter: filter}, nil
+}
+
+func encodeResponse(logger *logrus.Logger) kithttp.EncodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		if e, ok := response.(errorer); ok && e.error() != nil {
+			logger.Errorf("Handling error: %v", e.error())
+			httperror.EncodeError(ctx, e.error(), w)
+			return nil
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			logger.Errorf("Error encoding JSON response: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+		return nil
+	}
+}
+
+type errorer interface {
+	error() error
+}
@@ -0,0 +1,71 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// MaxBatchSize limits the number of items of a batch request.
+const MaxBatchSize = 5000
+
+// BatchMode tells whether a batch is applied all or nothing or item by item.
+type BatchMode string
+
+const (
+	BatchModeAtomic     BatchMode = "atomic"
+	BatchModeBestEffort BatchMode = "best_effort"
+)
+
+// ParseBatchMode parses a batch mode, the default is atomic.
+func ParseBatchMode(mode string) (BatchMode, error) {
+	switch BatchMode(mode) {
+	case "", BatchModeAtomic:
+		return BatchModeAtomic, nil
+	case BatchModeBestEffort:
+		return BatchModeBestEffort, nil
+	default:
+		return "", Errorf(ErrInvalidArgument, "invalid batch mode: %s", mode)
+	}
+}
+
+// Atomic reports whether the first failing item fails the whole batch.
+func (mode BatchMode) Atomic() bool {
+	return mode != BatchModeBestEffort
+}
+
+// ValidateBatchSize checks that a batch has between one and MaxBatchSize
+// items.
+func ValidateBatchSize(size int) error {
+	if size == 0 || size > MaxBatchSize {
+		return Errorf(ErrInvalidArgument, "batch must have between 1 and %d items, got %d", MaxBatchSize, size)
+	}
+	return nil
+}
+
+// BatchResult is the outcome of one item of a batch. Err is set when the
+// item failed in best effort mode.
+type BatchResult struct {
+	ID      string
+	Version int64
+	Err     error
+}
+
+// BatchItemError returns the error of the batch item at index, keeping the
+// kind of err.
+func BatchItemError(index int, err error) error {
+	return fmt.Errorf("batch item %d: %w", index, err)
+}
+
+// AccountBatchUpdate replaces the account with ID if it is still at Version.
+type AccountBatchUpdate struct {
+	ID      uuid.UUID     `json:"id"`
+	Version int64         `json:"version"`
+	Account AccountUpdate `json:"account"`
+}
+
+// AccountVersion names an account at a version, as expected by a delete.
+type AccountVersion struct {
+	ID      string `json:"id"`
+	Version int64  `json:"version"`
+}
@@ -0,0 +1,354 @@
+package model
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CookieFormat is a serialization of a cookie jar.
+type CookieFormat string
+
+const (
+	// CookieFormatJSON is the JSON array exported by browser cookie
+	// extensions. Accounts store their cookie jar in this format.
+	CookieFormatJSON CookieFormat = "json"
+	// CookieFormatNetscape is the tab separated cookies.txt format.
+	CookieFormatNetscape CookieFormat = "netscape"
+)
+
+// ParseCookieFormat parses a cookie format, the default is JSON.
+func ParseCookieFormat(format string) (CookieFormat, error) {
+	switch CookieFormat(format) {
+	case "", CookieFormatJSON:
+		return CookieFormatJSON, nil
+	case CookieFormatNetscape:
+		return CookieFormatNetscape, nil
+	default:
+		return "", Errorf(ErrInvalidArgument, "invalid cookie format: %s", format)
+	}
+}
+
+// Cookie is one cookie of a jar. A zero Expires makes it a session cookie.
+type Cookie struct {
+	Domain   string
+	HostOnly bool
+	Path     string
+	Name     string
+	Value    string
+	Expires  time.Time
+	Secure   bool
+	HTTPOnly bool
+}
+
+// extensionCookie is a Cookie as written by browser cookie extensions.
+// Fields they add, such as sameSite or storeId, are ignored.
+type extensionCookie struct {
+	Domain         string   `json:"domain"`
+	HostOnly       bool     `json:"hostOnly"`
+	Path           string   `json:"path"`
+	Name           string   `json:"name"`
+	Value          string   `json:"value"`
+	ExpirationDate *float64 `json:"expirationDate,omitempty"`
+	Session        bool     `json:"session"`
+	Secure         bool     `json:"secure"`
+	HTTPOnly       bool     `json:"httpOnly"`
+}
+
+func (cookie Cookie) MarshalJSON() ([]byte, error) {
+	extension := extensionCookie{
+		Domain:   cookie.Domain,
+		HostOnly: cookie.HostOnly,
+		Path:     cookie.Path,
+		Name:     cookie.Name,
+		Value:    cookie.Value,
+		Session:  cookie.Expires.IsZero(),
+		Secure:   cookie.Secure,
+		HTTPOnly: cookie.HTTPOnly,
+	}
+	if !cookie.Expires.IsZero() {
+		expirationDate := float64(cookie.Expires.UnixMilli()) / 1000
+		extension.ExpirationDate = &expirationDate
+	}
+	return json.Marshal(extension)
+}
+
+func (cookie *Cookie) UnmarshalJSON(data []byte) error {
+	var extension extensionCookie
+	if err := json.Unmarshal(data, &extension); err != nil {
+		return err
+	}
+
+	*cookie = Cookie{
+		Domain:   extension.Domain,
+		HostOnly: extension.HostOnly,
+		Path:     extension.Path,
+		Name:     extension.Name,
+		Value:    extension.Value,
+		Secure:   extension.Secure,
+		HTTPOnly: extension.HTTPOnly,
+	}
+	if extension.ExpirationDate != nil && !extension.Session {
+		seconds, fraction := math.Modf(*extension.ExpirationDate)
+		cookie.Expires = time.Unix(int64(seconds), int64(fraction*1e9)).UTC()
+	}
+	return nil
+}
+
+func (cookie Cookie) validate() error {
+	if cookie.Domain == "" {
+		return NewError(ErrInvalidArgument, "cookie domain is required")
+	}
+	if cookie.Name == "" {
+		return NewError(ErrInvalidArgument, "cookie name is required")
+	}
+	return nil
+}
+
+// key identifies a cookie within a jar, like browsers do.
+func (cookie Cookie) key() string {
+	return cookie.Domain + "\t" + cookie.Path + "\t" + cookie.Name
+}
+
+// CookieJar is the list of cookies of an account.
+type CookieJar []Cookie
+
+// ParseCookieJar parses a jar in the given format. Cookies without a path
+// get "/".
+func ParseCookieJar(data []byte, format CookieFormat) (CookieJar, error) {
+	var jar CookieJar
+	switch format {
+	case CookieFormatNetscape:
+		var err error
+		jar, err = parseNetscapeCookies(data)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		if err := json.Unmarshal(data, &jar); err != nil {
+			return nil, Errorf(ErrInvalidArgument, "error decoding cookie jar: %w", err)
+		}
+	}
+
+	for i := range jar {
+		if err := jar[i].validate(); err != nil {
+			return nil, Errorf(ErrInvalidArgument, "cookie %d: %w", i, err)
+		}
+		if jar[i].Path == "" {
+			jar[i].Path = "/"
+		}
+	}
+	return jar, nil
+}
+
+// DecodeCookieJar returns the jar stored in the cookie of an account, which
+// is JSON, or cookies.txt for accounts stored before cookies were
+// structured. Other values are opaque strings and fail with ErrConflict.
+func DecodeCookieJar(cookie string) (CookieJar, error) {
+	trimmed := strings.TrimSpace(cookie)
+	if trimmed == "" {
+		return CookieJar{}, nil
+	}
+
+	format := CookieFormatNetscape
+	if strings.HasPrefix(trimmed, "[") {
+		format = CookieFormatJSON
+	}
+	jar, err := ParseCookieJar([]byte(trimmed), format)
+	if err != nil {
+		return nil, Errorf(ErrConflict, "stored cookie is not a cookie jar, replace it to structure it: %v", err)
+	}
+	return jar, nil
+}
+
+// EarliestExpiry returns the earliest expiry of the cookies that have one,
+// or nil when all of them are session cookies.
+func (jar CookieJar) EarliestExpiry() *time.Time {
+	var earliest *time.Time
+	for i := range jar {
+		expires := jar[i].Expires
+		if expires.IsZero() {
+			continue
+		}
+		if earliest == nil || expires.Before(*earliest) {
+			earliest = &expires
+		}
+	}
+	return earliest
+}
+
+// CookiesExpireAt returns the earliest expiry of the jar stored in the
+// cookie of an account, or nil when it has none or is not a cookie jar.
+func CookiesExpireAt(cookie string) *time.Time {
+	jar, err := DecodeCookieJar(cookie)
+	if err != nil {
+		return nil
+	}
+	return jar.EarliestExpiry()
+}
+
+// String returns the jar as stored in the cookie of an account.
+func (jar CookieJar) String() string {
+	if len(jar) == 0 {
+		return ""
+	}
+	data, _ := jar.Format(CookieFormatJSON)
+	return string(data)
+}
+
+// Format serializes the jar.
+func (jar CookieJar) Format(format CookieFormat) ([]byte, error) {
+	if format == CookieFormatNetscape {
+		return jar.formatNetscape(), nil
+	}
+	if jar == nil {
+		jar = CookieJar{}
+	}
+	return json.Marshal(jar)
+}
+
+// Merge returns the jar with the cookies of other added. A cookie of other
+// replaces the cookie with the same domain, path and name, and removes it
+// when it has already expired.
+func (jar CookieJar) Merge(other CookieJar, now time.Time) CookieJar {
+	merged := make(CookieJar, len(jar))
+	copy(merged, jar)
+
+	index := make(map[string]int, len(merged))
+	for i, cookie := range merged {
+		index[cookie.key()] = i
+	}
+
+	removed := make(map[string]bool)
+	for _, cookie := range other {
+		key := cookie.key()
+		if !cookie.Expires.IsZero() && !cookie.Expires.After(now) {
+			removed[key] = true
+			continue
+		}
+		delete(removed, key)
+		if i, ok := index[key]; ok {
+			merged[i] = cookie
+			continue
+		}
+		index[key] = len(merged)
+		merged = append(merged, cookie)
+	}
+
+	if len(removed) == 0 {
+		return merged
+	}
+	kept := merged[:0]
+	for _, cookie := range merged {
+		if !removed[cookie.key()] {
+			kept = append(kept, cookie)
+		}
+	}
+	return kept
+}
+
+const (
+	netscapeHeader         = "# Netscape HTTP Cookie File"
+	netscapeHTTPOnlyPrefix = "#HttpOnly_"
+	netscapeFields         = 7
+)
+
+func parseNetscapeCookies(data []byte) (CookieJar, error) {
+	jar := CookieJar{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), "\r")
+
+		httpOnly := strings.HasPrefix(text, netscapeHTTPOnlyPrefix)
+		if httpOnly {
+			text = strings.TrimPrefix(text, netscapeHTTPOnlyPrefix)
+		}
+		if strings.TrimSpace(text) == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Split(text, "\t")
+		if len(fields) != netscapeFields {
+			return nil, Errorf(ErrInvalidArgument, "cookies.txt line %d: expected %d tab separated fields, got %d", line, netscapeFields, len(fields))
+		}
+
+		includeSubdomains, err := parseNetscapeBool(fields[1])
+		if err != nil {
+			return nil, Errorf(ErrInvalidArgument, "cookies.txt line %d: %w", line, err)
+		}
+		secure, err := parseNetscapeBool(fields[3])
+		if err != nil {
+			return nil, Errorf(ErrInvalidArgument, "cookies.txt line %d: %w", line, err)
+		}
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, Errorf(ErrInvalidArgument, "cookies.txt line %d: invalid expiry %q", line, fields[4])
+		}
+
+		cookie := Cookie{
+			Domain:   fields[0],
+			HostOnly: !includeSubdomains,
+			Path:     fields[2],
+			Name:     fields[5],
+			Value:    fields[6],
+			Secure:   secure,
+			HTTPOnly: httpOnly,
+		}
+		if expires > 0 {
+			cookie.Expires = time.Unix(expires, 0).UTC()
+		}
+		jar = append(jar, cookie)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, Errorf(ErrInvalidArgument, "error reading cookies.txt: %w", err)
+	}
+
+	return jar, nil
+}
+
+func parseNetscapeBool(value string) (bool, error) {
+	switch strings.ToUpper(value) {
+	case "TRUE":
+		return true, nil
+	case "FALSE":
+		return false, nil
+	default:
+		return false, fmt.Errorf("expected TRUE or FALSE, got %q", value)
+	}
+}
+
+func (jar CookieJar) formatNetscape() []byte {
+	var buf bytes.Buffer
+	buf.WriteString(netscapeHeader + "\n\n")
+
+	for _, cookie := range jar {
+		if cookie.HTTPOnly {
+			buf.WriteString(netscapeHTTPOnlyPrefix)
+		}
+
+		var expires int64
+		if !cookie.Expires.IsZero() {
+			expires = cookie.Expires.Unix()
+		}
+		fmt.Fprintf(&buf, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			cookie.Domain, netscapeBool(!cookie.HostOnly), cookie.Path, netscapeBool(cookie.Secure), expires, cookie.Name, cookie.Value)
+	}
+
+	return buf.Bytes()
+}
+
+func netscapeBool(value bool) string {
+	if value {
+		return "TRUE"
+	}
+	return "FALSE"
+}
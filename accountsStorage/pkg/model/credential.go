@@ -0,0 +1,102 @@
+package model
+
+import (
+	"slices"
+	"time"
+)
+
+// CredentialVersion is the secret fields of an account as they were at
+// Version, kept once they are replaced so they can be rolled back to.
+// Attributes holds the secret attributes only.
+type CredentialVersion struct {
+	AccountID             string     `json:"account_id,omitempty"`
+	Version               int64      `json:"version"`
+	Password              string     `json:"password,omitempty"`
+	EmailPassword         string     `json:"emailPassword,omitempty"`
+	RecoveryEmailPassword string     `json:"recovery_email_password,omitempty"`
+	Cookie                string     `json:"cookie,omitempty"`
+	Attributes            Attributes `json:"attributes,omitempty"`
+	Actor                 string     `json:"actor,omitempty"`
+	// CreatedAt is when the version was replaced.
+	CreatedAt time.Time `json:"created_at,omitempty"`
+}
+
+// NewCredentialVersion returns the secret fields of account at its version.
+func NewCredentialVersion(account Account) CredentialVersion {
+	credentialVersion := CredentialVersion{
+		AccountID:             account.ID.String(),
+		Version:               account.Version,
+		Password:              account.Password,
+		EmailPassword:         account.EmailPassword,
+		RecoveryEmailPassword: account.RecoveryEmailPassword,
+		Cookie:                account.Cookie,
+	}
+	for _, name := range account.Attributes.secretNames() {
+		if credentialVersion.Attributes == nil {
+			credentialVersion.Attributes = make(Attributes)
+		}
+		attribute := *account.Attributes[name]
+		credentialVersion.Attributes[name] = &attribute
+	}
+	return credentialVersion
+}
+
+// Secrets returns the secret fields in the order of Account.Secrets.
+func (credentialVersion *CredentialVersion) Secrets() []*string {
+	credentialVersion.Attributes = credentialVersion.Attributes.clone()
+	return append([]*string{
+		&credentialVersion.Password,
+		&credentialVersion.EmailPassword,
+		&credentialVersion.RecoveryEmailPassword,
+		&credentialVersion.Cookie,
+	}, credentialVersion.Attributes.secrets()...)
+}
+
+// Redacted returns a copy that is safe to log.
+func (credentialVersion CredentialVersion) Redacted() interface{} {
+	redact(credentialVersion.Secrets()...)
+	return credentialVersion
+}
+
+// Masked returns a copy with secrets hidden.
+func (credentialVersion CredentialVersion) Masked() CredentialVersion {
+	mask(credentialVersion.Secrets()...)
+	return credentialVersion
+}
+
+// Apply returns account with the secret fields of the version. Secret
+// attributes the version does not have are removed.
+func (credentialVersion CredentialVersion) Apply(account Account) Account {
+	account.Password = credentialVersion.Password
+	account.EmailPassword = credentialVersion.EmailPassword
+	account.RecoveryEmailPassword = credentialVersion.RecoveryEmailPassword
+	account.Cookie = credentialVersion.Cookie
+
+	account.Attributes = account.Attributes.clone()
+	for _, name := range account.Attributes.secretNames() {
+		delete(account.Attributes, name)
+	}
+	for name, attribute := range credentialVersion.Attributes.clone() {
+		if account.Attributes == nil {
+			account.Attributes = make(Attributes)
+		}
+		account.Attributes[name] = attribute
+	}
+	return account
+}
+
+// CredentialsChanged tells whether the secret fields differ between before
+// and after.
+func CredentialsChanged(before, after Account) bool {
+	if !slices.Equal(before.Attributes.secretNames(), after.Attributes.secretNames()) {
+		return true
+	}
+
+	afterSecrets := after.Secrets()
+	for i, field := range before.Secrets() {
+		if *field != *afterSecrets[i] {
+			return true
+		}
+	}
+	return false
+}
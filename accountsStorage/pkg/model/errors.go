@@ -0,0 +1,78 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error kinds. Repositories and services return errors that match one of
+// these under errors.Is, so transports can map them without looking at
+// messages. Errors matching none of them are internal.
+var (
+	ErrNotFound             = errors.New("not found")
+	ErrInvalidArgument      = errors.New("invalid argument")
+	ErrConflict             = errors.New("conflict")
+	ErrUnauthorized         = errors.New("unauthorized")
+	ErrForbidden            = errors.New("forbidden")
+	ErrPreconditionFailed   = errors.New("precondition failed")
+	ErrPreconditionRequired = errors.New("precondition required")
+)
+
+// Error codes, as returned to clients.
+const (
+	CodeNotFound             = "not_found"
+	CodeInvalidArgument      = "invalid_argument"
+	CodeConflict             = "conflict"
+	CodeUnauthorized         = "unauthorized"
+	CodeForbidden            = "forbidden"
+	CodePreconditionFailed   = "precondition_failed"
+	CodePreconditionRequired = "precondition_required"
+	CodeInternal             = "internal"
+)
+
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string {
+	return e.err.Error()
+}
+
+func (e *kindError) Unwrap() []error {
+	return []error{e.kind, e.err}
+}
+
+// Errorf formats an error like fmt.Errorf that also matches kind under
+// errors.Is. The kind is not part of the message.
+func Errorf(kind error, format string, args ...interface{}) error {
+	return &kindError{kind: kind, err: fmt.Errorf(format, args...)}
+}
+
+// NewError returns an error with the given message that matches kind under
+// errors.Is.
+func NewError(kind error, message string) error {
+	return &kindError{kind: kind, err: errors.New(message)}
+}
+
+// ErrorCode returns the code of the kind err matches, or CodeInternal.
+func ErrorCode(err error) string {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return CodeNotFound
+	case errors.Is(err, ErrInvalidArgument):
+		return CodeInvalidArgument
+	case errors.Is(err, ErrConflict):
+		return CodeConflict
+	case errors.Is(err, ErrUnauthorized):
+		return CodeUnauthorized
+	case errors.Is(err, ErrForbidden):
+		return CodeForbidden
+	case errors.Is(err, ErrPreconditionFailed):
+		return CodePreconditionFailed
+	case errors.Is(err, ErrPreconditionRequired):
+		return CodePreconditionRequired
+	default:
+		return CodeInternal
+	}
+}
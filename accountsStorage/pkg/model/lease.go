@@ -0,0 +1,43 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const DefaultLeaseTTL = 300
+
+var (
+	ErrNoAccountAvailable = NewError(ErrNotFound, "no account available for lease")
+	ErrLeaseNotHeld       = NewError(ErrConflict, "lease is not held")
+)
+
+// Lease reserves an account for a single holder until ExpiresAt. An
+// expired lease is treated as released.
+type Lease struct {
+	ID        uuid.UUID `json:"id,omitempty"`
+	AccountID uuid.UUID `json:"account_id,omitempty"`
+	Holder    string    `json:"holder,omitempty"`
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+type LeaseCreate struct {
+	AccountType string `json:"account_type,omitempty"`
+	Status      string `json:"status,omitempty"`
+	TTL         int    `json:"ttl,omitempty"`
+	Holder      string `json:"-"`
+}
+
+type LeaseRenew struct {
+	LeaseID string `json:"lease_id,omitempty"`
+	TTL     int    `json:"ttl,omitempty"`
+}
+
+// LeaseDuration returns ttl seconds as a duration, DefaultLeaseTTL if unset.
+func LeaseDuration(ttl int) time.Duration {
+	if ttl <= 0 {
+		ttl = DefaultLeaseTTL
+	}
+	return time.Duration(ttl) * time.Second
+}
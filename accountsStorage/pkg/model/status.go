@@ -0,0 +1,121 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StatusMachine lists the account statuses and the transitions allowed
+// between them. Without transitions statuses are free-form. Statuses the
+// machine does not know, kept from before it was configured, may change to
+// any known status.
+type StatusMachine struct {
+	Initial     string              `toml:"initial"`
+	Transitions map[string][]string `toml:"transitions"`
+}
+
+func (statusMachine StatusMachine) enabled() bool {
+	return len(statusMachine.Transitions) > 0
+}
+
+func (statusMachine StatusMachine) known(status string) bool {
+	if status == statusMachine.Initial {
+		return true
+	}
+	for from, targets := range statusMachine.Transitions {
+		if from == status {
+			return true
+		}
+		for _, to := range targets {
+			if to == status {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// InitialStatus returns the status of a new account created with status,
+// which is the initial status when empty.
+func (statusMachine StatusMachine) InitialStatus(status string) (string, error) {
+	if !statusMachine.enabled() {
+		return status, nil
+	}
+	if status == "" {
+		return statusMachine.Initial, nil
+	}
+	if !statusMachine.known(status) {
+		return "", Errorf(ErrInvalidArgument, "unknown status %s", status)
+	}
+	return status, nil
+}
+
+// CheckTransition fails with ErrConflict when an account may not change
+// from one status to the other.
+func (statusMachine StatusMachine) CheckTransition(from, to string) error {
+	if !statusMachine.enabled() || from == to {
+		return nil
+	}
+	if !statusMachine.known(to) {
+		return Errorf(ErrInvalidArgument, "unknown status %s", to)
+	}
+	if !statusMachine.known(from) {
+		return nil
+	}
+
+	for _, allowed := range statusMachine.Transitions[from] {
+		if allowed == to {
+			return nil
+		}
+	}
+	return Errorf(ErrConflict, "status cannot change from %s to %s", from, to)
+}
+
+// Blocking returns the statuses that may not change to status.
+func (statusMachine StatusMachine) Blocking(status string) []string {
+	blocking := []string{}
+	if !statusMachine.enabled() {
+		return blocking
+	}
+
+	seen := make(map[string]bool)
+	add := func(from string) {
+		if seen[from] {
+			return
+		}
+		seen[from] = true
+		if statusMachine.CheckTransition(from, status) != nil {
+			blocking = append(blocking, from)
+		}
+	}
+
+	add(statusMachine.Initial)
+	for from, targets := range statusMachine.Transitions {
+		add(from)
+		for _, to := range targets {
+			add(to)
+		}
+	}
+	return blocking
+}
+
+// StatusTransition is a recorded change of the status of an account. From
+// is empty for the status an account was created with.
+type StatusTransition struct {
+	ID        uuid.UUID `json:"id,omitempty"`
+	AccountID string    `json:"account_id,omitempty"`
+	From      string    `json:"from,omitempty"`
+	To        string    `json:"to,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	Actor     string    `json:"actor,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+}
+
+type StatusTransitionCreate struct {
+	AccountID string `json:"account_id,omitempty"`
+	From      string `json:"from,omitempty"`
+	To        string `json:"to,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+	Actor     string `json:"actor,omitempty"`
+}
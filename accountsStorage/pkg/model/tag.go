@@ -0,0 +1,56 @@
+package model
+
+import (
+	"regexp"
+
+	"github.com/google/uuid"
+)
+
+// TagMatch is how accounts are matched against several tags.
+type TagMatch string
+
+const (
+	// TagMatchAll matches the accounts that have every tag.
+	TagMatchAll TagMatch = "all"
+	// TagMatchAny matches the accounts that have at least one of the tags.
+	TagMatchAny TagMatch = "any"
+)
+
+var tagPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.:/-]{0,63}$`)
+
+// ParseTagMatch parses a tag match, the default is TagMatchAll.
+func ParseTagMatch(match string) (TagMatch, error) {
+	switch TagMatch(match) {
+	case "", TagMatchAll:
+		return TagMatchAll, nil
+	case TagMatchAny:
+		return TagMatchAny, nil
+	default:
+		return "", Errorf(ErrInvalidArgument, "invalid tag match %q, expected all or any", match)
+	}
+}
+
+// ValidateTags checks that tags holds at least one tag and only valid ones.
+func ValidateTags(tags []string) error {
+	if len(tags) == 0 {
+		return NewError(ErrInvalidArgument, "tags are required")
+	}
+	for _, tag := range tags {
+		if !tagPattern.MatchString(tag) {
+			return Errorf(ErrInvalidArgument, "invalid tag %q", tag)
+		}
+	}
+	return nil
+}
+
+// AccountTags are tags added to or removed from accounts in bulk.
+type AccountTags struct {
+	AccountIDs []uuid.UUID `json:"account_ids"`
+	Tags       []string    `json:"tags"`
+}
+
+// TagCount is a tag and the number of accounts that have it.
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}